@@ -0,0 +1,211 @@
+package controller
+
+import (
+	"codedln/admin_module/model"
+	"codedln/admin_module/service"
+	"codedln/shared/http_error"
+	urlModel "codedln/url_module/model"
+	userModel "codedln/user_module/model"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+type AdminController struct {
+	adminService *service.AdminService
+}
+
+func New(adminService *service.AdminService) *AdminController {
+	return &AdminController{
+		adminService: adminService,
+	}
+}
+
+func (c *AdminController) SearchUrls(w http.ResponseWriter, r *http.Request) error {
+	limit, skip := pagination(r)
+
+	result, err := c.adminService.SearchUrls(r.Context(), r.URL.Query().Get("query"), limit, skip)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, result)
+}
+
+func (c *AdminController) SearchUsers(w http.ResponseWriter, r *http.Request) error {
+	limit, skip := pagination(r)
+
+	result, err := c.adminService.SearchUsers(r.Context(), r.URL.Query().Get("query"), limit, skip)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, result)
+}
+
+func (c *AdminController) GetAuditLogs(w http.ResponseWriter, r *http.Request) error {
+	limit, skip := pagination(r)
+
+	result, err := c.adminService.GetAuditLogs(r.Context(), limit, skip)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, result)
+}
+
+func (c *AdminController) GetReports(w http.ResponseWriter, r *http.Request) error {
+	limit, skip := pagination(r)
+	state := types.ReportState(r.URL.Query().Get("state"))
+
+	result, err := c.adminService.GetReports(r.Context(), state, limit, skip)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, result)
+}
+
+func (c *AdminController) ReviewReport(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	ReviewValue := r.Context().Value(constant.PayloadKey)
+	if ReviewValue == nil {
+		return http_error.New(http.StatusBadRequest, "unable to get payload")
+	}
+
+	ReviewPayload, ok := ReviewValue.(model.ReviewReportSchema)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid review payload")
+	}
+
+	reportId, exist := mux.Vars(r)["reportId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no report id found")
+	}
+
+	report, err := c.adminService.ReviewReport(r.Context(), reportId, ReviewPayload.State, ReviewPayload.Reason, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, report)
+}
+
+func (c *AdminController) DisableUrl(w http.ResponseWriter, r *http.Request) error {
+	return c.moderateUrl(w, r, c.adminService.DisableUrl)
+}
+
+func (c *AdminController) TakeDownUrl(w http.ResponseWriter, r *http.Request) error {
+	return c.moderateUrl(w, r, c.adminService.TakeDownUrl)
+}
+
+func (c *AdminController) EnableUrl(w http.ResponseWriter, r *http.Request) error {
+	return c.moderateUrl(w, r, c.adminService.EnableUrl)
+}
+
+func (c *AdminController) SuspendUser(w http.ResponseWriter, r *http.Request) error {
+	return c.moderateUser(w, r, c.adminService.SuspendUser)
+}
+
+func (c *AdminController) UnsuspendUser(w http.ResponseWriter, r *http.Request) error {
+	return c.moderateUser(w, r, c.adminService.UnsuspendUser)
+}
+
+func (c *AdminController) moderateUrl(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, urlId string, reason string, adminId string) (*urlModel.Url, error)) error {
+	adminId, payload, err := moderation(r)
+	if err != nil {
+		return err
+	}
+
+	urlId, exist := mux.Vars(r)["urlId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no url id found")
+	}
+
+	url, err := action(r.Context(), urlId, payload.Reason, adminId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, url)
+}
+
+func (c *AdminController) moderateUser(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, userId string, reason string, adminId string) (*userModel.User, error)) error {
+	adminId, payload, err := moderation(r)
+	if err != nil {
+		return err
+	}
+
+	userId, exist := mux.Vars(r)["userId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no user id found")
+	}
+
+	user, err := action(r.Context(), userId, payload.Reason, adminId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, user)
+}
+
+// moderation extracts the acting admin and the validated moderation payload from the request context.
+func moderation(r *http.Request) (string, model.ModerationSchema, error) {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+	if UserIDValue == nil {
+		return "", model.ModerationSchema{}, http_error.New(http.StatusBadRequest, "no user id")
+	}
+
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return "", model.ModerationSchema{}, http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	PayloadValue := r.Context().Value(constant.PayloadKey)
+	if PayloadValue == nil {
+		return "", model.ModerationSchema{}, http_error.New(http.StatusBadRequest, "unable to get payload")
+	}
+
+	payload, ok := PayloadValue.(model.ModerationSchema)
+	if !ok {
+		return "", model.ModerationSchema{}, http_error.New(http.StatusBadRequest, "invalid moderation payload")
+	}
+
+	return UserIdPayload.UserId, payload, nil
+}
+
+func pagination(r *http.Request) (int64, int64) {
+	query := r.URL.Query()
+
+	var limit int64
+	var skip int64
+
+	val, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil || val <= 0 || val > constant.MaxLimit {
+		limit = constant.MaxLimit
+	} else {
+		limit = val
+	}
+
+	val, err = strconv.ParseInt(query.Get("skip"), 10, 64)
+	if err != nil || val < 0 {
+		skip = 0
+	} else {
+		skip = val * limit
+	}
+
+	return limit, skip
+}
@@ -0,0 +1,16 @@
+package model
+
+import (
+	"codedln/util/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type AuditLog struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	AdminId   primitive.ObjectID `bson:"adminId" json:"adminId"`
+	Action    types.AdminAction  `bson:"action" json:"action"`
+	TargetId  primitive.ObjectID `bson:"targetId" json:"targetId"`
+	Reason    string             `bson:"reason" json:"reason"`
+	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
+}
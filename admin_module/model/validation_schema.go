@@ -0,0 +1,50 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"net/http"
+	"strings"
+)
+
+type ModerationSchema struct {
+	Reason string `json:"reason"`
+}
+
+func NewModerationSchema() ModerationSchema {
+	return ModerationSchema{}
+}
+
+func (s ModerationSchema) Validate() error {
+	if strings.TrimSpace(s.Reason) == "" {
+		return http_error.New(http.StatusBadRequest, "a reason must be provided")
+	}
+
+	if len(s.Reason) > constant.ModerationReasonMaxLength {
+		return http_error.New(http.StatusBadRequest, "reason must be within 500 characters")
+	}
+
+	return nil
+}
+
+type ReviewReportSchema struct {
+	State  types.ReportState `json:"state"`
+	Reason string            `json:"reason"`
+}
+
+func NewReviewReportSchema() ReviewReportSchema {
+	return ReviewReportSchema{}
+}
+
+func (s ReviewReportSchema) Validate() error {
+	states := []types.ReportState{constant.ReviewedReport, constant.ActionedReport, constant.DismissedReport}
+	if !helpers.InList(states, s.State, func(a types.ReportState, b types.ReportState) bool {
+		return a == b
+	}) {
+		return http_error.New(http.StatusBadRequest, "invalid report state")
+	}
+
+	return ModerationSchema{Reason: s.Reason}.Validate()
+}
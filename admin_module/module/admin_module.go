@@ -0,0 +1,140 @@
+package module
+
+import (
+	"codedln/admin_module/controller"
+	"codedln/admin_module/model"
+	"codedln/admin_module/repository"
+	"codedln/admin_module/service"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/util/constant"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"net/http"
+)
+
+func AdminModule(router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Database, cfg *config.Config) {
+	urlCollection := db.Collection(constant.UrlCollection)
+	if urlCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UrlCollection)
+	}
+
+	userCollection := db.Collection(constant.UserCollection)
+	if userCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UserCollection)
+	}
+
+	auditCollection := db.Collection(constant.AuditCollection)
+	if auditCollection == nil {
+		log.Fatalf("%s does not exist:", constant.AuditCollection)
+	}
+
+	reportCollection := db.Collection(constant.ReportCollection)
+	if reportCollection == nil {
+		log.Fatalf("%s does not exist:", constant.ReportCollection)
+	}
+
+	adminRepo := repository.New(urlCollection, userCollection, auditCollection, reportCollection)
+	adminService := service.New(adminRepo)
+	adminController := controller.New(adminService)
+
+	adminRouter := router.PathPrefix("/admin").Subrouter()
+
+	adminRouter.HandleFunc("/search_urls",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.SearchUrls,
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	adminRouter.HandleFunc("/search_users",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.SearchUsers,
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	adminRouter.HandleFunc("/audit_logs",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.GetAuditLogs,
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	adminRouter.HandleFunc("/disable_url/{urlId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.DisableUrl,
+			middleware.PayloadValidationMiddleware(model.NewModerationSchema),
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	adminRouter.HandleFunc("/takedown_url/{urlId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.TakeDownUrl,
+			middleware.PayloadValidationMiddleware(model.NewModerationSchema),
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	adminRouter.HandleFunc("/enable_url/{urlId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.EnableUrl,
+			middleware.PayloadValidationMiddleware(model.NewModerationSchema),
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	adminRouter.HandleFunc("/suspend_user/{userId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.SuspendUser,
+			middleware.PayloadValidationMiddleware(model.NewModerationSchema),
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	adminRouter.HandleFunc("/unsuspend_user/{userId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.UnsuspendUser,
+			middleware.PayloadValidationMiddleware(model.NewModerationSchema),
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	adminRouter.HandleFunc("/reports",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.GetReports,
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	adminRouter.HandleFunc("/review_report/{reportId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.ReviewReport,
+			middleware.PayloadValidationMiddleware(model.NewReviewReportSchema),
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+}
@@ -0,0 +1,210 @@
+package repository
+
+import (
+	"codedln/admin_module/model"
+	reportModel "codedln/report_module/model"
+	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	urlModel "codedln/url_module/model"
+	userModel "codedln/user_module/model"
+	"codedln/util/types"
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"regexp"
+	"time"
+)
+
+type AdminRepository interface {
+	SearchUrls(ctx context.Context, query string, limit int64, skip int64) (*types.PaginationResult[urlModel.Url], error)
+	SearchUsers(ctx context.Context, query string, limit int64, skip int64) (*types.PaginationResult[userModel.User], error)
+	GetUrl(ctx context.Context, urlId primitive.ObjectID) (*urlModel.Url, error)
+	UpdateUrl(ctx context.Context, urlId primitive.ObjectID, update bson.D) (*urlModel.Url, error)
+	UpdateUser(ctx context.Context, userId primitive.ObjectID, update bson.D) (*userModel.User, error)
+	CountUsers(ctx context.Context, filter bson.D) (int64, error)
+	UpdateUrls(ctx context.Context, filter bson.D, update bson.D) error
+	GetReports(ctx context.Context, filter bson.D, limit int64, skip int64) (*types.PaginationResult[reportModel.Report], error)
+	GetReport(ctx context.Context, reportId primitive.ObjectID) (*reportModel.Report, error)
+	UpdateReports(ctx context.Context, filter bson.D, update bson.D) error
+	CreateAuditLog(ctx context.Context, auditLog model.AuditLog) error
+	GetAuditLogs(ctx context.Context, limit int64, skip int64) (*types.PaginationResult[model.AuditLog], error)
+}
+
+type MongoAdminRepository struct {
+	urlCollection    *mongo.Collection
+	userCollection   *mongo.Collection
+	auditCollection  *mongo.Collection
+	reportCollection *mongo.Collection
+}
+
+func New(urlCollection *mongo.Collection, userCollection *mongo.Collection, auditCollection *mongo.Collection, reportCollection *mongo.Collection) AdminRepository {
+	return &MongoAdminRepository{
+		urlCollection:    urlCollection,
+		userCollection:   userCollection,
+		auditCollection:  auditCollection,
+		reportCollection: reportCollection,
+	}
+}
+
+func (r *MongoAdminRepository) SearchUrls(ctx context.Context, query string, limit int64, skip int64) (*types.PaginationResult[urlModel.Url], error) {
+	pattern := regexp.QuoteMeta(query)
+	searchFilter := bson.D{{"$or", bson.A{
+		bson.D{{"alias", bson.D{{"$regex", pattern}, {"$options", "i"}}}},
+		bson.D{{"originalUrl", bson.D{{"$regex", pattern}, {"$options", "i"}}}},
+	}}}
+
+	return paginate[urlModel.Url](ctx, r.urlCollection, searchFilter, limit, skip)
+}
+
+func (r *MongoAdminRepository) SearchUsers(ctx context.Context, query string, limit int64, skip int64) (*types.PaginationResult[userModel.User], error) {
+	pattern := regexp.QuoteMeta(query)
+	searchFilter := bson.D{{"$or", bson.A{
+		bson.D{{"email", bson.D{{"$regex", pattern}, {"$options", "i"}}}},
+		bson.D{{"firstname", bson.D{{"$regex", pattern}, {"$options", "i"}}}},
+		bson.D{{"lastname", bson.D{{"$regex", pattern}, {"$options", "i"}}}},
+	}}}
+
+	return paginate[userModel.User](ctx, r.userCollection, searchFilter, limit, skip)
+}
+
+func (r *MongoAdminRepository) GetUrl(ctx context.Context, urlId primitive.ObjectID) (*urlModel.Url, error) {
+	res := r.urlCollection.FindOne(ctx, bson.D{{"_id", urlId}})
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, http_error.New(http.StatusNotFound, "url does not exist")
+	}
+
+	var url urlModel.Url
+	if decodeErr := res.Decode(&url); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get url")
+	}
+
+	return &url, nil
+}
+
+func (r *MongoAdminRepository) UpdateUrl(ctx context.Context, urlId primitive.ObjectID, update bson.D) (*urlModel.Url, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	res := r.urlCollection.FindOneAndUpdate(ctx, bson.D{{"_id", urlId}}, update, opts)
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, http_error.New(http.StatusNotFound, "url does not exist")
+	}
+
+	var url urlModel.Url
+	if decodeErr := res.Decode(&url); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to update url")
+	}
+
+	return &url, nil
+}
+
+func (r *MongoAdminRepository) UpdateUser(ctx context.Context, userId primitive.ObjectID, update bson.D) (*userModel.User, error) {
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+	res := r.userCollection.FindOneAndUpdate(ctx, bson.D{{"_id", userId}}, update, opts)
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, http_error.New(http.StatusNotFound, "user does not exist")
+	}
+
+	var user userModel.User
+	if decodeErr := res.Decode(&user); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to update user")
+	}
+
+	return &user, nil
+}
+
+func (r *MongoAdminRepository) CountUsers(ctx context.Context, filter bson.D) (int64, error) {
+	count, err := r.userCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to count users")
+	}
+	return count, nil
+}
+
+func (r *MongoAdminRepository) UpdateUrls(ctx context.Context, filter bson.D, update bson.D) error {
+	_, err := r.urlCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to update urls")
+	}
+	return nil
+}
+
+func (r *MongoAdminRepository) GetReports(ctx context.Context, filter bson.D, limit int64, skip int64) (*types.PaginationResult[reportModel.Report], error) {
+	return paginate[reportModel.Report](ctx, r.reportCollection, filter, limit, skip)
+}
+
+func (r *MongoAdminRepository) GetReport(ctx context.Context, reportId primitive.ObjectID) (*reportModel.Report, error) {
+	res := r.reportCollection.FindOne(ctx, bson.D{{"_id", reportId}})
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, http_error.New(http.StatusNotFound, "report does not exist")
+	}
+
+	var report reportModel.Report
+	if decodeErr := res.Decode(&report); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get report")
+	}
+
+	return &report, nil
+}
+
+func (r *MongoAdminRepository) UpdateReports(ctx context.Context, filter bson.D, update bson.D) error {
+	_, err := r.reportCollection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to update reports")
+	}
+	return nil
+}
+
+func (r *MongoAdminRepository) CreateAuditLog(ctx context.Context, auditLog model.AuditLog) error {
+	_, err := r.auditCollection.InsertOne(ctx, auditLog)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to record audit log")
+	}
+	return nil
+}
+
+func (r *MongoAdminRepository) GetAuditLogs(ctx context.Context, limit int64, skip int64) (*types.PaginationResult[model.AuditLog], error) {
+	return paginate[model.AuditLog](ctx, r.auditCollection, bson.D{}, limit, skip)
+}
+
+// paginate returns one page of documents matching filter, newest first, along with the total match count.
+func paginate[T any](ctx context.Context, collection *mongo.Collection, filter bson.D, limit int64, skip int64) (*types.PaginationResult[T], error) {
+	totalCount, err := collection.CountDocuments(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to count documents")
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"createdAt", -1}}).
+		SetSkip(skip).
+		SetLimit(limit).
+		SetMaxTime(2 * time.Second)
+
+	cursor, err := collection.Find(ctx, filter, opts)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to fetch documents")
+	}
+
+	results := make([]T, 0)
+	if err = cursor.All(ctx, &results); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to process fetched documents")
+	}
+
+	return &types.PaginationResult[T]{
+		Data:  results,
+		Total: totalCount,
+	}, nil
+}
@@ -0,0 +1,231 @@
+package service
+
+import (
+	"codedln/admin_module/model"
+	"codedln/admin_module/repository"
+	reportModel "codedln/report_module/model"
+	"codedln/shared/http_error"
+	urlModel "codedln/url_module/model"
+	userModel "codedln/user_module/model"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"time"
+)
+
+type AdminService struct {
+	repo repository.AdminRepository
+}
+
+func New(repo repository.AdminRepository) *AdminService {
+	return &AdminService{
+		repo: repo,
+	}
+}
+
+func (s *AdminService) SearchUrls(ctx context.Context, query string, limit int64, skip int64) (*types.PaginationResult[urlModel.Url], error) {
+	return s.repo.SearchUrls(ctx, query, limit, skip)
+}
+
+func (s *AdminService) SearchUsers(ctx context.Context, query string, limit int64, skip int64) (*types.PaginationResult[userModel.User], error) {
+	return s.repo.SearchUsers(ctx, query, limit, skip)
+}
+
+func (s *AdminService) GetAuditLogs(ctx context.Context, limit int64, skip int64) (*types.PaginationResult[model.AuditLog], error) {
+	return s.repo.GetAuditLogs(ctx, limit, skip)
+}
+
+func (s *AdminService) GetReports(ctx context.Context, state types.ReportState, limit int64, skip int64) (*types.PaginationResult[reportModel.Report], error) {
+	filter := bson.D{}
+	if state != "" {
+		filter = bson.D{{"state", state}}
+	}
+	return s.repo.GetReports(ctx, filter, limit, skip)
+}
+
+// ReviewReport resolves a report together with every other open report against the same link,
+// since the decision is about the link rather than a single reporter.
+// Actioning takes the link down, dismissing lifts an automatic suspension. A link suspended for another reason,
+// such as its owner being suspended, stays suspended when its reports are dismissed.
+func (s *AdminService) ReviewReport(ctx context.Context, reportId string, state types.ReportState, reason string, adminId string) (*reportModel.Report, error) {
+	reportIdObj, err := primitive.ObjectIDFromHex(reportId)
+	if err != nil {
+		return nil, http_error.New(http.StatusBadRequest, "unable to parse report id")
+	}
+
+	adminIdObj, err := primitive.ObjectIDFromHex(adminId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse admin id")
+	}
+
+	report, err := s.repo.GetReport(ctx, reportIdObj)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := bson.D{{"$or", bson.A{
+		bson.D{{"_id", report.ID}},
+		bson.D{{"urlId", report.UrlId}, {"state", constant.OpenReport}},
+	}}}
+	update := bson.D{{"$set", bson.D{
+		{"state", state},
+		{"reviewedBy", adminIdObj},
+		{"updatedAt", time.Now().UTC()},
+	}}}
+	if err = s.repo.UpdateReports(ctx, filter, update); err != nil {
+		return nil, err
+	}
+
+	switch state {
+	case constant.ActionedReport:
+		if _, err = s.repo.UpdateUrl(ctx, report.UrlId, bson.D{{"$set", bson.D{
+			{"status", constant.TakenDownUrl},
+			{"statusReason", reason},
+			{"updatedAt", time.Now().UTC()},
+		}}}); err != nil {
+			return nil, err
+		}
+	case constant.DismissedReport:
+		filter := bson.D{{"_id", report.UrlId}, {"status", constant.SuspendedUrl}, {"statusReason", constant.ReportSuspendedReason}}
+		if err = s.repo.UpdateUrls(ctx, filter, bson.D{{"$set", bson.D{
+			{"status", constant.ActiveUrl},
+			{"statusReason", ""},
+			{"updatedAt", time.Now().UTC()},
+		}}}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err = s.audit(ctx, adminId, constant.ReviewReportAction, report.ID, reason); err != nil {
+		return nil, err
+	}
+
+	report.State = state
+	report.ReviewedBy = adminIdObj
+	return report, nil
+}
+
+func (s *AdminService) DisableUrl(ctx context.Context, urlId string, reason string, adminId string) (*urlModel.Url, error) {
+	return s.setUrlStatus(ctx, urlId, constant.DisabledUrl, reason, constant.DisableUrlAction, adminId)
+}
+
+func (s *AdminService) TakeDownUrl(ctx context.Context, urlId string, reason string, adminId string) (*urlModel.Url, error) {
+	return s.setUrlStatus(ctx, urlId, constant.TakenDownUrl, reason, constant.TakeDownUrlAction, adminId)
+}
+
+// EnableUrl puts a link back in service. The links of a suspended user stay suspended until the user is unsuspended.
+func (s *AdminService) EnableUrl(ctx context.Context, urlId string, reason string, adminId string) (*urlModel.Url, error) {
+	urlIdObj, err := primitive.ObjectIDFromHex(urlId)
+	if err != nil {
+		return nil, http_error.New(http.StatusBadRequest, "unable to parse urlId")
+	}
+
+	url, err := s.repo.GetUrl(ctx, urlIdObj)
+	if err != nil {
+		return nil, err
+	}
+
+	if !url.UserId.IsZero() {
+		suspendedOwners, countErr := s.repo.CountUsers(ctx, bson.D{{"_id", url.UserId}, {"suspended", true}})
+		if countErr != nil {
+			return nil, countErr
+		}
+		if suspendedOwners > 0 {
+			return nil, http_error.New(http.StatusConflict, "the owner of this link is suspended")
+		}
+	}
+
+	return s.setUrlStatus(ctx, urlId, constant.ActiveUrl, reason, constant.EnableUrlAction, adminId)
+}
+
+func (s *AdminService) SuspendUser(ctx context.Context, userId string, reason string, adminId string) (*userModel.User, error) {
+	return s.setUserSuspension(ctx, userId, true, reason, constant.SuspendUserAction, adminId)
+}
+
+func (s *AdminService) UnsuspendUser(ctx context.Context, userId string, reason string, adminId string) (*userModel.User, error) {
+	return s.setUserSuspension(ctx, userId, false, reason, constant.UnsuspendUserAction, adminId)
+}
+
+func (s *AdminService) setUrlStatus(ctx context.Context, urlId string, status types.UrlStatus, reason string, action types.AdminAction, adminId string) (*urlModel.Url, error) {
+	urlIdObj, err := primitive.ObjectIDFromHex(urlId)
+	if err != nil {
+		return nil, http_error.New(http.StatusBadRequest, "unable to parse urlId")
+	}
+
+	update := bson.D{{"$set", bson.D{
+		{"status", status},
+		// The reason is only shown on the link while it is out of service; it is always kept in the audit log
+		{"statusReason", helpers.Ternary(status == constant.ActiveUrl, "", reason)},
+		{"updatedAt", time.Now().UTC()},
+	}}}
+
+	url, err := s.repo.UpdateUrl(ctx, urlIdObj, update)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = s.audit(ctx, adminId, action, urlIdObj, reason); err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+func (s *AdminService) setUserSuspension(ctx context.Context, userId string, suspended bool, reason string, action types.AdminAction, adminId string) (*userModel.User, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusBadRequest, "unable to parse user id")
+	}
+
+	if userId == adminId {
+		return nil, http_error.New(http.StatusBadRequest, "admins cannot change their own suspension")
+	}
+
+	update := bson.D{{"$set", bson.D{
+		{"suspended", suspended},
+		{"updatedAt", time.Now().UTC()},
+	}}}
+
+	user, err := s.repo.UpdateUser(ctx, userIdObj, update)
+	if err != nil {
+		return nil, err
+	}
+
+	// The user's links stop redirecting with them. Only links suspended this way are restored, so a link that was
+	// disabled, reported or taken down in the meantime keeps that status.
+	linkFilter := bson.D{{"userId", userIdObj}, {"status", constant.SuspendedUrl}, {"statusReason", constant.OwnerSuspendedReason}}
+	linkUpdate := bson.D{{"$set", bson.D{{"status", constant.ActiveUrl}, {"statusReason", ""}, {"updatedAt", time.Now().UTC()}}}}
+	if suspended {
+		// Links created before moderation existed have no status and are active too
+		linkFilter = bson.D{{"userId", userIdObj}, {"status", bson.D{{"$in", bson.A{constant.ActiveUrl, nil}}}}}
+		linkUpdate = bson.D{{"$set", bson.D{{"status", constant.SuspendedUrl}, {"statusReason", constant.OwnerSuspendedReason}, {"updatedAt", time.Now().UTC()}}}}
+	}
+	if err = s.repo.UpdateUrls(ctx, linkFilter, linkUpdate); err != nil {
+		return nil, err
+	}
+
+	if err = s.audit(ctx, adminId, action, userIdObj, reason); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *AdminService) audit(ctx context.Context, adminId string, action types.AdminAction, targetId primitive.ObjectID, reason string) error {
+	adminIdObj, err := primitive.ObjectIDFromHex(adminId)
+	if err != nil {
+		return http_error.New(http.StatusInternalServerError, "unable to parse admin id")
+	}
+
+	return s.repo.CreateAuditLog(ctx, model.AuditLog{
+		AdminId:   adminIdObj,
+		Action:    action,
+		TargetId:  targetId,
+		Reason:    reason,
+		CreatedAt: time.Now().UTC(),
+	})
+}
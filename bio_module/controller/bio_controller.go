@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"codedln/bio_module/model"
+	"codedln/bio_module/service"
+	"codedln/shared/http_error"
+	"codedln/shared/view"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type BioController struct {
+	bioService *service.BioService
+}
+
+func New(bioService *service.BioService) *BioController {
+	return &BioController{
+		bioService: bioService,
+	}
+}
+
+func (c *BioController) GetBioPage(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	page, err := c.bioService.GetBioPage(r.Context(), UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, page)
+}
+
+func (c *BioController) SaveBioPage(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	PageValue := r.Context().Value(constant.PayloadKey)
+	if PageValue == nil {
+		return http_error.New(http.StatusBadRequest, "no bio page provided")
+	}
+	PagePayload, ok := PageValue.(model.SaveBioPageSchema)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid bio page payload")
+	}
+
+	page, err := c.bioService.SaveBioPage(r.Context(), PagePayload, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, page)
+}
+
+func (c *BioController) DeleteBioPage(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	if err := c.bioService.DeleteBioPage(r.Context(), UserIdPayload.UserId); err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, nil)
+}
+
+// ViewBioPage renders a bio page for visitors.
+func (c *BioController) ViewBioPage(w http.ResponseWriter, r *http.Request) error {
+	handle, exist := mux.Vars(r)["handle"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no handle found")
+	}
+
+	page, err := c.bioService.GetPublicBioPage(r.Context(), handle)
+	if err != nil {
+		return err
+	}
+
+	return view.Render(w, http.StatusOK, "bio.html", page)
+}
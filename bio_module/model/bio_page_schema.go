@@ -0,0 +1,43 @@
+package model
+
+import (
+	"codedln/util/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// BioPage is a user's public link-in-bio page, served at /p/{handle}.
+type BioPage struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId      primitive.ObjectID `bson:"userId" json:"userId"`
+	Handle      string             `bson:"handle" json:"handle"`
+	DisplayName string             `bson:"displayName,omitempty" json:"displayName,omitempty"`
+	Bio         string             `bson:"bio,omitempty" json:"bio,omitempty"`
+	Theme       types.BioTheme     `bson:"theme" json:"theme"`
+	Links       []BioLink          `bson:"links" json:"links"`
+	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// BioLink is one of the owner's short links on their page. Title and Icon replace the link's own title and show no icon when empty.
+type BioLink struct {
+	UrlId primitive.ObjectID `bson:"urlId" json:"urlId"`
+	Title string             `bson:"title,omitempty" json:"title,omitempty"`
+	Icon  string             `bson:"icon,omitempty" json:"icon,omitempty"`
+}
+
+// PublicBioPage is what visitors of a bio page see. Links that are disabled, trashed or outside their active window are left out.
+type PublicBioPage struct {
+	Handle  string
+	Name    string
+	Bio     string
+	Picture string
+	Theme   types.BioTheme
+	Links   []PublicBioLink
+}
+
+type PublicBioLink struct {
+	Alias string
+	Title string
+	Icon  string
+}
@@ -0,0 +1,96 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	urlModel "codedln/url_module/model"
+	"codedln/util/constant"
+	"codedln/util/types"
+	"fmt"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// SaveBioPageSchema replaces the user's bio page, creating it the first time. Links are shown in the order given.
+type SaveBioPageSchema struct {
+	Handle      string          `json:"handle"`
+	DisplayName string          `json:"displayName"`
+	Bio         string          `json:"bio"`
+	Theme       types.BioTheme  `json:"theme"`
+	Links       []BioLinkSchema `json:"links"`
+}
+
+type BioLinkSchema struct {
+	UrlId string `json:"urlId"`
+	Title string `json:"title"`
+	Icon  string `json:"icon"`
+}
+
+var bioThemes = []types.BioTheme{constant.LightBioTheme, constant.DarkBioTheme, constant.SunsetBioTheme, constant.ForestBioTheme}
+
+func NewSaveBioPageSchema() SaveBioPageSchema {
+	return SaveBioPageSchema{}
+}
+
+func (s SaveBioPageSchema) Validate() error {
+	if !ValidHandle(NormalizeHandle(s.Handle)) {
+		return http_error.New(http.StatusBadRequest, fmt.Sprintf("handle must be %d to %d letters, digits, dashes or underscores", constant.BioHandleMinLength, constant.BioHandleMaxLength))
+	}
+
+	if len(s.DisplayName) > constant.TitleMaxLength {
+		return http_error.New(http.StatusBadRequest, "display name must be at most 200 characters")
+	}
+
+	if len(s.Bio) > constant.BioMaxLength {
+		return http_error.New(http.StatusBadRequest, "bio must be at most 300 characters")
+	}
+
+	if s.Theme != "" && !slices.Contains(bioThemes, s.Theme) {
+		return http_error.New(http.StatusBadRequest, "invalid theme")
+	}
+
+	if len(s.Links) > constant.MaxBioLinks {
+		return http_error.New(http.StatusBadRequest, "at most 50 links can be added")
+	}
+
+	seen := map[string]bool{}
+	for _, link := range s.Links {
+		if _, err := primitive.ObjectIDFromHex(link.UrlId); err != nil {
+			return http_error.New(http.StatusBadRequest, "invalid url id "+link.UrlId)
+		}
+		if seen[link.UrlId] {
+			return http_error.New(http.StatusBadRequest, "a link can only be added once")
+		}
+		seen[link.UrlId] = true
+
+		if len(link.Title) > constant.TitleMaxLength {
+			return http_error.New(http.StatusBadRequest, "link titles must be at most 200 characters")
+		}
+
+		if link.Icon != "" {
+			if _, err := urlModel.CanonicalUrl(link.Icon); err != nil {
+				return http_error.New(http.StatusBadRequest, "invalid icon: "+err.Error())
+			}
+		}
+	}
+
+	return nil
+}
+
+// NormalizeHandle makes handles case insensitive.
+func NormalizeHandle(handle string) string {
+	return strings.ToLower(strings.TrimSpace(handle))
+}
+
+func ValidHandle(handle string) bool {
+	if len(handle) < constant.BioHandleMinLength || len(handle) > constant.BioHandleMaxLength {
+		return false
+	}
+	for _, c := range handle {
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' && c != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,75 @@
+package module
+
+import (
+	"codedln/bio_module/controller"
+	"codedln/bio_module/model"
+	"codedln/bio_module/repository"
+	"codedln/bio_module/service"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/util/constant"
+	"context"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"net/http"
+)
+
+func BioModule(router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Database, cfg *config.Config) {
+	collection := db.Collection(constant.BioPageCollection)
+	if collection == nil {
+		log.Fatalf("%s does not exist:", constant.BioPageCollection)
+	}
+
+	urlCollection := db.Collection(constant.UrlCollection)
+	if urlCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UrlCollection)
+	}
+
+	userCollection := db.Collection(constant.UserCollection)
+	if userCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UserCollection)
+	}
+
+	bioRepo := repository.New(collection, urlCollection, userCollection)
+	if indexErr := bioRepo.CreateIndexes(context.Background()); indexErr != nil {
+		log.Fatalf("Error creating %s indexes", constant.BioPageCollection)
+	}
+	bioService := service.New(bioRepo)
+	bioController := controller.New(bioService)
+
+	bioRouter := router.PathPrefix("/bio").Subrouter()
+
+	bioRouter.HandleFunc("/page",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			bioController.GetBioPage,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	bioRouter.HandleFunc("/page",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			bioController.SaveBioPage,
+			middleware.PayloadValidationMiddleware(model.NewSaveBioPageSchema),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPut)
+
+	bioRouter.HandleFunc("/page",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			bioController.DeleteBioPage,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodDelete)
+
+	// Public pages, so no api key is required
+	router.HandleFunc("/p/{handle}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			bioController.ViewBioPage,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Public),
+		))).Methods(http.MethodGet)
+}
@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"codedln/bio_module/model"
+	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	urlModel "codedln/url_module/model"
+	userModel "codedln/user_module/model"
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+)
+
+type BioRepository interface {
+	CreateIndexes(ctx context.Context) error
+	GetBioPage(ctx context.Context, filter bson.D) (*model.BioPage, error)
+	SaveBioPage(ctx context.Context, page model.BioPage) (*model.BioPage, error)
+	DeleteBioPage(ctx context.Context, userId primitive.ObjectID) (bool, error)
+	GetUrls(ctx context.Context, filter bson.D) ([]urlModel.Url, error)
+	GetUser(ctx context.Context, userId primitive.ObjectID) (*userModel.User, error)
+}
+
+type MongoBioRepository struct {
+	collection     *mongo.Collection
+	urlCollection  *mongo.Collection
+	userCollection *mongo.Collection
+}
+
+func New(collection *mongo.Collection, urlCollection *mongo.Collection, userCollection *mongo.Collection) BioRepository {
+	return &MongoBioRepository{
+		collection:     collection,
+		urlCollection:  urlCollection,
+		userCollection: userCollection,
+	}
+}
+
+func (r *MongoBioRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"handle", 1}},
+			Options: options.Index().SetName("handle").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}},
+			Options: options.Index().SetName("user").SetUnique(true),
+		},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to create bio page indexes")
+	}
+	return nil
+}
+
+func (r *MongoBioRepository) GetBioPage(ctx context.Context, filter bson.D) (*model.BioPage, error) {
+	res := r.collection.FindOne(ctx, filter)
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	var page model.BioPage
+	if decodeErr := res.Decode(&page); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get bio page")
+	}
+
+	return &page, nil
+}
+
+// SaveBioPage replaces the user's page, or creates it when they have none. The handle is unique across all pages.
+func (r *MongoBioRepository) SaveBioPage(ctx context.Context, page model.BioPage) (*model.BioPage, error) {
+	update := bson.D{
+		{"$set", bson.D{
+			{"handle", page.Handle},
+			{"displayName", page.DisplayName},
+			{"bio", page.Bio},
+			{"theme", page.Theme},
+			{"links", page.Links},
+			{"updatedAt", page.UpdatedAt},
+		}},
+		{"$setOnInsert", bson.D{{"createdAt", page.UpdatedAt}}},
+	}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved model.BioPage
+	if err := r.collection.FindOneAndUpdate(ctx, bson.D{{"userId", page.UserId}}, update, opts).Decode(&saved); err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, http_error.New(http.StatusConflict, "handle is already taken")
+		}
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to save bio page")
+	}
+
+	return &saved, nil
+}
+
+func (r *MongoBioRepository) DeleteBioPage(ctx context.Context, userId primitive.ObjectID) (bool, error) {
+	res, err := r.collection.DeleteOne(ctx, bson.D{{"userId", userId}})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return false, http_error.New(http.StatusInternalServerError, "unable to delete bio page")
+	}
+	return res.DeletedCount > 0, nil
+}
+
+func (r *MongoBioRepository) GetUrls(ctx context.Context, filter bson.D) ([]urlModel.Url, error) {
+	cursor, err := r.urlCollection.Find(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get urls")
+	}
+
+	urls := []urlModel.Url{}
+	if err = cursor.All(ctx, &urls); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to decode urls")
+	}
+
+	return urls, nil
+}
+
+func (r *MongoBioRepository) GetUser(ctx context.Context, userId primitive.ObjectID) (*userModel.User, error) {
+	res := r.userCollection.FindOne(ctx, bson.D{{"_id", userId}})
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	var user userModel.User
+	if decodeErr := res.Decode(&user); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get user")
+	}
+
+	return &user, nil
+}
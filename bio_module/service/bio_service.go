@@ -0,0 +1,197 @@
+package service
+
+import (
+	"codedln/bio_module/model"
+	"codedln/bio_module/repository"
+	"codedln/shared/http_error"
+	urlModel "codedln/url_module/model"
+	"codedln/util/constant"
+	"context"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"strings"
+	"time"
+)
+
+type BioService struct {
+	repo repository.BioRepository
+}
+
+func New(repo repository.BioRepository) *BioService {
+	return &BioService{
+		repo: repo,
+	}
+}
+
+func (s *BioService) GetBioPage(ctx context.Context, userId string) (*model.BioPage, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	page, err := s.repo.GetBioPage(ctx, bson.D{{"userId", userIdObj}})
+	if err != nil {
+		return nil, err
+	}
+
+	if page == nil {
+		return nil, http_error.New(http.StatusNotFound, "you have no bio page yet")
+	}
+
+	return page, nil
+}
+
+// SaveBioPage replaces the user's bio page. Every link on it must be one of the user's own short links.
+func (s *BioService) SaveBioPage(ctx context.Context, payload model.SaveBioPageSchema, userId string) (*model.BioPage, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	links := make([]model.BioLink, 0, len(payload.Links))
+	urlIds := make(bson.A, 0, len(payload.Links))
+	for _, link := range payload.Links {
+		urlId, idErr := primitive.ObjectIDFromHex(link.UrlId)
+		if idErr != nil {
+			return nil, http_error.New(http.StatusBadRequest, "invalid url id "+link.UrlId)
+		}
+		urlIds = append(urlIds, urlId)
+		links = append(links, model.BioLink{
+			UrlId: urlId,
+			Title: strings.TrimSpace(link.Title),
+			Icon:  strings.TrimSpace(link.Icon),
+		})
+	}
+
+	if len(urlIds) > 0 {
+		owned, urlErr := s.repo.GetUrls(ctx, bson.D{
+			{"_id", bson.D{{"$in", urlIds}}},
+			{"userId", userIdObj},
+			{"deletedAt", bson.D{{"$exists", false}}},
+		})
+		if urlErr != nil {
+			return nil, urlErr
+		}
+		if len(owned) != len(urlIds) {
+			return nil, http_error.New(http.StatusBadRequest, "only your own short links can be added")
+		}
+	}
+
+	theme := payload.Theme
+	if theme == "" {
+		theme = constant.LightBioTheme
+	}
+
+	return s.repo.SaveBioPage(ctx, model.BioPage{
+		UserId:      userIdObj,
+		Handle:      model.NormalizeHandle(payload.Handle),
+		DisplayName: strings.TrimSpace(payload.DisplayName),
+		Bio:         strings.TrimSpace(payload.Bio),
+		Theme:       theme,
+		Links:       links,
+		UpdatedAt:   time.Now().UTC(),
+	})
+}
+
+func (s *BioService) DeleteBioPage(ctx context.Context, userId string) error {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	deleted, err := s.repo.DeleteBioPage(ctx, userIdObj)
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return http_error.New(http.StatusNotFound, "you have no bio page yet")
+	}
+
+	return nil
+}
+
+// GetPublicBioPage returns the page visitors see at /p/{handle}.
+// Pages of suspended users are hidden, and links that would not redirect right now are left off.
+func (s *BioService) GetPublicBioPage(ctx context.Context, handle string) (*model.PublicBioPage, error) {
+	handle = model.NormalizeHandle(handle)
+	if !model.ValidHandle(handle) {
+		return nil, http_error.New(http.StatusNotFound, "no bio page found for the handle")
+	}
+
+	page, err := s.repo.GetBioPage(ctx, bson.D{{"handle", handle}})
+	if err != nil {
+		return nil, err
+	}
+
+	if page == nil {
+		return nil, http_error.New(http.StatusNotFound, "no bio page found for the handle")
+	}
+
+	user, err := s.repo.GetUser(ctx, page.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil || user.Suspended {
+		return nil, http_error.New(http.StatusNotFound, "no bio page found for the handle")
+	}
+
+	public := &model.PublicBioPage{
+		Handle:  page.Handle,
+		Name:    page.DisplayName,
+		Bio:     page.Bio,
+		Picture: user.Picture,
+		Theme:   page.Theme,
+		Links:   []model.PublicBioLink{},
+	}
+	if public.Name == "" {
+		public.Name = strings.TrimSpace(user.FirstName + " " + user.LastName)
+	}
+
+	if len(page.Links) == 0 {
+		return public, nil
+	}
+
+	urlIds := make(bson.A, 0, len(page.Links))
+	for _, link := range page.Links {
+		urlIds = append(urlIds, link.UrlId)
+	}
+	urls, err := s.repo.GetUrls(ctx, bson.D{
+		{"_id", bson.D{{"$in", urlIds}}},
+		{"userId", page.UserId},
+		{"deletedAt", bson.D{{"$exists", false}}},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	byId := make(map[primitive.ObjectID]urlModel.Url, len(urls))
+	for _, url := range urls {
+		byId[url.ID] = url
+	}
+
+	for _, link := range page.Links {
+		url, found := byId[link.UrlId]
+		if !found || url.IsDisabled() || url.IsPending() || url.IsExpired() {
+			continue
+		}
+
+		title := link.Title
+		if title == "" {
+			title = url.Unfurl().Title
+		}
+		if title == "" {
+			title = url.Alias
+		}
+
+		public.Links = append(public.Links, model.PublicBioLink{
+			Alias: url.Alias,
+			Title: title,
+			Icon:  link.Icon,
+		})
+	}
+
+	return public, nil
+}
@@ -1,94 +1,173 @@
 package main
 
 import (
+	admin "codedln/admin_module/module"
+	bio "codedln/bio_module/module"
+	report "codedln/report_module/module"
+	"codedln/shared/config"
+	"codedln/shared/logging"
+	"codedln/shared/metrics"
+	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
+	"codedln/shared/probe"
 	"codedln/shared/redis"
+	"codedln/shared/sse"
+	"codedln/shared/tracing"
 	url "codedln/url_module/module"
 	user "codedln/user_module/module"
 	"codedln/util/helpers"
+	webhook "codedln/webhook_module/module"
 	"context"
 	"errors"
 	"github.com/go-redis/redis_rate/v10"
 	"github.com/gorilla/mux"
-	"github.com/joho/godotenv"
+	"go.mongodb.org/mongo-driver/mongo/readpref"
 	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"syscall"
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		dotErr := godotenv.Load()
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
+func main() {
+
+	//Configuration is validated before anything is started so a bad setting stops the service straight away
+	cfg, cfgErr := config.Load()
+	if cfgErr != nil {
+		log.Fatal(cfgErr)
 	}
-}
 
-func main() {
+	//Everything logged through the standard log package is written by this logger too
+	slog.SetDefault(logging.New(cfg.LogLevel, cfg.Production()))
+
+	//Tracing is set up before the database clients so their commands are traced from the first one
+	shutdownTracing, tracingErr := tracing.Init(context.Background(), cfg.Tracing)
+	if tracingErr != nil {
+		log.Fatal("Error setting up tracing: ", tracingErr)
+	}
 
 	//Initialize mux
 	r := mux.NewRouter()
 
-	r.NotFoundHandler = helpers.NotFound()
-	r.MethodNotAllowedHandler = helpers.MethodNotAllowed()
+	//Unmatched requests are logged and counted too, they are most of what scanners produce
+	routerMiddlewares := []mux.MiddlewareFunc{middleware.ClientIPMiddleware(cfg.Server.TrustedProxies), middleware.RequestIdMiddleware, middleware.AccessLogMiddleware, metrics.Middleware, middleware.CorsMiddleware(cfg.Origin)}
+	r.NotFoundHandler = middleware.WrapHandler(helpers.NotFound(), routerMiddlewares...)
+	r.MethodNotAllowedHandler = middleware.WrapHandler(helpers.MethodNotAllowed(), routerMiddlewares...)
 	r.Methods(http.MethodOptions).HandlerFunc(helpers.PreflightRequest())
+	r.Use(routerMiddlewares...)
 
 	//Connect to mongo database
-	mClient := mongodb.ConnectToDatabase()
+	mClient := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	//Database
-	db := mClient.Database(os.Getenv("DATABASE_NAME"))
+	db := mClient.Database(cfg.Mongo.Database)
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 
 	//Initialize RateLimiter
 	rateLimiter := redis_rate.NewLimiter(rClient)
 
+	//Probes are registered before the modules so the catch-all short link route never shadows them
+	probes := probe.New(cfg.Server.ReadinessTimeout,
+		probe.Dependency{Name: "mongodb", Ping: func(ctx context.Context) error { return mClient.Ping(ctx, readpref.Primary()) }},
+		probe.Dependency{Name: "redis", Ping: func(ctx context.Context) error { return rClient.Ping(ctx).Err() }},
+	)
+	r.HandleFunc("/healthz", probes.Live()).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", probes.Ready()).Methods(http.MethodGet)
+
+	//Background jobs started by the modules run until shutdown begins
+	jobs, stopJobs := context.WithCancel(context.Background())
+
 	//Mount Modules
-	user.UserModule(r, rateLimiter, db)
-	url.UrlModule(r, rateLimiter, db)
+	user.UserModule(r, rateLimiter, db, cfg)
+	admin.AdminModule(r, rateLimiter, db, cfg)
+	report.ReportModule(r, rateLimiter, db, cfg)
+	bio.BioModule(r, rateLimiter, db, cfg)
+	webhooks := webhook.WebhookModule(jobs, r, rateLimiter, db, cfg)
+	//The url module registers the catch-all short link route so it must be mounted last
+	url.UrlModule(jobs, r, rateLimiter, db, rClient, webhooks, cfg)
 
 	//Setup Http Server
 	server := &http.Server{
-		Addr:           ":8080",
+		Addr:           cfg.Server.Addr,
 		Handler:        r,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
+		ReadTimeout:    cfg.Server.ReadTimeout,
+		WriteTimeout:   cfg.Server.WriteTimeout,
 		MaxHeaderBytes: 1 << 20, // 2^20 shifting 1 left by 20 = 1,048,576
 	}
 
+	//Shutdown waits for active requests without cancelling them, so live click streams are ended once it starts
+	server.RegisterOnShutdown(sse.Close)
+
+	//Metrics are served on their own port so they are never reachable through the public listener
+	metricsMux := http.NewServeMux()
+	metricsMux.Handle("/metrics", metrics.Handler())
+	metricsServer := &http.Server{
+		Addr:              cfg.Server.MetricsAddr,
+		Handler:           metricsMux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		slog.Info("metrics listening", "addr", metricsServer.Addr)
+		if err := metricsServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("metrics server error", "error", err)
+		}
+	}()
+
 	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, os.Interrupt, os.Kill)
+	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
 
 	go func() {
+		defer close(done)
 		// Setting up a channel to listen for OS signals
 		<-quit
+		stopJobs()
+
+		//Readiness fails first so the load balancer stops routing here while requests are still being served
+		probes.Drain()
+		slog.Info("draining before shutdown", "delay", cfg.Server.DrainDelay.String())
+		time.Sleep(cfg.Server.DrainDelay)
+
 		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Second)
 		defer cancel()
 
-		dbErr := mClient.Disconnect(ctx)
-		if dbErr != nil {
-			log.Fatal("Error disconnecting from database: ", dbErr)
+		//The database clients are closed only once in-flight requests are done with them.
+		//A timeout is only logged so the clients are still closed and buffered telemetry is still flushed.
+		if err := server.Shutdown(ctx); err != nil {
+			slog.Error("server shutdown error", "error", err)
+		}
+
+		//The shutdown context may have run out waiting on requests, so the clean up gets a deadline of its own
+		cleanupCtx, cancelCleanup := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancelCleanup()
+
+		if err := mClient.Disconnect(cleanupCtx); err != nil {
+			slog.Error("unable to disconnect from database", "error", err)
 		}
 
-		closeErr := rClient.Close()
-		if closeErr != nil {
-			log.Fatal("Error disconnecting from redis: ", closeErr)
+		if err := rClient.Close(); err != nil {
+			slog.Error("unable to disconnect from redis", "error", err)
 		}
 
-		if err := server.Shutdown(ctx); err != nil {
-			log.Fatal("Server shutdown error: ", err)
+		_ = metricsServer.Shutdown(cleanupCtx)
+
+		if err := shutdownTracing(cleanupCtx); err != nil {
+			slog.Error("unable to flush traces", "error", err)
 		}
 
-		log.Println("Server exiting gracefully")
+		slog.Info("server exiting gracefully")
 	}()
 
-	log.Println("Server listening on port 8080")
+	slog.Info("server listening", "addr", server.Addr)
 	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
 		log.Fatalf("ListenAndServe(): %v", err)
 	}
+
+	//ListenAndServe returns as soon as shutdown starts, so wait for the clean up to finish
+	<-done
 }
@@ -0,0 +1,46 @@
+package controller
+
+import (
+	"codedln/report_module/model"
+	"codedln/report_module/service"
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+type ReportController struct {
+	reportService *service.ReportService
+}
+
+func New(reportService *service.ReportService) *ReportController {
+	return &ReportController{
+		reportService: reportService,
+	}
+}
+
+func (c *ReportController) CreateReport(w http.ResponseWriter, r *http.Request) error {
+	alias, exist := mux.Vars(r)["alias"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no alias found")
+	}
+
+	ReportValue := r.Context().Value(constant.PayloadKey)
+
+	if ReportValue == nil {
+		return http_error.New(http.StatusBadRequest, "unable to get payload")
+	}
+
+	ReportPayload, ok := ReportValue.(model.CreateReportSchema)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid report payload")
+	}
+
+	report, err := c.reportService.CreateReport(r.Context(), alias, ReportPayload, helpers.GetClientIP(r))
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusCreated, report)
+}
@@ -0,0 +1,20 @@
+package model
+
+import (
+	"codedln/util/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+type Report struct {
+	ID           primitive.ObjectID   `bson:"_id,omitempty" json:"_id,omitempty"`
+	UrlId        primitive.ObjectID   `bson:"urlId" json:"urlId"`
+	Alias        string               `bson:"alias" json:"alias"`
+	Category     types.ReportCategory `bson:"category" json:"category"`
+	Comment      string               `bson:"comment" json:"comment"`
+	State        types.ReportState    `bson:"state" json:"state"`
+	ReporterHash string               `bson:"reporterHash" json:"-"`
+	ReviewedBy   primitive.ObjectID   `bson:"reviewedBy,omitempty" json:"reviewedBy,omitempty"`
+	CreatedAt    time.Time            `bson:"createdAt" json:"createdAt"`
+	UpdatedAt    time.Time            `bson:"updatedAt" json:"updatedAt"`
+}
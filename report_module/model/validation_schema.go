@@ -0,0 +1,33 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"net/http"
+)
+
+type CreateReportSchema struct {
+	Category types.ReportCategory `json:"category"`
+	Comment  string               `json:"comment"`
+}
+
+func NewCreateReportSchema() CreateReportSchema {
+	return CreateReportSchema{}
+}
+
+func (s CreateReportSchema) Validate() error {
+	categories := []types.ReportCategory{constant.PhishingReport, constant.MalwareReport, constant.SpamReport, constant.IllegalContentReport, constant.OtherReport}
+	if !helpers.InList(categories, s.Category, func(a types.ReportCategory, b types.ReportCategory) bool {
+		return a == b
+	}) {
+		return http_error.New(http.StatusBadRequest, "invalid report category")
+	}
+
+	if len(s.Comment) > constant.ReportCommentMaxLength {
+		return http_error.New(http.StatusBadRequest, "comment must be within 1000 characters")
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package module
+
+import (
+	"codedln/report_module/controller"
+	"codedln/report_module/model"
+	"codedln/report_module/repository"
+	"codedln/report_module/service"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/util/constant"
+	"context"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"net/http"
+)
+
+func ReportModule(router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Database, cfg *config.Config) {
+	collection := db.Collection(constant.ReportCollection)
+	if collection == nil {
+		log.Fatalf("%s does not exist:", constant.ReportCollection)
+	}
+
+	urlCollection := db.Collection(constant.UrlCollection)
+	if urlCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UrlCollection)
+	}
+
+	reportRepo := repository.New(collection, urlCollection)
+	if indexErr := reportRepo.CreateIndexes(context.Background()); indexErr != nil {
+		log.Fatalf("Error creating %s indexes", constant.ReportCollection)
+	}
+	reportService := service.New(reportRepo, cfg.Reports.Threshold)
+	reportController := controller.New(reportService)
+
+	reportRouter := router.PathPrefix("/report").Subrouter()
+
+	// Public so that any visitor can report a link, hence no api key is required.
+	reportRouter.HandleFunc("/{alias}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			reportController.CreateReport,
+			middleware.PayloadValidationMiddleware(model.NewCreateReportSchema),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Report),
+		))).Methods(http.MethodPost)
+}
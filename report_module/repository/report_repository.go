@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"codedln/report_module/model"
+	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	urlModel "codedln/url_module/model"
+	"codedln/util/constant"
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+)
+
+type ReportRepository interface {
+	CreateIndexes(ctx context.Context) error
+	CreateReport(ctx context.Context, report model.Report) (*model.Report, error)
+	CountReports(ctx context.Context, filter bson.D) (int64, error)
+	GetUrl(ctx context.Context, filter bson.D) (*urlModel.Url, error)
+	UpdateUrl(ctx context.Context, filter bson.D, update bson.D) error
+}
+
+type MongoReportRepository struct {
+	collection    *mongo.Collection
+	urlCollection *mongo.Collection
+}
+
+func New(collection *mongo.Collection, urlCollection *mongo.Collection) ReportRepository {
+	return &MongoReportRepository{
+		collection:    collection,
+		urlCollection: urlCollection,
+	}
+}
+
+func (r *MongoReportRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		// Enforced by the database rather than a lookup so that concurrent reports from one visitor still count once
+		Keys: bson.D{{"urlId", 1}, {"reporterHash", 1}},
+		Options: options.Index().SetName("open_report_per_reporter").SetUnique(true).
+			SetPartialFilterExpression(bson.D{{"state", constant.OpenReport}}),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to create report indexes")
+	}
+	return nil
+}
+
+func (r *MongoReportRepository) CreateReport(ctx context.Context, report model.Report) (*model.Report, error) {
+	res, err := r.collection.InsertOne(ctx, report)
+	if mongo.IsDuplicateKeyError(err) {
+		return nil, http_error.New(http.StatusConflict, "you have already reported this link")
+	}
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to create report")
+	}
+
+	report.ID = res.InsertedID.(primitive.ObjectID)
+	return &report, nil
+}
+
+func (r *MongoReportRepository) CountReports(ctx context.Context, filter bson.D) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to count reports")
+	}
+	return count, nil
+}
+
+func (r *MongoReportRepository) GetUrl(ctx context.Context, filter bson.D) (*urlModel.Url, error) {
+	res := r.urlCollection.FindOne(ctx, filter)
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	var url urlModel.Url
+	if decodeErr := res.Decode(&url); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get url")
+	}
+
+	return &url, nil
+}
+
+func (r *MongoReportRepository) UpdateUrl(ctx context.Context, filter bson.D, update bson.D) error {
+	_, err := r.urlCollection.UpdateOne(ctx, filter, update)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to update url")
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package service
+
+import (
+	"codedln/report_module/model"
+	"codedln/report_module/repository"
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"go.mongodb.org/mongo-driver/bson"
+	"net/http"
+	"time"
+)
+
+type ReportService struct {
+	repo      repository.ReportRepository
+	threshold int64
+}
+
+// New returns a report service that suspends a link once it has threshold open reports.
+func New(repo repository.ReportRepository, threshold int64) *ReportService {
+	return &ReportService{
+		repo:      repo,
+		threshold: threshold,
+	}
+}
+
+func (s *ReportService) CreateReport(ctx context.Context, alias string, payload model.CreateReportSchema, clientIP string) (*model.Report, error) {
+	// Trashed links no longer redirect, so there is nothing left to report
+	url, err := s.repo.GetUrl(ctx, bson.D{{"alias", alias}, {"deletedAt", bson.D{{"$exists", false}}}})
+	if err != nil {
+		return nil, err
+	}
+
+	if url == nil {
+		return nil, http_error.New(http.StatusNotFound, "no url found for the alias")
+	}
+
+	// Only a hash of the reporter's ip is kept, enough to stop one visitor from counting twice towards the threshold
+	h := sha256.Sum256([]byte(clientIP + ":" + url.ID.Hex()))
+	reporterHash := hex.EncodeToString(h[:])
+
+	report, err := s.repo.CreateReport(ctx, model.Report{
+		UrlId:        url.ID,
+		Alias:        url.Alias,
+		Category:     payload.Category,
+		Comment:      payload.Comment,
+		State:        constant.OpenReport,
+		ReporterHash: reporterHash,
+		CreatedAt:    time.Now().UTC(),
+		UpdatedAt:    time.Now().UTC(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	openReports, err := s.repo.CountReports(ctx, bson.D{{"urlId", url.ID}, {"state", constant.OpenReport}})
+	if err != nil {
+		return nil, err
+	}
+
+	if openReports >= s.threshold {
+		// Only active links are suspended so an admin decision such as a takedown is never overwritten
+		filter := bson.D{{"_id", url.ID}, {"status", bson.D{{"$in", bson.A{constant.ActiveUrl, nil}}}}}
+		update := bson.D{{"$set", bson.D{
+			{"status", constant.SuspendedUrl},
+			{"statusReason", constant.ReportSuspendedReason},
+			{"updatedAt", time.Now().UTC()},
+		}}}
+		if err = s.repo.UpdateUrl(ctx, filter, update); err != nil {
+			return nil, err
+		}
+	}
+
+	return report, nil
+}
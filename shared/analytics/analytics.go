@@ -0,0 +1,252 @@
+package analytics
+
+import (
+	"codedln/util/constant"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"github.com/redis/go-redis/v9"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Tracker keeps per-link daily click counts and unique visitor estimates in redis.
+// Visitors are counted with HyperLogLogs of a salted hash of their ip and user agent, so no raw personal data is stored.
+// Clicks the classifier flags as bots are kept under separate keys so they can be left out of stats.
+// A nil Tracker records nothing and reports empty stats.
+type Tracker struct {
+	client *redis.Client
+	bots   *BotClassifier
+
+	mu   sync.Mutex
+	salt string
+}
+
+// Day is the activity of a link on one UTC day.
+type Day struct {
+	Date           string `json:"date"`
+	Clicks         int64  `json:"clicks"`
+	UniqueVisitors int64  `json:"uniqueVisitors"`
+}
+
+// Range is the activity of one or more links over consecutive UTC days. UniqueVisitors counts each visitor once across the whole range.
+type Range struct {
+	Clicks         int64 `json:"clicks"`
+	UniqueVisitors int64 `json:"uniqueVisitors"`
+	Days           []Day `json:"days"`
+}
+
+// New returns a tracker that hashes visitors with salt. An empty salt is generated once and shared between instances through redis.
+func New(client *redis.Client, bots *BotClassifier, salt string) *Tracker {
+	return &Tracker{
+		client: client,
+		bots:   bots,
+		salt:   salt,
+	}
+}
+
+// IsBot reports whether a request looks like it came from a crawler, link unfurler, scanner or prefetch.
+func (t *Tracker) IsBot(method string, header http.Header) bool {
+	if t == nil {
+		return false
+	}
+	return t.bots.IsBot(method, header)
+}
+
+// Track records a visit to the link at the given time. A non-empty source also counts the visit as a referral from it.
+func (t *Tracker) Track(ctx context.Context, urlId string, ip string, userAgent string, source string, bot bool, at time.Time) error {
+	if t == nil {
+		return nil
+	}
+
+	visitor, err := t.visitorHash(ctx, ip, userAgent)
+	if err != nil {
+		return err
+	}
+
+	day := at.UTC().Format(time.DateOnly)
+	retention := constant.DailyStatsRetentionDays * 24 * time.Hour
+
+	pipe := t.client.TxPipeline()
+	pipe.PFAdd(ctx, allTimeKey(urlId, bot), visitor)
+	pipe.PFAdd(ctx, dailyVisitorsKey(urlId, day, bot), visitor)
+	pipe.Expire(ctx, dailyVisitorsKey(urlId, day, bot), retention)
+	pipe.HIncrBy(ctx, dailyClicksKey(urlId, bot), day, 1)
+	pipe.Expire(ctx, dailyClicksKey(urlId, bot), retention)
+	if source != "" {
+		pipe.HIncrBy(ctx, referralsKey(urlId, bot), source, 1)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Referrals returns how many of the links' clicks came from each source.
+func (t *Tracker) Referrals(ctx context.Context, urlIds []string, includeBots bool) (map[string]int64, error) {
+	referrals := map[string]int64{}
+	if t == nil || len(urlIds) == 0 {
+		return referrals, nil
+	}
+
+	pipe := t.client.Pipeline()
+	var counts []*redis.MapStringStringCmd
+	for _, bot := range segments(includeBots) {
+		for _, urlId := range urlIds {
+			counts = append(counts, pipe.HGetAll(ctx, referralsKey(urlId, bot)))
+		}
+	}
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for _, sources := range counts {
+		for source, raw := range sources.Val() {
+			count, _ := strconv.ParseInt(raw, 10, 64)
+			referrals[source] += count
+		}
+	}
+	return referrals, nil
+}
+
+// UniqueVisitors estimates how many different visitors the links have had between them.
+func (t *Tracker) UniqueVisitors(ctx context.Context, urlIds []string, includeBots bool) (int64, error) {
+	if t == nil || len(urlIds) == 0 {
+		return 0, nil
+	}
+
+	var keys []string
+	for _, bot := range segments(includeBots) {
+		for _, urlId := range urlIds {
+			keys = append(keys, allTimeKey(urlId, bot))
+		}
+	}
+	return t.client.PFCount(ctx, keys...).Result()
+}
+
+// Between returns the combined activity of the links from the start of from to the end of to, both UTC days.
+// The daily HyperLogLogs are merged by counting them together, so a visitor seen on several days or links is counted once.
+func (t *Tracker) Between(ctx context.Context, urlIds []string, from time.Time, to time.Time, includeBots bool) (*Range, error) {
+	result := &Range{Days: []Day{}}
+
+	var days []string
+	for day := from.UTC(); !day.After(to.UTC()); day = day.AddDate(0, 0, 1) {
+		days = append(days, day.Format(time.DateOnly))
+	}
+	if t == nil || len(days) == 0 || len(urlIds) == 0 {
+		return result, nil
+	}
+
+	pipe := t.client.Pipeline()
+
+	var clicks []*redis.SliceCmd
+	var allKeys []string
+	dailyKeys := make([][]string, len(days))
+	for _, bot := range segments(includeBots) {
+		for _, urlId := range urlIds {
+			clicks = append(clicks, pipe.HMGet(ctx, dailyClicksKey(urlId, bot), days...))
+			for i, day := range days {
+				dailyKeys[i] = append(dailyKeys[i], dailyVisitorsKey(urlId, day, bot))
+				allKeys = append(allKeys, dailyVisitorsKey(urlId, day, bot))
+			}
+		}
+	}
+
+	daily := make([]*redis.IntCmd, len(days))
+	for i, keys := range dailyKeys {
+		daily[i] = pipe.PFCount(ctx, keys...)
+	}
+	merged := pipe.PFCount(ctx, allKeys...)
+	if _, err := pipe.Exec(ctx); err != nil && err != redis.Nil {
+		return nil, err
+	}
+
+	for i, day := range days {
+		var dayClicks int64
+		for _, counts := range clicks {
+			if raw, ok := counts.Val()[i].(string); ok {
+				count, _ := strconv.ParseInt(raw, 10, 64)
+				dayClicks += count
+			}
+		}
+		result.Clicks += dayClicks
+		result.Days = append(result.Days, Day{
+			Date:           day,
+			Clicks:         dayClicks,
+			UniqueVisitors: daily[i].Val(),
+		})
+	}
+	result.UniqueVisitors = merged.Val()
+
+	return result, nil
+}
+
+// visitorHash identifies a visitor without keeping their ip or user agent.
+// The salt is the one the tracker was created with, or is generated once and shared between instances through redis.
+func (t *Tracker) visitorHash(ctx context.Context, ip string, userAgent string) (string, error) {
+	salt, err := t.visitorSalt(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	h := sha256.Sum256([]byte(salt + "|" + ip + "|" + userAgent))
+	return hex.EncodeToString(h[:]), nil
+}
+
+func (t *Tracker) visitorSalt(ctx context.Context) (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.salt != "" {
+		return t.salt, nil
+	}
+
+	random := make([]byte, 32)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+
+	// Whichever instance sets the salt first wins and the others read it back
+	if err := t.client.SetNX(ctx, constant.VisitorSaltKey, hex.EncodeToString(random), 0).Err(); err != nil {
+		return "", err
+	}
+	salt, err := t.client.Get(ctx, constant.VisitorSaltKey).Result()
+	if err != nil {
+		return "", err
+	}
+
+	t.salt = salt
+	return t.salt, nil
+}
+
+// segments lists which kinds of clicks to read: people only, or people and bots.
+func segments(includeBots bool) []bool {
+	if includeBots {
+		return []bool{false, true}
+	}
+	return []bool{false}
+}
+
+func allTimeKey(urlId string, bot bool) string {
+	return constant.VisitorsKeyPrefix + botSegment(bot) + urlId
+}
+
+func dailyVisitorsKey(urlId string, day string, bot bool) string {
+	return constant.VisitorsKeyPrefix + botSegment(bot) + urlId + ":" + day
+}
+
+func dailyClicksKey(urlId string, bot bool) string {
+	return constant.DailyClicksKeyPrefix + botSegment(bot) + urlId
+}
+
+func referralsKey(urlId string, bot bool) string {
+	return constant.ReferralsKeyPrefix + botSegment(bot) + urlId
+}
+
+func botSegment(bot bool) string {
+	if bot {
+		return constant.BotKeySegment
+	}
+	return ""
+}
@@ -0,0 +1,76 @@
+package analytics
+
+import (
+	"bufio"
+	_ "embed"
+	"net/http"
+	"os"
+	"strings"
+)
+
+//go:embed bots.txt
+var defaultSignatures string
+
+// BotClassifier flags requests from crawlers, link unfurlers, security scanners and browser prefetches.
+type BotClassifier struct {
+	signatures []string
+}
+
+// NewBotClassifier loads the built in signatures plus any listed in the file at path, in the same one per line format.
+// An empty path uses only the built in list.
+func NewBotClassifier(path string) (*BotClassifier, error) {
+	signatures := parseSignatures(defaultSignatures)
+
+	if path != "" {
+		extra, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		signatures = append(signatures, parseSignatures(string(extra))...)
+	}
+
+	return &BotClassifier{
+		signatures: signatures,
+	}, nil
+}
+
+func (c *BotClassifier) IsBot(method string, header http.Header) bool {
+	if c == nil {
+		return false
+	}
+
+	// Unfurlers often only ask for headers, and real browsers always send a user agent
+	userAgent := strings.ToLower(header.Get("User-Agent"))
+	if method == http.MethodHead || userAgent == "" {
+		return true
+	}
+
+	// Speculative loads from browsers and link previews are not visits
+	for _, name := range []string{"Purpose", "Sec-Purpose", "X-Purpose", "X-Moz"} {
+		purpose := strings.ToLower(header.Get(name))
+		if strings.Contains(purpose, "prefetch") || strings.Contains(purpose, "preview") {
+			return true
+		}
+	}
+
+	for _, signature := range c.signatures {
+		if strings.Contains(userAgent, signature) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func parseSignatures(list string) []string {
+	var signatures []string
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		signatures = append(signatures, line)
+	}
+	return signatures
+}
@@ -0,0 +1,316 @@
+package config
+
+import (
+	"bytes"
+	"codedln/util/constant"
+	"errors"
+	"fmt"
+	"github.com/BurntSushi/toml"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+	"io"
+	"io/fs"
+	"log/slog"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config holds every setting the service reads at startup.
+type Config struct {
+	Environment string     `yaml:"environment" toml:"environment"`
+	LogLevel    slog.Level `yaml:"logLevel" toml:"logLevel"`
+	Origin      string     `yaml:"origin" toml:"origin"` //allowed CORS origin
+	Server      Server     `yaml:"server" toml:"server"`
+	Tracing     Tracing    `yaml:"tracing" toml:"tracing"`
+	Mongo       Mongo      `yaml:"mongo" toml:"mongo"`
+	Redis       Redis      `yaml:"redis" toml:"redis"`
+	Auth        Auth       `yaml:"auth" toml:"auth"`
+	Links       Links      `yaml:"links" toml:"links"`
+	Reports     Reports    `yaml:"reports" toml:"reports"`
+	RateLimits  RateLimits `yaml:"rateLimits" toml:"rateLimits"`
+}
+
+type Server struct {
+	Addr             string         `yaml:"addr" toml:"addr"`
+	MetricsAddr      string         `yaml:"metricsAddr" toml:"metricsAddr"`
+	ReadTimeout      time.Duration  `yaml:"readTimeout" toml:"readTimeout"`
+	WriteTimeout     time.Duration  `yaml:"writeTimeout" toml:"writeTimeout"`
+	ReadinessTimeout time.Duration  `yaml:"readinessTimeout" toml:"readinessTimeout"` //allowed for every dependency to answer /readyz
+	DrainDelay       time.Duration  `yaml:"drainDelay" toml:"drainDelay"`             //readiness fails for this long before shutting down
+	TrustedProxies   []netip.Prefix `yaml:"trustedProxies" toml:"trustedProxies"`     //ranges whose X-Forwarded-For is believed
+}
+
+type Tracing struct {
+	Exporter    string `yaml:"exporter" toml:"exporter"`       //otlp, console or none, otlp when only an endpoint is set
+	Endpoint    string `yaml:"endpoint" toml:"endpoint"`       //full url spans are sent to over OTLP/HTTP
+	ServiceName string `yaml:"serviceName" toml:"serviceName"` //reported to the trace collector
+}
+
+type Mongo struct {
+	Url      string `yaml:"url" toml:"url"`
+	Database string `yaml:"database" toml:"database"`
+}
+
+type Redis struct {
+	Addr     string `yaml:"addr" toml:"addr"`
+	Username string `yaml:"username" toml:"username"`
+	Password string `yaml:"password" toml:"password"`
+}
+
+type Auth struct {
+	JwtSecret      string `yaml:"jwtSecret" toml:"jwtSecret"`
+	ClientKey      string `yaml:"clientKey" toml:"clientKey"`
+	GoogleClientId string `yaml:"googleClientId" toml:"googleClientId"`
+	AccessTokenTTL int    `yaml:"accessTokenTTL" toml:"accessTokenTTL"` //hours
+}
+
+type Links struct {
+	TrashRetentionDays int    `yaml:"trashRetentionDays" toml:"trashRetentionDays"`
+	AliasCooldownDays  int    `yaml:"aliasCooldownDays" toml:"aliasCooldownDays"`
+	BlocklistPath      string `yaml:"blocklistPath" toml:"blocklistPath"`
+	SafeBrowsingApiKey string `yaml:"safeBrowsingApiKey" toml:"safeBrowsingApiKey"`
+	SafeBrowsingUrl    string `yaml:"safeBrowsingUrl" toml:"safeBrowsingUrl"`
+	TemplatesDir       string `yaml:"templatesDir" toml:"templatesDir"`
+	BotSignaturesPath  string `yaml:"botSignaturesPath" toml:"botSignaturesPath"`
+	VisitorHashSalt    string `yaml:"visitorHashSalt" toml:"visitorHashSalt"`
+}
+
+type Reports struct {
+	Threshold int64 `yaml:"threshold" toml:"threshold"` //open reports before a link is suspended
+}
+
+// RateLimits are the tiers routes are rate limited with. Each is set in the config file as rate, burst and period.
+type RateLimits struct {
+	Strict   redis_rate.Limit `yaml:"strict" toml:"strict"`     //creating and changing things
+	Standard redis_rate.Limit `yaml:"standard" toml:"standard"` //reading a user's own data
+	Bulk     redis_rate.Limit `yaml:"bulk" toml:"bulk"`         //changes to many links at once
+	Public   redis_rate.Limit `yaml:"public" toml:"public"`     //redirects and public pages
+	Report   redis_rate.Limit `yaml:"report" toml:"report"`     //anonymous abuse reports
+}
+
+// Default returns the settings used when neither the config file nor the environment sets them.
+func Default() Config {
+	return Config{
+		Environment: constant.DevelopmentEnvironment,
+		LogLevel:    slog.LevelInfo,
+		Server: Server{
+			Addr:             constant.DefaultServerAddr,
+			MetricsAddr:      constant.DefaultMetricsAddr,
+			ReadTimeout:      constant.DefaultReadTimeout * time.Second,
+			WriteTimeout:     constant.DefaultWriteTimeout * time.Second,
+			ReadinessTimeout: constant.DefaultReadinessTimeout * time.Second,
+			DrainDelay:       constant.DefaultShutdownDrainDelay * time.Second,
+		},
+		Tracing: Tracing{
+			ServiceName: constant.ServiceName,
+		},
+		Auth: Auth{
+			AccessTokenTTL: constant.DefaultAccessTokenTTL,
+		},
+		Links: Links{
+			TrashRetentionDays: constant.DefaultTrashRetentionDays,
+			AliasCooldownDays:  constant.DefaultAliasCooldownDays,
+			SafeBrowsingUrl:    constant.DefaultSafeBrowsingUrl,
+		},
+		Reports: Reports{
+			Threshold: constant.DefaultReportThreshold,
+		},
+		RateLimits: RateLimits{
+			Strict:   redis_rate.Limit{Rate: 10, Burst: 5, Period: time.Minute * 2},
+			Standard: redis_rate.Limit{Rate: 100, Burst: 50, Period: time.Minute * 2},
+			Bulk:     redis_rate.Limit{Rate: 10, Burst: 50, Period: time.Minute * 2},
+			Public:   redis_rate.Limit{Rate: 1000, Burst: 500, Period: time.Minute * 2},
+			Report:   redis_rate.Limit{Rate: 5, Burst: 2, Period: time.Hour},
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, then the YAML or TOML file named by CONFIG_FILE when there is one,
+// then the environment. Each source overrides the one before it. The env files, or .env in the working directory when
+// none are given, are read into the environment first without overriding variables that are already set, and are
+// skipped when they do not exist. Every invalid or missing value is reported in the one error.
+func Load(envFiles ...string) (*Config, error) {
+	if err := godotenv.Load(envFiles...); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("unable to read .env: %w", err)
+	}
+
+	c := Default()
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := c.readFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	var errs []error
+	env := func(key string, apply func(value string) error) {
+		// Empty variables count as unset, as they always have
+		if value := os.Getenv(key); value != "" {
+			if err := apply(value); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", key, err))
+			}
+		}
+	}
+
+	env("ENVIRONMENT", setString(&c.Environment))
+	env("LOG_LEVEL", func(value string) error { return c.LogLevel.UnmarshalText([]byte(value)) })
+	env("ORIGIN", setString(&c.Origin))
+	env("SERVER_ADDR", setString(&c.Server.Addr))
+	env("METRICS_ADDR", setString(&c.Server.MetricsAddr))
+	env("SERVER_READ_TIMEOUT", setDuration(&c.Server.ReadTimeout))
+	env("SERVER_WRITE_TIMEOUT", setDuration(&c.Server.WriteTimeout))
+	env("READINESS_TIMEOUT", setDuration(&c.Server.ReadinessTimeout))
+	env("SHUTDOWN_DRAIN_DELAY", setDuration(&c.Server.DrainDelay))
+	env("TRUSTED_PROXIES", setPrefixes(&c.Server.TrustedProxies))
+	env("OTEL_TRACES_EXPORTER", setString(&c.Tracing.Exporter))
+	env("OTEL_EXPORTER_OTLP_ENDPOINT", func(value string) error {
+		c.Tracing.Endpoint = strings.TrimSuffix(value, "/") + "/v1/traces"
+		return nil
+	})
+	env("OTEL_EXPORTER_OTLP_TRACES_ENDPOINT", setString(&c.Tracing.Endpoint))
+	env("OTEL_SERVICE_NAME", setString(&c.Tracing.ServiceName))
+	env("MONGO_DB_URL", setString(&c.Mongo.Url))
+	env("DATABASE_NAME", setString(&c.Mongo.Database))
+	env("REDIS_ADDR", setString(&c.Redis.Addr))
+	env("REDIS_USERNAME", setString(&c.Redis.Username))
+	env("REDIS_PWD", setString(&c.Redis.Password))
+	env("JWT_SECRET", setString(&c.Auth.JwtSecret))
+	env("CLIENT_KEY", setString(&c.Auth.ClientKey))
+	env("GOOGLE_CLIENT_ID", setString(&c.Auth.GoogleClientId))
+	env("ACCESS_TOKEN_TTL", setInt(&c.Auth.AccessTokenTTL))
+	env("TRASH_RETENTION_DAYS", setInt(&c.Links.TrashRetentionDays))
+	env("ALIAS_COOLDOWN_DAYS", setInt(&c.Links.AliasCooldownDays))
+	env("BLOCKLIST_PATH", setString(&c.Links.BlocklistPath))
+	env("SAFE_BROWSING_API_KEY", setString(&c.Links.SafeBrowsingApiKey))
+	env("SAFE_BROWSING_URL", setString(&c.Links.SafeBrowsingUrl))
+	env("TEMPLATES_DIR", setString(&c.Links.TemplatesDir))
+	env("BOT_SIGNATURES_PATH", setString(&c.Links.BotSignaturesPath))
+	env("VISITOR_HASH_SALT", setString(&c.Links.VisitorHashSalt))
+	env("REPORT_THRESHOLD", func(value string) (err error) { c.Reports.Threshold, err = strconv.ParseInt(value, 10, 64); return err })
+
+	if err := errors.Join(append(errs, c.Validate())...); err != nil {
+		return nil, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return &c, nil
+}
+
+// Production reports whether the service is running in production.
+func (c *Config) Production() bool {
+	return c.Environment == constant.ProductionEnvironment
+}
+
+// Validate reports every setting that is missing or out of range, naming the environment variable that sets it.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	check(c.Environment == constant.DevelopmentEnvironment || c.Environment == constant.ProductionEnvironment,
+		"ENVIRONMENT must be %q or %q, got %q", constant.DevelopmentEnvironment, constant.ProductionEnvironment, c.Environment)
+	check(c.Server.Addr != "", "SERVER_ADDR is required")
+	check(c.Server.MetricsAddr != "", "METRICS_ADDR is required")
+	check(c.Server.ReadTimeout > 0, "SERVER_READ_TIMEOUT must be positive")
+	check(c.Server.WriteTimeout > 0, "SERVER_WRITE_TIMEOUT must be positive")
+	check(c.Server.ReadinessTimeout > 0, "READINESS_TIMEOUT must be positive")
+	check(c.Server.DrainDelay >= 0, "SHUTDOWN_DRAIN_DELAY must not be negative")
+	check(slices.Contains([]string{"", "none", "otlp", "console"}, c.Tracing.Exporter),
+		"OTEL_TRACES_EXPORTER must be otlp, console or none, got %q", c.Tracing.Exporter)
+	check(c.Tracing.ServiceName != "", "OTEL_SERVICE_NAME is required")
+	check(c.Mongo.Url != "", "MONGO_DB_URL is required")
+	check(c.Mongo.Database != "", "DATABASE_NAME is required")
+	check(c.Redis.Addr != "", "REDIS_ADDR is required")
+	check(c.Auth.JwtSecret != "", "JWT_SECRET is required")
+	check(c.Auth.ClientKey != "", "CLIENT_KEY is required")
+	check(c.Auth.GoogleClientId != "", "GOOGLE_CLIENT_ID is required")
+	check(c.Auth.AccessTokenTTL > 0, "ACCESS_TOKEN_TTL must be a positive number of hours")
+	check(c.Links.TrashRetentionDays > 0, "TRASH_RETENTION_DAYS must be a positive number of days")
+	check(c.Links.AliasCooldownDays > 0, "ALIAS_COOLDOWN_DAYS must be a positive number of days")
+	check(c.Reports.Threshold > 0, "REPORT_THRESHOLD must be positive")
+
+	limits := map[string]redis_rate.Limit{
+		"strict":   c.RateLimits.Strict,
+		"standard": c.RateLimits.Standard,
+		"bulk":     c.RateLimits.Bulk,
+		"public":   c.RateLimits.Public,
+		"report":   c.RateLimits.Report,
+	}
+	for _, name := range []string{"strict", "standard", "bulk", "public", "report"} {
+		limit := limits[name]
+		check(limit.Rate > 0 && limit.Burst > 0 && limit.Period > 0, "rateLimits.%s needs a positive rate, burst and period", name)
+	}
+
+	return errors.Join(errs...)
+}
+
+// readFile overlays the settings in a YAML or TOML file. Unknown keys are rejected so a misspelt setting is not silently ignored.
+func (c *Config) readFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("unable to read config file: %w", err)
+	}
+
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err = decoder.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+	case ".toml":
+		meta, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("invalid config file %s: %w", path, err)
+		}
+		if undecoded := meta.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("invalid config file %s: unknown setting %s", path, undecoded[0])
+		}
+	default:
+		return fmt.Errorf("config file %s must be .yaml, .yml or .toml", path)
+	}
+	return nil
+}
+
+func setString(field *string) func(string) error {
+	return func(value string) error {
+		*field = value
+		return nil
+	}
+}
+
+func setInt(field *int) func(string) error {
+	return func(value string) (err error) {
+		*field, err = strconv.Atoi(value)
+		return err
+	}
+}
+
+func setDuration(field *time.Duration) func(string) error {
+	return func(value string) (err error) {
+		*field, err = time.ParseDuration(value)
+		return err
+	}
+}
+
+// setPrefixes reads a comma separated list of CIDR ranges.
+func setPrefixes(field *[]netip.Prefix) func(string) error {
+	return func(value string) error {
+		var prefixes []netip.Prefix
+		for _, part := range strings.Split(value, ",") {
+			prefix, err := netip.ParsePrefix(strings.TrimSpace(part))
+			if err != nil {
+				return err
+			}
+			prefixes = append(prefixes, prefix)
+		}
+		*field = prefixes
+		return nil
+	}
+}
@@ -0,0 +1,162 @@
+package healthcheck
+
+import (
+	"codedln/shared/safehttp"
+	"codedln/util/constant"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// Result is the outcome of checking one destination.
+type Result struct {
+	StatusCode    int
+	Latency       time.Duration
+	RedirectChain []string
+	Error         string
+	CheckedAt     time.Time
+}
+
+// Healthy reports whether the destination answered without an error status once redirects were followed.
+func (r Result) Healthy() bool {
+	return r.Error == "" && r.StatusCode > 0 && r.StatusCode < http.StatusBadRequest
+}
+
+// Checker requests destinations to see whether they are still reachable.
+// Requests to the same host are spaced out by the host interval, however many checks run at once.
+type Checker struct {
+	client       *http.Client
+	hostInterval time.Duration
+	mu           sync.Mutex
+	nextSlot     map[string]time.Time
+}
+
+// New returns a checker using the given client, or a client that refuses internal addresses when it is nil.
+// Redirects are always followed by the checker itself so each hop can be recorded and rate limited.
+func New(client *http.Client, timeout time.Duration, hostInterval time.Duration) *Checker {
+	if client == nil {
+		client = safehttp.NewClient(timeout)
+	}
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Checker{
+		client:       &noRedirects,
+		hostInterval: hostInterval,
+		nextSlot:     map[string]time.Time{},
+	}
+}
+
+// Check requests the destination with HEAD, falling back to GET for servers that do not support it, and follows any redirects.
+func (c *Checker) Check(ctx context.Context, destination string) Result {
+	result := Result{CheckedAt: time.Now().UTC()}
+
+	current, err := url.Parse(destination)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	for hop := 0; ; hop++ {
+		if err = c.wait(ctx, current.Host); err != nil {
+			result.Error = err.Error()
+			return result
+		}
+
+		start := time.Now()
+		resp, reqErr := c.request(ctx, current.String())
+		result.Latency += time.Since(start)
+		if reqErr != nil {
+			result.Error = reqErr.Error()
+			return result
+		}
+		result.StatusCode = resp.StatusCode
+
+		location := resp.Header.Get("Location")
+		if resp.StatusCode < 300 || resp.StatusCode >= 400 || location == "" {
+			return result
+		}
+
+		if hop == constant.MaxHealthCheckRedirects {
+			result.Error = fmt.Sprintf("stopped after %d redirects", constant.MaxHealthCheckRedirects)
+			return result
+		}
+
+		next, parseErr := current.Parse(location)
+		if parseErr != nil {
+			result.Error = "invalid redirect location: " + parseErr.Error()
+			return result
+		}
+		current = next
+		result.RedirectChain = append(result.RedirectChain, current.String())
+	}
+}
+
+// request sends a HEAD request and retries with GET when the server rejects HEAD or fails to answer it.
+func (c *Checker) request(ctx context.Context, destination string) (*http.Response, error) {
+	resp, err := c.do(ctx, http.MethodHead, destination)
+	if err == nil && resp.StatusCode != http.StatusMethodNotAllowed && resp.StatusCode != http.StatusNotImplemented {
+		return resp, nil
+	}
+	if errors.Is(err, safehttp.ErrForbiddenAddress) || ctx.Err() != nil {
+		return nil, err
+	}
+	return c.do(ctx, http.MethodGet, destination)
+}
+
+func (c *Checker) do(ctx context.Context, method string, destination string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, destination, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "codedln-linkcheck/1.0")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	// Reading a little of the body lets the connection be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, constant.HealthCheckDrainBytes))
+	_ = resp.Body.Close()
+	return resp, nil
+}
+
+// wait blocks until the host's next request slot and books the one after it.
+func (c *Checker) wait(ctx context.Context, host string) error {
+	if c.hostInterval <= 0 {
+		return nil
+	}
+
+	c.mu.Lock()
+	now := time.Now()
+	slot := c.nextSlot[host]
+	if slot.Before(now) {
+		slot = now
+	}
+	c.nextSlot[host] = slot.Add(c.hostInterval)
+	// Hosts whose slots have passed no longer need remembering
+	if len(c.nextSlot) > 1024 {
+		for known, next := range c.nextSlot {
+			if next.Before(now) {
+				delete(c.nextSlot, known)
+			}
+		}
+	}
+	c.mu.Unlock()
+
+	timer := time.NewTimer(time.Until(slot))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,44 @@
+package logging
+
+import (
+	"codedln/util/constant"
+	"context"
+	"log/slog"
+	"os"
+)
+
+// New builds the service's logger, writing lines at level and above. Logs are JSON in production so they can be
+// indexed, and plain text anywhere else.
+func New(level slog.Level, production bool) *slog.Logger {
+	options := &slog.HandlerOptions{Level: level, AddSource: true}
+
+	if production {
+		return slog.New(slog.NewJSONHandler(os.Stdout, options))
+	}
+	return slog.New(slog.NewTextHandler(os.Stdout, options))
+}
+
+// FromContext returns the logger of the request the context belongs to, which tags every line with its request id.
+// Outside of a request it returns the default logger.
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(constant.LoggerKey).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
+
+// WithLogger returns a copy of the context carrying the logger.
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, constant.LoggerKey, logger)
+}
+
+// With returns a copy of the context whose logger adds the attributes to every line.
+func With(ctx context.Context, args ...any) context.Context {
+	return WithLogger(ctx, FromContext(ctx).With(args...))
+}
+
+// RequestId returns the id of the request the context belongs to, or an empty string outside of a request.
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(constant.RequestIdKey).(string)
+	return id
+}
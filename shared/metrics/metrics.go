@@ -0,0 +1,158 @@
+package metrics
+
+import (
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"errors"
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/event"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Registry holds every metric the service exposes. It is separate from the prometheus default registry so
+// nothing a dependency registers globally ends up exposed by accident.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "codedln_http_requests_total",
+		Help: "HTTP requests handled, by method, route template and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "codedln_http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by method, route template and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	redirects = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "codedln_redirects_total",
+		Help: "Short link visits, by whether a link was found and what was done with the visit.",
+	}, []string{"result"})
+
+	rateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "codedln_rate_limited_total",
+		Help: "Requests turned away by the rate limiter, by route template.",
+	}, []string{"route"})
+
+	mongoDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "codedln_mongodb_command_duration_seconds",
+		Help:    "Time taken by MongoDB commands, by command name and outcome.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"command", "status"})
+
+	redisDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "codedln_redis_command_duration_seconds",
+		Help:    "Time taken by Redis commands, by command name and outcome. Pipelines are recorded as one pipeline command.",
+		Buckets: []float64{.0001, .00025, .0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5},
+	}, []string{"command", "status"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpDuration,
+		redirects,
+		rateLimited,
+		mongoDuration,
+		redisDuration,
+	)
+}
+
+// Handler serves the metrics in the prometheus text format.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// Middleware records the count and latency of every request the router matched. Routes are labeled with their
+// template rather than the requested path, so every short link is counted under /{alias} instead of a label of its own.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := helpers.NewStatusRecorder(w)
+
+		next.ServeHTTP(recorder, r)
+
+		labels := prometheus.Labels{"method": r.Method, "route": Route(r), "status": strconv.Itoa(recorder.Status())}
+		httpRequests.With(labels).Inc()
+		httpDuration.With(labels).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Route returns the template of the route that matched the request, or "unmatched".
+func Route(r *http.Request) string {
+	if route := mux.CurrentRoute(r); route != nil {
+		if template, err := route.GetPathTemplate(); err == nil {
+			return template
+		}
+	}
+	return "unmatched"
+}
+
+// RecordRedirect counts a short link visit.
+func RecordRedirect(result types.RedirectResult) {
+	redirects.WithLabelValues(string(result)).Inc()
+}
+
+// RecordRateLimited counts a request turned away by the rate limiter.
+func RecordRateLimited(r *http.Request) {
+	rateLimited.WithLabelValues(Route(r)).Inc()
+}
+
+// MongoMonitor times every command the MongoDB driver sends.
+func MongoMonitor() *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			mongoDuration.WithLabelValues(e.CommandName, "ok").Observe(e.Duration.Seconds())
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			mongoDuration.WithLabelValues(e.CommandName, "error").Observe(e.Duration.Seconds())
+		},
+	}
+}
+
+// RedisHook times every command and pipeline sent through a go-redis client.
+func RedisHook() redis.Hook {
+	return redisHook{}
+}
+
+type redisHook struct{}
+
+func (redisHook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+func (redisHook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmd)
+		redisDuration.WithLabelValues(cmd.Name(), redisStatus(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+func (redisHook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		start := time.Now()
+		err := next(ctx, cmds)
+		redisDuration.WithLabelValues("pipeline", redisStatus(err)).Observe(time.Since(start).Seconds())
+		return err
+	}
+}
+
+// redisStatus treats a missing key as a successful command, since that is an answer rather than a failure.
+func redisStatus(err error) string {
+	if err != nil && !errors.Is(err, redis.Nil) {
+		return "error"
+	}
+	return "ok"
+}
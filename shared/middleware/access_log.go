@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"codedln/shared/logging"
+	"codedln/shared/metrics"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// AccessLogMiddleware writes one line for every request once it has been handled. It must run inside
+// RequestIdMiddleware so the line carries the request id.
+func AccessLogMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := helpers.NewStatusRecorder(w)
+
+		// The user is only known once AuthenticationMiddleware has run, so it is filled in further down the chain
+		entry := &types.AccessLog{}
+		ctx := context.WithValue(r.Context(), constant.AccessLogKey, entry)
+
+		next.ServeHTTP(recorder, r.WithContext(ctx))
+
+		status := recorder.Status()
+		level := slog.LevelInfo
+		if status >= http.StatusInternalServerError {
+			level = slog.LevelError
+		}
+		logging.FromContext(ctx).LogAttrs(ctx, level, "request",
+			slog.String("method", r.Method),
+			slog.String("route", metrics.Route(r)),
+			slog.String("path", r.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("user_id", entry.UserId),
+			slog.String("ip", helpers.GetClientIP(r)),
+		)
+	})
+}
@@ -2,48 +2,87 @@ package middleware
 
 import (
 	"codedln/shared/http_error"
+	"codedln/shared/logging"
 	"codedln/util/constant"
 	"codedln/util/types"
 	"context"
 	"errors"
 	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 	"net/http"
-	"os"
 )
 
-func AuthenticationMiddleware(next types.HTTPHandler) types.HTTPHandler {
-	return func(w http.ResponseWriter, r *http.Request) error {
-		cookie, cookieErr := r.Cookie(constant.JwtCookieName)
-		switch {
-		case errors.Is(cookieErr, http.ErrNoCookie):
-			return http_error.New(http.StatusUnauthorized, "no authentication cookie provided")
-		case cookieErr != nil:
-			return http_error.New(http.StatusUnauthorized, "must be authenticated")
-		}
+// AuthenticationMiddleware accepts requests carrying an access token signed with the secret.
+// The user's role and suspension are read from the user collection on every request so that suspending
+// or deleting a user locks them out immediately instead of when their access token expires.
+func AuthenticationMiddleware(collection *mongo.Collection, secret string) types.Middleware {
+	return func(next types.HTTPHandler) types.HTTPHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			cookie, cookieErr := r.Cookie(constant.JwtCookieName)
+			switch {
+			case errors.Is(cookieErr, http.ErrNoCookie):
+				return http_error.New(http.StatusUnauthorized, "no authentication cookie provided")
+			case cookieErr != nil:
+				return http_error.New(http.StatusUnauthorized, "must be authenticated")
+			}
 
-		err := cookie.Valid()
-		if err != nil {
-			return http_error.New(http.StatusUnauthorized, "cookie expired")
-		}
+			err := cookie.Valid()
+			if err != nil {
+				return http_error.New(http.StatusUnauthorized, "cookie expired")
+			}
 
-		accessToken := cookie.Value
-		jwtClaim := &types.JWTClaim{}
-		token, tokenErr := jwt.ParseWithClaims(accessToken, jwtClaim, func(token *jwt.Token) (interface{}, error) {
-			return []byte(os.Getenv("JWT_SECRET")), nil
-		})
-
-		switch {
-		case errors.Is(tokenErr, jwt.ErrTokenExpired):
-			return http_error.New(http.StatusUnauthorized, "access token expired")
-		case tokenErr != nil:
-			return http_error.New(http.StatusUnauthorized, "invalid authentication token")
-		}
+			accessToken := cookie.Value
+			jwtClaim := &types.JWTClaim{}
+			token, tokenErr := jwt.ParseWithClaims(accessToken, jwtClaim, func(token *jwt.Token) (interface{}, error) {
+				return []byte(secret), nil
+			})
 
-		var ctx context.Context
-		if claim, ok := token.Claims.(*types.JWTClaim); ok {
-			ctx = context.WithValue(r.Context(), constant.AuthUserKey, types.AuthUser{UserId: claim.UserId})
-		}
+			switch {
+			case errors.Is(tokenErr, jwt.ErrTokenExpired):
+				return http_error.New(http.StatusUnauthorized, "access token expired")
+			case tokenErr != nil:
+				return http_error.New(http.StatusUnauthorized, "invalid authentication token")
+			}
+
+			claim, ok := token.Claims.(*types.JWTClaim)
+			if !ok {
+				return http_error.New(http.StatusUnauthorized, "invalid authentication token")
+			}
+
+			userId, err := primitive.ObjectIDFromHex(claim.UserId)
+			if err != nil {
+				return http_error.New(http.StatusUnauthorized, "invalid authentication token")
+			}
 
-		return next(w, r.WithContext(ctx))
+			var user struct {
+				Role      types.Role `bson:"role"`
+				Suspended bool       `bson:"suspended"`
+			}
+
+			opts := options.FindOne().SetProjection(bson.D{{"role", 1}, {"suspended", 1}})
+			findErr := collection.FindOne(r.Context(), bson.D{{"_id", userId}}, opts).Decode(&user)
+			switch {
+			case errors.Is(findErr, mongo.ErrNoDocuments):
+				return http_error.New(http.StatusUnauthorized, "user not found")
+			case findErr != nil:
+				logging.FromContext(r.Context()).Error("unable to authenticate user", "error", findErr)
+				return http_error.New(http.StatusInternalServerError, "unable to authenticate user")
+			}
+
+			if user.Suspended {
+				return http_error.New(http.StatusForbidden, "account suspended")
+			}
+
+			ctx := context.WithValue(r.Context(), constant.AuthUserKey, types.AuthUser{UserId: claim.UserId, Role: user.Role})
+			ctx = logging.With(ctx, "user_id", claim.UserId)
+			if entry, ok := ctx.Value(constant.AccessLogKey).(*types.AccessLog); ok {
+				entry.UserId = claim.UserId
+			}
+
+			return next(w, r.WithContext(ctx))
+		}
 	}
 }
@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"net/http"
+)
+
+// AuthorizationMiddleware only lets through authenticated users holding one of the given roles.
+// The role is the one AuthenticationMiddleware read from the user collection for this request, so
+// demoting a user takes effect immediately instead of when their access token expires.
+// It must run after AuthenticationMiddleware.
+func AuthorizationMiddleware(roles ...types.Role) types.Middleware {
+	return func(next types.HTTPHandler) types.HTTPHandler {
+		return func(w http.ResponseWriter, r *http.Request) error {
+			value := r.Context().Value(constant.AuthUserKey)
+			if value == nil {
+				return http_error.New(http.StatusUnauthorized, "must be authenticated")
+			}
+
+			authUser, ok := value.(types.AuthUser)
+			if !ok {
+				return http_error.New(http.StatusBadRequest, "invalid user id")
+			}
+
+			if !helpers.InList(roles, authUser.Role, func(a types.Role, b types.Role) bool {
+				return a == b
+			}) {
+				return http_error.New(http.StatusForbidden, "insufficient permissions")
+			}
+
+			return next(w, r)
+		}
+	}
+}
@@ -1,6 +1,10 @@
 package middleware
 
-import "codedln/util/types"
+import (
+	"codedln/util/types"
+	"github.com/gorilla/mux"
+	"net/http"
+)
 
 // ChainMiddlewares applies all provided middlewares to a given handler
 func ChainMiddlewares(handler types.HTTPHandler, middlewares ...types.Middleware) types.HTTPHandler {
@@ -9,3 +13,13 @@ func ChainMiddlewares(handler types.HTTPHandler, middlewares ...types.Middleware
 	}
 	return handler
 }
+
+// WrapHandler applies router middlewares to a handler in the same order as Router.Use, the first one running first.
+// The router only runs its middlewares on matched routes, so this is how its not found and method not allowed
+// handlers get them too.
+func WrapHandler(handler http.Handler, middlewares ...mux.MiddlewareFunc) http.Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+	return handler
+}
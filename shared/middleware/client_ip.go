@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"codedln/util/constant"
+	"context"
+	"github.com/gorilla/mux"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// ClientIPMiddleware resolves the address of the client once for everything that logs, hashes or rate limits by it.
+// Forwarding headers are only believed when the connection comes from one of the trusted proxies, so a client
+// talking to the service directly cannot pose as someone else.
+func ClientIPMiddleware(trustedProxies []netip.Prefix) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), constant.ClientIPKey, clientIP(r, trustedProxies))
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// clientIP walks X-Forwarded-For from the right, since each proxy appends the address it received the request from,
+// and stops at the first address that is not a trusted proxy. Anything further left was sent by the client.
+func clientIP(r *http.Request, trustedProxies []netip.Prefix) string {
+	remote, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		remote = r.RemoteAddr
+	}
+	if !trusted(remote, trustedProxies) {
+		return remote
+	}
+
+	forwarded := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(forwarded[i])
+		if hop == "" {
+			continue
+		}
+		addr, parseErr := netip.ParseAddr(hop)
+		if parseErr != nil {
+			break
+		}
+		if !trusted(hop, trustedProxies) {
+			return addr.Unmap().String()
+		}
+	}
+
+	if addr, parseErr := netip.ParseAddr(strings.TrimSpace(r.Header.Get("X-Real-IP"))); parseErr == nil {
+		return addr.Unmap().String()
+	}
+	return remote
+}
+
+func trusted(ip string, trustedProxies []netip.Prefix) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
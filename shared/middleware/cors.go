@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gorilla/mux"
+	"net/http"
+)
+
+// CorsMiddleware lets the frontend at origin call the API with its cookies. It runs on the router so every response,
+// preflight answers included, carries the same headers.
+func CorsMiddleware(origin string) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
+			w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+			w.Header().Set("Access-Control-Allow-Credentials", "true")
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -2,27 +2,69 @@ package middleware
 
 import (
 	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	"codedln/shared/metrics"
+	"codedln/shared/tracing"
 	"codedln/util/helpers"
 	"codedln/util/types"
 	"errors"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
 	"net/http"
 	"time"
 )
 
 func ExceptionMiddleware(handler types.HTTPHandler) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// The span continues the caller's trace when the request carries a traceparent header, and its context is
+		// handed down the handler chain so database and cache calls are recorded beneath it
+		route := metrics.Route(r)
+		ctx := otel.GetTextMapPropagator().Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+		ctx, span := tracing.Tracer().Start(ctx, r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				semconv.HTTPRequestMethodKey.String(r.Method),
+				semconv.HTTPRoute(route),
+				semconv.URLPath(r.URL.Path),
+				semconv.ClientAddress(helpers.GetClientIP(r)),
+				semconv.UserAgentOriginal(r.UserAgent()),
+			))
+		defer span.End()
+		if spanContext := span.SpanContext(); spanContext.IsValid() {
+			ctx = logging.With(ctx, "trace_id", spanContext.TraceID().String())
+		}
+		r = r.WithContext(ctx)
+		recorder := helpers.NewStatusRecorder(w)
+		w = recorder
+
 		err := handler(w, r)
 		if err != nil {
 			path := r.URL.Path
 			method := r.Method
+			// The request id lets a caller quote the failure so it can be found in the logs
+			requestId := logging.RequestId(ctx)
 			var httpErr *http_error.HTTPError
 			if errors.As(err, &httpErr) {
 				// Construct and send the JSON response with the status code and message from the error
-				_ = helpers.JSONResponse(w, httpErr.StatusCode, map[string]interface{}{"message": httpErr.Message, "statusCode": httpErr.StatusCode, "path": path, "method": method, "timestamp": time.Now().UTC()})
+				_ = helpers.JSONResponse(w, httpErr.StatusCode, map[string]interface{}{"message": httpErr.Message, "statusCode": httpErr.StatusCode, "path": path, "method": method, "requestId": requestId, "timestamp": time.Now().UTC()})
 			} else {
 				// For non-HTTPError, send a generic server error
-				_ = helpers.JSONResponse(w, http.StatusInternalServerError, map[string]interface{}{"message": "Internal Server Error", "statusCode": 500, "path": path, "method": method, "timestamp": time.Now().UTC()})
+				logging.FromContext(ctx).Error("unhandled error", "error", err)
+				_ = helpers.JSONResponse(w, http.StatusInternalServerError, map[string]interface{}{"message": "Internal Server Error", "statusCode": 500, "path": path, "method": method, "requestId": requestId, "timestamp": time.Now().UTC()})
+			}
+		}
+
+		// Client errors are the caller's fault, so only server errors mark the span as failed
+		status := recorder.Status()
+		span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+		if status >= http.StatusInternalServerError {
+			if err != nil {
+				span.RecordError(err)
 			}
+			span.SetStatus(codes.Error, http.StatusText(status))
 		}
 	}
 }
@@ -2,6 +2,7 @@ package middleware
 
 import (
 	"codedln/shared/http_error"
+	"codedln/shared/metrics"
 	"codedln/util/helpers"
 	"codedln/util/types"
 	"fmt"
@@ -29,6 +30,7 @@ func RateLimitMiddleware(rateLimiter *redis_rate.Limiter, limit redis_rate.Limit
 			if res.Allowed == 0 {
 				seconds := int(res.RetryAfter / time.Second)
 				h.Set("RateLimit-RetryAfter", strconv.Itoa(seconds))
+				metrics.RecordRateLimited(r)
 				return http_error.New(429, "too many request")
 			}
 			return next(w, r)
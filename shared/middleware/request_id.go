@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"codedln/shared/logging"
+	"codedln/util/constant"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+// RequestIdMiddleware tags the request with the X-Request-ID sent by the caller, or a new one when there is none, and
+// echoes it back in the response. The request's context carries a logger that adds the id to every line it writes.
+func RequestIdMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestId := r.Header.Get(constant.RequestIdHeader)
+		if !validRequestId(requestId) {
+			requestId = newRequestId()
+		}
+		w.Header().Set(constant.RequestIdHeader, requestId)
+
+		ctx := context.WithValue(r.Context(), constant.RequestIdKey, requestId)
+		ctx = logging.With(ctx, "request_id", requestId)
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// validRequestId accepts ids of a sensible length made only of characters that are safe to echo into headers and logs.
+func validRequestId(id string) bool {
+	if id == "" || len(id) > constant.RequestIdMaxLength {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
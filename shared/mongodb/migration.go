@@ -0,0 +1,35 @@
+package mongodb
+
+import (
+	"codedln/util/constant"
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"time"
+)
+
+// RunOnce applies a data migration unless it is already recorded in the migrations collection,
+// and records it once it succeeds so later startups skip it.
+// Migrations must be safe to repeat, since two instances starting together can both apply one.
+func RunOnce(ctx context.Context, db *mongo.Database, name string, migrate func(ctx context.Context) error) error {
+	migrations := db.Collection(constant.MigrationCollection)
+
+	err := migrations.FindOne(ctx, bson.D{{"_id", name}}).Err()
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, mongo.ErrNoDocuments) {
+		return err
+	}
+
+	if err = migrate(ctx); err != nil {
+		return err
+	}
+
+	_, err = migrations.InsertOne(ctx, bson.D{{"_id", name}, {"appliedAt", time.Now().UTC()}})
+	if mongo.IsDuplicateKeyError(err) {
+		return nil
+	}
+	return err
+}
@@ -1,21 +1,51 @@
 package mongodb
 
 import (
+	"codedln/shared/config"
+	"codedln/shared/metrics"
+	"codedln/shared/tracing"
 	"context"
+	"go.mongodb.org/mongo-driver/event"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"log"
-	"os"
 )
 
-func ConnectToDatabase() *mongo.Client {
+func ConnectToDatabase(cfg config.Mongo) *mongo.Client {
 
 	client, err := mongo.Connect(
 		context.TODO(),
-		options.Client().ApplyURI(os.Getenv("MONGO_DB_URL")))
+		options.Client().ApplyURI(cfg.Url).SetMonitor(chainMonitors(tracing.MongoMonitor(), metrics.MongoMonitor())))
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	return client
 }
+
+// chainMonitors passes every command event to each of the monitors in turn, since the driver only accepts one.
+func chainMonitors(monitors ...*event.CommandMonitor) *event.CommandMonitor {
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Started != nil {
+					monitor.Started(ctx, e)
+				}
+			}
+		},
+		Succeeded: func(ctx context.Context, e *event.CommandSucceededEvent) {
+			for _, monitor := range monitors {
+				if monitor.Succeeded != nil {
+					monitor.Succeeded(ctx, e)
+				}
+			}
+		},
+		Failed: func(ctx context.Context, e *event.CommandFailedEvent) {
+			for _, monitor := range monitors {
+				if monitor.Failed != nil {
+					monitor.Failed(ctx, e)
+				}
+			}
+		},
+	}
+}
@@ -0,0 +1,168 @@
+package opengraph
+
+import (
+	"codedln/shared/safehttp"
+	"codedln/util/constant"
+	"context"
+	"errors"
+	"fmt"
+	"golang.org/x/net/html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ErrNotHTML is returned when the destination does not serve an html page.
+var ErrNotHTML = errors.New("destination is not an html page")
+
+// Metadata is what a page says about itself in its head.
+type Metadata struct {
+	Title       string
+	Description string
+	Image       string
+}
+
+// Fetcher reads the title and Open Graph tags of destination pages.
+type Fetcher struct {
+	client *http.Client
+}
+
+// New returns a fetcher using the given client, or a client that refuses internal addresses when it is nil.
+func New(client *http.Client, timeout time.Duration) *Fetcher {
+	if client == nil {
+		client = safehttp.NewClient(timeout)
+	}
+	return &Fetcher{
+		client: client,
+	}
+}
+
+// Fetch downloads the start of the destination page and reads its metadata.
+// Only the first OpenGraphMaxBytes are read, which is plenty for the head of any reasonable page.
+func (f *Fetcher) Fetch(ctx context.Context, destination string) (Metadata, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, destination, nil)
+	if err != nil {
+		return Metadata{}, err
+	}
+	req.Header.Set("User-Agent", "codedln-unfurl/1.0")
+	req.Header.Set("Accept", "text/html,application/xhtml+xml")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return Metadata{}, err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return Metadata{}, fmt.Errorf("destination returned %d", resp.StatusCode)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return Metadata{}, ErrNotHTML
+	}
+
+	metadata := parse(io.LimitReader(resp.Body, constant.OpenGraphMaxBytes))
+
+	// Images are often given relative to the page, which is wherever the redirects ended up
+	if metadata.Image != "" {
+		metadata.Image = absolute(resp.Request.URL, metadata.Image)
+	}
+
+	return metadata, nil
+}
+
+// parse reads the page until its head ends. Open Graph values win over the plain title and description.
+func parse(body io.Reader) Metadata {
+	var metadata Metadata
+	var title, description string
+
+	tokenizer := html.NewTokenizer(body)
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return fill(metadata, title, description)
+		case html.EndTagToken:
+			if name, _ := tokenizer.TagName(); string(name) == "head" {
+				return fill(metadata, title, description)
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := tokenizer.TagName()
+			switch string(name) {
+			case "body":
+				return fill(metadata, title, description)
+			case "title":
+				if title == "" && tokenizer.Next() == html.TextToken {
+					title = clean(string(tokenizer.Text()), constant.TitleMaxLength)
+				}
+			case "meta":
+				if !hasAttr {
+					continue
+				}
+				attrs := map[string]string{}
+				for {
+					key, value, more := tokenizer.TagAttr()
+					attrs[string(key)] = string(value)
+					if !more {
+						break
+					}
+				}
+
+				property := strings.ToLower(attrs["property"])
+				if property == "" {
+					property = strings.ToLower(attrs["name"])
+				}
+				content := attrs["content"]
+				switch property {
+				case "og:title":
+					metadata.Title = clean(content, constant.TitleMaxLength)
+				case "og:description":
+					metadata.Description = clean(content, constant.DescriptionMaxLength)
+				case "og:image", "og:image:url":
+					if metadata.Image == "" {
+						metadata.Image = strings.TrimSpace(content)
+					}
+				case "description":
+					description = clean(content, constant.DescriptionMaxLength)
+				}
+			}
+		}
+	}
+}
+
+func fill(metadata Metadata, title string, description string) Metadata {
+	if metadata.Title == "" {
+		metadata.Title = title
+	}
+	if metadata.Description == "" {
+		metadata.Description = description
+	}
+	return metadata
+}
+
+// clean collapses whitespace and cuts the text down to at most max bytes without splitting a character.
+func clean(text string, max int) string {
+	text = strings.Join(strings.Fields(text), " ")
+	if len(text) <= max {
+		return text
+	}
+	for max > 0 && !utf8.RuneStart(text[max]) {
+		max--
+	}
+	return text[:max]
+}
+
+// absolute resolves an image reference against the page and drops anything that is not a web url.
+func absolute(page *url.URL, ref string) string {
+	resolved, err := page.Parse(ref)
+	if err != nil || (resolved.Scheme != "http" && resolved.Scheme != "https") || len(resolved.String()) > constant.MaxUrlLength {
+		return ""
+	}
+	return resolved.String()
+}
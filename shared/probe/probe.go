@@ -0,0 +1,118 @@
+package probe
+
+import (
+	"codedln/util/constant"
+	"codedln/util/types"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Dependency is something the service cannot serve traffic without.
+type Dependency struct {
+	Name string
+	Ping func(ctx context.Context) error
+}
+
+// Status is the outcome of pinging one dependency.
+type Status struct {
+	Status    types.ProbeStatus `json:"status"`
+	LatencyMs int64             `json:"latencyMs"`
+	Error     string            `json:"error,omitempty"`
+}
+
+// Report is the body of a readiness response.
+type Report struct {
+	Status       types.ProbeStatus `json:"status"`
+	Dependencies map[string]Status `json:"dependencies"`
+}
+
+// Probe answers the orchestrator's liveness and readiness checks.
+type Probe struct {
+	dependencies []Dependency
+	timeout      time.Duration
+	draining     atomic.Bool
+}
+
+// New returns a probe that pings each dependency for at most timeout when asked whether the instance is ready.
+func New(timeout time.Duration, dependencies ...Dependency) *Probe {
+	return &Probe{
+		dependencies: dependencies,
+		timeout:      timeout,
+	}
+}
+
+// Drain makes the instance report itself as not ready from now on, so the load balancer stops sending it traffic
+// while the requests it already has finish.
+func (p *Probe) Drain() {
+	p.draining.Store(true)
+}
+
+// Live reports that the process is up and serving requests. It checks nothing else, so a dependency outage does not
+// get every instance restarted.
+func (p *Probe) Live() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]types.ProbeStatus{"status": constant.ProbeOk})
+	}
+}
+
+// Ready pings every dependency at once and reports each one's status and latency. The instance is ready only when all of
+// them answered within the timeout and it is not shutting down.
+func (p *Probe) Ready() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := p.Check(r.Context())
+		if report.Status != constant.ProbeOk {
+			writeJSON(w, http.StatusServiceUnavailable, report)
+			return
+		}
+		writeJSON(w, http.StatusOK, report)
+	}
+}
+
+// Check pings every dependency and reports the result.
+func (p *Probe) Check(ctx context.Context) Report {
+	ctx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+
+	statuses := make([]Status, len(p.dependencies))
+	var wg sync.WaitGroup
+	for i, dependency := range p.dependencies {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			start := time.Now()
+			err := dependency.Ping(ctx)
+			statuses[i] = Status{Status: constant.ProbeOk, LatencyMs: time.Since(start).Milliseconds()}
+			if err != nil {
+				statuses[i].Status = constant.ProbeUnavailable
+				statuses[i].Error = err.Error()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: constant.ProbeOk, Dependencies: make(map[string]Status, len(statuses))}
+	for i, status := range statuses {
+		report.Dependencies[p.dependencies[i].Name] = status
+		if status.Status != constant.ProbeOk {
+			report.Status = constant.ProbeUnavailable
+		}
+	}
+	// Dependencies are still reported while draining so the cause of an unready instance is never hidden
+	if p.draining.Load() {
+		report.Status = constant.ProbeDraining
+	}
+	return report
+}
+
+// writeJSON writes the body as is. Probes are read by orchestrators and load balancers, so every answer has the same
+// shape rather than the envelope the API wraps successful responses in.
+func writeJSON(w http.ResponseWriter, statusCode int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -0,0 +1,75 @@
+package pubsub
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/redis/go-redis/v9"
+)
+
+// Broker fans messages out through redis pub/sub so every server instance sees them.
+// A nil Broker drops published messages, which lets features that only observe events run without redis.
+type Broker struct {
+	client *redis.Client
+}
+
+func New(client *redis.Client) *Broker {
+	return &Broker{
+		client: client,
+	}
+}
+
+// Publish sends payload as json to each channel.
+func (b *Broker) Publish(ctx context.Context, payload any, channels ...string) error {
+	if b == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	pipe := b.client.Pipeline()
+	for _, channel := range channels {
+		pipe.Publish(ctx, channel, data)
+	}
+	_, err = pipe.Exec(ctx)
+	return err
+}
+
+// Subscribe returns the payloads published to the channels until ctx is cancelled, then closes the subscription.
+// It waits for redis to confirm the subscription so a connection failure is reported before anything is streamed.
+func (b *Broker) Subscribe(ctx context.Context, channels ...string) (<-chan []byte, error) {
+	sub := b.client.Subscribe(ctx, channels...)
+	if _, err := sub.Receive(ctx); err != nil {
+		_ = sub.Close()
+		return nil, err
+	}
+
+	messages := make(chan []byte)
+	go func() {
+		defer close(messages)
+		defer func() {
+			_ = sub.Close()
+		}()
+
+		incoming := sub.Channel()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case msg, ok := <-incoming:
+				if !ok {
+					return
+				}
+				select {
+				case messages <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			}
+		}
+	}()
+
+	return messages, nil
+}
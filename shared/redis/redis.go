@@ -1,19 +1,27 @@
 package redis
 
 import (
+	"codedln/shared/config"
+	"codedln/shared/metrics"
+	"github.com/redis/go-redis/extra/redisotel/v9"
 	"github.com/redis/go-redis/v9"
-	"os"
+	"log/slog"
 )
 
-func ConnectToRedis() *redis.Client {
+func ConnectToRedis(cfg config.Redis) *redis.Client {
 	// Define Redis options
 	options := &redis.Options{
-		Addr:     os.Getenv("REDIS_ADDR"),
-		Password: os.Getenv("REDIS_PWD"),
-		Username: os.Getenv("REDIS_USERNAME"),
+		Addr:     cfg.Addr,
+		Password: cfg.Password,
+		Username: cfg.Username,
 	}
 	// Create a new Redis client with the options
 	rdb := redis.NewClient(options)
+	rdb.AddHook(metrics.RedisHook())
+	// Commands are traced as children of the span in their context
+	if err := redisotel.InstrumentTracing(rdb); err != nil {
+		slog.Error("unable to trace redis commands", "error", err)
+	}
 
 	return rdb
 }
@@ -0,0 +1,205 @@
+package screening
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"golang.org/x/net/idna"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// blocklistFile is the on-disk format of the blocklist.
+//
+//	{
+//	  "domains":  ["evil.com"],
+//	  "suffixes": ["badtld", "phish.example.org"],
+//	  "patterns": ["(?i)login-verify"],
+//	  "cidrs":    ["203.0.113.0/24"]
+//	}
+type blocklistFile struct {
+	Domains  []string `json:"domains"`
+	Suffixes []string `json:"suffixes"`
+	Patterns []string `json:"patterns"`
+	Cidrs    []string `json:"cidrs"`
+}
+
+// Blocklist is a local list of blocked destinations that can be reloaded while the server runs.
+type Blocklist struct {
+	mu       sync.RWMutex
+	path     string
+	modTime  time.Time
+	size     int64
+	domains  map[string]struct{}
+	suffixes []string
+	patterns []*regexp.Regexp
+	cidrs    []*net.IPNet
+}
+
+// LoadBlocklist reads the blocklist at path. An empty path gives an empty blocklist that never matches.
+func LoadBlocklist(path string) (*Blocklist, error) {
+	b := &Blocklist{path: path, domains: map[string]struct{}{}}
+	if path == "" {
+		return b, nil
+	}
+
+	if _, err := b.reload(); err != nil {
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// Match reports whether the destination is blocked and which rule blocked it.
+func (b *Blocklist) Match(destination *url.URL) (string, bool) {
+	if b == nil {
+		return "", false
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	host := strings.TrimSuffix(strings.ToLower(destination.Hostname()), ".")
+
+	if _, ok := b.domains[host]; ok {
+		return fmt.Sprintf("domain %s is blocked", host), true
+	}
+
+	for _, suffix := range b.suffixes {
+		if host == suffix || strings.HasSuffix(host, "."+suffix) {
+			return fmt.Sprintf("domain %s is blocked", suffix), true
+		}
+	}
+
+	// Only ip literals are checked, resolving every destination would make link creation depend on dns
+	if ip := net.ParseIP(host); ip != nil {
+		for _, cidr := range b.cidrs {
+			if cidr.Contains(ip) {
+				return fmt.Sprintf("address range %s is blocked", cidr.String()), true
+			}
+		}
+	}
+
+	for _, pattern := range b.patterns {
+		if pattern.MatchString(destination.String()) {
+			return "destination matches a blocked pattern", true
+		}
+	}
+
+	return "", false
+}
+
+// Watch polls the blocklist file and reloads it whenever it changes, calling onChange after every successful reload.
+// A file that fails to parse is logged and the previous list stays in effect. It returns once ctx is cancelled.
+func (b *Blocklist) Watch(ctx context.Context, interval time.Duration, onChange func()) {
+	if b == nil || b.path == "" {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		changed, err := b.reload()
+		if err != nil {
+			slog.Error("unable to reload blocklist", "error", err)
+			continue
+		}
+
+		if changed && onChange != nil {
+			onChange()
+		}
+	}
+}
+
+func (b *Blocklist) reload() (bool, error) {
+	info, err := os.Stat(b.path)
+	if err != nil {
+		return false, err
+	}
+
+	b.mu.RLock()
+	unchanged := info.ModTime().Equal(b.modTime) && info.Size() == b.size
+	b.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	data, err := os.ReadFile(b.path)
+	if err != nil {
+		return false, err
+	}
+
+	var file blocklistFile
+	if err = json.Unmarshal(data, &file); err != nil {
+		return false, err
+	}
+
+	domains := make(map[string]struct{}, len(file.Domains))
+	for _, domain := range file.Domains {
+		host, hostErr := normalizeHost(domain)
+		if hostErr != nil {
+			return false, fmt.Errorf("invalid domain %q: %w", domain, hostErr)
+		}
+		domains[host] = struct{}{}
+	}
+
+	suffixes := make([]string, 0, len(file.Suffixes))
+	for _, suffix := range file.Suffixes {
+		host, hostErr := normalizeHost(suffix)
+		if hostErr != nil {
+			return false, fmt.Errorf("invalid suffix %q: %w", suffix, hostErr)
+		}
+		suffixes = append(suffixes, host)
+	}
+
+	patterns := make([]*regexp.Regexp, 0, len(file.Patterns))
+	for _, pattern := range file.Patterns {
+		compiled, compileErr := regexp.Compile(pattern)
+		if compileErr != nil {
+			return false, fmt.Errorf("invalid pattern %q: %w", pattern, compileErr)
+		}
+		patterns = append(patterns, compiled)
+	}
+
+	cidrs := make([]*net.IPNet, 0, len(file.Cidrs))
+	for _, cidr := range file.Cidrs {
+		_, network, parseErr := net.ParseCIDR(cidr)
+		if parseErr != nil {
+			return false, fmt.Errorf("invalid cidr %q: %w", cidr, parseErr)
+		}
+		cidrs = append(cidrs, network)
+	}
+
+	b.mu.Lock()
+	b.modTime = info.ModTime()
+	b.size = info.Size()
+	b.domains = domains
+	b.suffixes = suffixes
+	b.patterns = patterns
+	b.cidrs = cidrs
+	b.mu.Unlock()
+
+	return true, nil
+}
+
+// normalizeHost converts an entry to the punycode form destinations are canonicalized to, so unicode entries still match.
+func normalizeHost(host string) (string, error) {
+	host, err := idna.Lookup.ToASCII(strings.Trim(strings.TrimSpace(host), "."))
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(host), nil
+}
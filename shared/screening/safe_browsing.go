@@ -0,0 +1,105 @@
+package screening
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// ThreatLookup checks a destination against a remote threat list.
+// It returns the threat type when the destination is listed and an empty string otherwise.
+type ThreatLookup interface {
+	Lookup(ctx context.Context, destination string) (string, error)
+}
+
+// SafeBrowsingClient speaks the Google Safe Browsing v4 threatMatches:find api.
+// The endpoint is configurable so any compatible service, including a local fake, can serve it.
+type SafeBrowsingClient struct {
+	endpoint   string
+	apiKey     string
+	httpClient *http.Client
+}
+
+func NewSafeBrowsingClient(endpoint string, apiKey string, httpClient *http.Client) *SafeBrowsingClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 3 * time.Second}
+	}
+	return &SafeBrowsingClient{
+		endpoint:   endpoint,
+		apiKey:     apiKey,
+		httpClient: httpClient,
+	}
+}
+
+type threatEntry struct {
+	Url string `json:"url"`
+}
+
+type findRequest struct {
+	Client struct {
+		ClientId      string `json:"clientId"`
+		ClientVersion string `json:"clientVersion"`
+	} `json:"client"`
+	ThreatInfo struct {
+		ThreatTypes      []string      `json:"threatTypes"`
+		PlatformTypes    []string      `json:"platformTypes"`
+		ThreatEntryTypes []string      `json:"threatEntryTypes"`
+		ThreatEntries    []threatEntry `json:"threatEntries"`
+	} `json:"threatInfo"`
+}
+
+type findResponse struct {
+	Matches []struct {
+		ThreatType string      `json:"threatType"`
+		Threat     threatEntry `json:"threat"`
+	} `json:"matches"`
+}
+
+func (c *SafeBrowsingClient) Lookup(ctx context.Context, destination string) (string, error) {
+	var body findRequest
+	body.Client.ClientId = "codedln"
+	body.Client.ClientVersion = "1.0.0"
+	body.ThreatInfo.ThreatTypes = []string{"MALWARE", "SOCIAL_ENGINEERING", "UNWANTED_SOFTWARE", "POTENTIALLY_HARMFUL_APPLICATION"}
+	body.ThreatInfo.PlatformTypes = []string{"ANY_PLATFORM"}
+	body.ThreatInfo.ThreatEntryTypes = []string{"URL"}
+	body.ThreatInfo.ThreatEntries = []threatEntry{{Url: destination}}
+
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return "", err
+	}
+
+	endpoint := fmt.Sprintf("%s/v4/threatMatches:find?key=%s", c.endpoint, url.QueryEscape(c.apiKey))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("safe browsing lookup failed with status %d", resp.StatusCode)
+	}
+
+	var result findResponse
+	if err = json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", err
+	}
+
+	if len(result.Matches) == 0 {
+		return "", nil
+	}
+
+	return result.Matches[0].ThreatType, nil
+}
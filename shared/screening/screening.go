@@ -0,0 +1,73 @@
+package screening
+
+import (
+	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Screener decides whether a destination may be shortened.
+type Screener struct {
+	blocklist *Blocklist
+	lookup    ThreatLookup
+}
+
+// New creates a screener. The threat lookup is optional and may be nil.
+func New(blocklist *Blocklist, lookup ThreatLookup) *Screener {
+	return &Screener{
+		blocklist: blocklist,
+		lookup:    lookup,
+	}
+}
+
+// Screen returns a 400 error explaining why the destination is blocked, or nil when it is allowed.
+// A failing threat lookup is logged and the destination allowed, so an outage of the provider does not stop link creation.
+func (s *Screener) Screen(ctx context.Context, destination string) error {
+	if s == nil {
+		return nil
+	}
+
+	if reason, blocked := s.Check(destination); blocked {
+		return http_error.New(http.StatusBadRequest, "destination blocked: "+reason)
+	}
+
+	if s.lookup != nil {
+		threat, err := s.lookup.Lookup(ctx, destination)
+		if err != nil {
+			logging.FromContext(ctx).Error("threat lookup failed", "error", err)
+			return nil
+		}
+
+		if threat != "" {
+			return http_error.New(http.StatusBadRequest, "destination blocked: flagged as "+strings.ToLower(threat))
+		}
+	}
+
+	return nil
+}
+
+// Check matches the destination against the local blocklist only. It is cheap enough to run over every stored link.
+func (s *Screener) Check(destination string) (string, bool) {
+	if s == nil {
+		return "", false
+	}
+
+	if !strings.Contains(destination, "://") {
+		destination = "http://" + destination
+	}
+
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return "destination is not a valid url", true
+	}
+
+	return s.blocklist.Match(parsed)
+}
+
+// Blocklist returns the local blocklist used by the screener.
+func (s *Screener) Blocklist() *Blocklist {
+	return s.blocklist
+}
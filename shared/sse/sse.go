@@ -0,0 +1,72 @@
+package sse
+
+import (
+	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	"codedln/util/constant"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// closing is closed once the server starts shutting down.
+var closing = make(chan struct{})
+var closeOnce sync.Once
+
+// Close ends every open stream and any stream started afterwards. http.Server.Shutdown waits for active requests to
+// finish without cancelling them, so it is registered with RegisterOnShutdown for the server to be able to stop.
+func Close() {
+	closeOnce.Do(func() { close(closing) })
+}
+
+// Stream writes each message as a server-sent event until the client disconnects, messages is closed or Close is called.
+// A comment line is sent on an interval so proxies do not close the idle connection.
+func Stream(w http.ResponseWriter, r *http.Request, event string, messages <-chan []byte) error {
+	rc := http.NewResponseController(w)
+
+	// Streams stay open far longer than the server timeouts allow. The read deadline matters too because
+	// the server cancels the request context when its background read of the connection times out.
+	for _, setDeadline := range []func(time.Time) error{rc.SetReadDeadline, rc.SetWriteDeadline} {
+		if err := setDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			logging.FromContext(r.Context()).Error("unable to start stream", "error", err)
+			return http_error.New(http.StatusInternalServerError, "unable to start stream")
+		}
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	// Once the headers are written errors can no longer be reported as json, so write failures just end the stream
+	if _, err := fmt.Fprint(w, ": connected\n\n"); err != nil || rc.Flush() != nil {
+		return nil
+	}
+
+	heartbeat := time.NewTicker(constant.SSEHeartbeatInterval * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		var err error
+		select {
+		case <-r.Context().Done():
+			return nil
+		case <-closing:
+			return nil
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case message, ok := <-messages:
+			if !ok {
+				return nil
+			}
+			_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, message)
+		}
+
+		if err != nil || rc.Flush() != nil {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package tracing
+
+import (
+	"codedln/shared/config"
+	"codedln/util/constant"
+	"context"
+	"fmt"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/contrib/instrumentation/go.mongodb.org/mongo-driver/mongo/otelmongo"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Init installs the global tracer provider and the W3C trace context propagator. Spans are exported with the configured
+// exporter: "otlp" sends them to the endpoint, "console" prints them to stdout for local runs and "none" turns tracing
+// off. When no exporter is set, spans are sent over OTLP only if an endpoint is configured.
+// The returned function flushes any spans still buffered and must be called before the process exits.
+func Init(ctx context.Context, cfg config.Tracing) (func(context.Context) error, error) {
+	// Incoming traceparent headers are honoured even when this instance does not export anything
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	exporter, err := newExporter(ctx, cfg)
+	if err != nil || exporter == nil {
+		return func(context.Context) error { return nil }, err
+	}
+
+	// The configured service name takes precedence over one given in OTEL_RESOURCE_ATTRIBUTES
+	res, err := resource.New(ctx,
+		resource.WithFromEnv(),
+		resource.WithAttributes(semconv.ServiceName(cfg.ServiceName)),
+		resource.WithTelemetrySDK(),
+		resource.WithHost(),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.AlwaysSample())),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+func newExporter(ctx context.Context, cfg config.Tracing) (sdktrace.SpanExporter, error) {
+	exporter := cfg.Exporter
+	if exporter == "" && cfg.Endpoint != "" {
+		exporter = "otlp"
+	}
+
+	switch exporter {
+	case "", "none":
+		return nil, nil
+	case "otlp":
+		// Headers and TLS settings are still read from the standard OTEL_EXPORTER_OTLP_* variables
+		if cfg.Endpoint == "" {
+			return otlptracehttp.New(ctx)
+		}
+		return otlptracehttp.New(ctx, otlptracehttp.WithEndpointURL(cfg.Endpoint))
+	case "console":
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", exporter)
+	}
+}
+
+// Tracer returns the tracer the service's own spans are created with.
+func Tracer() trace.Tracer {
+	return otel.Tracer(constant.TracerName)
+}
+
+// MongoMonitor creates a span for every command the MongoDB driver sends, as a child of the span in the command's context.
+func MongoMonitor() *event.CommandMonitor {
+	return otelmongo.NewMonitor()
+}
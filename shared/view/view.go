@@ -0,0 +1,51 @@
+package view
+
+import (
+	"bytes"
+	"codedln/shared/http_error"
+	"embed"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"path/filepath"
+)
+
+//go:embed templates/*.html
+var files embed.FS
+
+var templates = template.Must(template.ParseFS(files, "templates/*.html"))
+
+// Override replaces the built in pages with any html files of the same name in dir, so the pages can be restyled
+// without rebuilding. It is meant to be called once at startup. An empty dir keeps the built in pages.
+func Override(dir string) error {
+	if dir == "" {
+		return nil
+	}
+
+	overridden, err := template.ParseFS(files, "templates/*.html")
+	if err != nil {
+		return err
+	}
+	if _, err = overridden.ParseGlob(filepath.Join(dir, "*.html")); err != nil {
+		return err
+	}
+
+	templates = overridden
+	return nil
+}
+
+// Render writes the named html template with the given status code.
+// The page is rendered into a buffer first so a template error can still be reported as a json error.
+func Render(w http.ResponseWriter, statusCode int, name string, data any) error {
+	var buf bytes.Buffer
+	if err := templates.ExecuteTemplate(&buf, name, data); err != nil {
+		slog.Error("unable to render template", "template", name, "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to render page")
+	}
+
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(statusCode)
+	_, err := buf.WriteTo(w)
+	return err
+}
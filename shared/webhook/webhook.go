@@ -0,0 +1,119 @@
+package webhook
+
+import (
+	"bytes"
+	"codedln/shared/safehttp"
+	"codedln/util/constant"
+	"codedln/util/types"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Message is one signed request to a receiver.
+type Message struct {
+	Url        string
+	Secret     string
+	DeliveryId string
+	Event      types.WebhookEvent
+	Body       []byte
+}
+
+// Attempt is the outcome of sending a message once.
+type Attempt struct {
+	StatusCode  int
+	Response    string
+	Error       string
+	Latency     time.Duration
+	AttemptedAt time.Time
+}
+
+// Succeeded reports whether the receiver accepted the message. Redirects are not followed, so they count as failures.
+func (a Attempt) Succeeded() bool {
+	return a.Error == "" && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// Sender posts signed messages to webhook receivers.
+type Sender struct {
+	client *http.Client
+}
+
+// New returns a sender using the given client, or a client that refuses internal addresses when it is nil.
+func New(client *http.Client, timeout time.Duration) *Sender {
+	if client == nil {
+		client = safehttp.NewClient(timeout)
+	}
+	noRedirects := *client
+	noRedirects.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Sender{
+		client: &noRedirects,
+	}
+}
+
+// Send posts the message body as json. The start of the response is kept so owners can see why a receiver refused it.
+func (s *Sender) Send(ctx context.Context, message Message) Attempt {
+	attempt := Attempt{AttemptedAt: time.Now().UTC()}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, message.Url, bytes.NewReader(message.Body))
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+
+	timestamp := attempt.AttemptedAt.Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "codedln-webhook/1.0")
+	req.Header.Set(constant.WebhookEventHeader, string(message.Event))
+	req.Header.Set(constant.WebhookDeliveryHeader, message.DeliveryId)
+	req.Header.Set(constant.WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(constant.WebhookSignatureHeader, Sign(message.Secret, timestamp, message.Body))
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	attempt.Latency = time.Since(start)
+	if err != nil {
+		attempt.Error = err.Error()
+		return attempt
+	}
+	defer func() {
+		_ = resp.Body.Close()
+	}()
+
+	attempt.StatusCode = resp.StatusCode
+	response, _ := io.ReadAll(io.LimitReader(resp.Body, constant.WebhookResponseMaxBytes))
+	if utf8.Valid(response) {
+		attempt.Response = string(response)
+	}
+	return attempt
+}
+
+// Sign returns the signature sent with a body at timestamp: "sha256=" followed by the hex HMAC-SHA256 of "{timestamp}.{body}".
+// The timestamp is signed too so a captured request cannot be replayed later.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a received body, and that it was signed no more than tolerance ago.
+func Verify(secret string, timestamp string, signature string, body []byte, tolerance time.Duration) bool {
+	sentAt, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return false
+	}
+	if age := time.Since(time.Unix(sentAt, 0)); age > tolerance || age < -tolerance {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(secret, sentAt, body)), []byte(signature))
+}
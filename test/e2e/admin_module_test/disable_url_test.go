@@ -0,0 +1,189 @@
+package admin_module_test
+
+import (
+	"codedln/admin_module/controller"
+	"codedln/admin_module/model"
+	"codedln/admin_module/repository"
+	"codedln/admin_module/service"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestDisableUrl(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+	auditCollection := db.Collection(constant.AuditCollection)
+	reportCollection := db.Collection(constant.ReportCollection)
+
+	admin, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "admin@codedln.com", "verified": true, "role": constant.AdminRole})
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Jane", "lastname": "Doe", "email": "jane@codedln.com", "verified": true, "role": constant.UserRole})
+	url, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://google.com", "alias": "Huixyk"})
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = auditCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	adminRepo := repository.New(urlCollection, userCollection, auditCollection, reportCollection)
+	adminService := service.New(adminRepo)
+	adminController := controller.New(adminService)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/disable_url/{urlId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.DisableUrl,
+			middleware.PayloadValidationMiddleware(model.NewModerationSchema),
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+				Rate:   100,
+				Burst:  50,
+				Period: time.Minute * 2,
+			}),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	urlId := url.InsertedID.(primitive.ObjectID).Hex()
+
+	tests := []struct {
+		description    string
+		endpoint       string
+		method         string
+		setCookie      bool
+		clientKey      string
+		jwt            string
+		payload        map[string]any
+		expectedStatus int
+	}{
+		{
+			description:    "Should return a 401 status code with invalid client authorization key",
+			endpoint:       fmt.Sprintf("%s/disable_url/%s", server.URL, urlId),
+			method:         "PATCH",
+			clientKey:      "bad_client_key",
+			payload:        map[string]any{"reason": "phishing"},
+			expectedStatus: 401,
+		},
+		{
+			description:    "Should return a 401 status code with no cookie set",
+			endpoint:       fmt.Sprintf("%s/disable_url/%s", server.URL, urlId),
+			method:         "PATCH",
+			setCookie:      false,
+			clientKey:      cfg.Auth.ClientKey,
+			payload:        map[string]any{"reason": "phishing"},
+			expectedStatus: 401,
+		},
+		{
+			description:    "Should return a 403 status code for a user without the admin role",
+			endpoint:       fmt.Sprintf("%s/disable_url/%s", server.URL, urlId),
+			method:         "PATCH",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"reason": "phishing"},
+			expectedStatus: 403,
+		},
+		{
+			description:    "Should return a 400 status code with no reason",
+			endpoint:       fmt.Sprintf("%s/disable_url/%s", server.URL, urlId),
+			method:         "PATCH",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: admin.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"reason": ""},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 404 status code with a url that does not exist",
+			endpoint:       fmt.Sprintf("%s/disable_url/%s", server.URL, primitive.NewObjectID().Hex()),
+			method:         "PATCH",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: admin.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"reason": "phishing"},
+			expectedStatus: 404,
+		},
+		{
+			description:    "Should return a 200 status code when an admin disables a url",
+			endpoint:       fmt.Sprintf("%s/disable_url/%s", server.URL, urlId),
+			method:         "PATCH",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: admin.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"reason": "phishing"},
+			expectedStatus: 200,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			client := &http.Client{}
+			payload, err := helpers.AnyTypeToReader(test.payload)
+			req, err := http.NewRequest(test.method, test.endpoint, payload)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", test.clientKey))
+			req.Header.Add("Content-Type", "application/json")
+			if test.setCookie {
+				cookie := &http.Cookie{
+					Name:  constant.JwtCookieName,
+					Value: test.jwt,
+				}
+				req.AddCookie(cookie)
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	count, _ := auditCollection.CountDocuments(context.TODO(), map[string]any{"action": constant.DisableUrlAction})
+	if count != 1 {
+		t.Errorf("expected %v audit log got %v", 1, count)
+	}
+}
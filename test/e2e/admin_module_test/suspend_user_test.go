@@ -0,0 +1,195 @@
+package admin_module_test
+
+import (
+	"codedln/admin_module/controller"
+	"codedln/admin_module/model"
+	"codedln/admin_module/repository"
+	"codedln/admin_module/service"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	urlModel "codedln/url_module/model"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSuspendUser(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load()
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+	auditCollection := db.Collection(constant.AuditCollection)
+	reportCollection := db.Collection(constant.ReportCollection)
+
+	admin, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "root@codedln.com", "verified": true, "role": constant.AdminRole})
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "John", "lastname": "Doe", "email": "john@codedln.com", "verified": true, "role": constant.UserRole})
+	active, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://google.com", "alias": "Suspqa", "status": constant.ActiveUrl})
+	disabled, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://google.com", "alias": "Suspqb", "status": constant.DisabledUrl, "statusReason": "spam"})
+	report, _ := reportCollection.InsertOne(context.TODO(), map[string]any{"urlId": active.InsertedID, "alias": "Suspqa", "category": constant.PhishingReport, "state": constant.OpenReport})
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = auditCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = reportCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	adminRepo := repository.New(urlCollection, userCollection, auditCollection, reportCollection)
+	adminService := service.New(adminRepo)
+	adminController := controller.New(adminService)
+
+	router := mux.NewRouter()
+	for path, handler := range map[string]func(http.ResponseWriter, *http.Request) error{
+		"/suspend_user/{userId}":   adminController.SuspendUser,
+		"/unsuspend_user/{userId}": adminController.UnsuspendUser,
+		"/enable_url/{urlId}":      adminController.EnableUrl,
+	} {
+		router.HandleFunc(path,
+			middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+				handler,
+				middleware.PayloadValidationMiddleware(model.NewModerationSchema),
+				middleware.AuthorizationMiddleware(constant.AdminRole),
+				middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+				middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+					Rate:   100,
+					Burst:  50,
+					Period: time.Minute * 2,
+				}),
+				middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+			))).Methods(http.MethodPatch)
+	}
+	router.HandleFunc("/review_report/{reportId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			adminController.ReviewReport,
+			middleware.PayloadValidationMiddleware(model.NewReviewReportSchema),
+			middleware.AuthorizationMiddleware(constant.AdminRole),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	userId := user.InsertedID.(primitive.ObjectID).Hex()
+	activeId := active.InsertedID.(primitive.ObjectID).Hex()
+	reportId := report.InsertedID.(primitive.ObjectID).Hex()
+	moderation := map[string]any{"reason": "abuse"}
+	adminJwt := helpers.CreateJWT(types.AuthUser{UserId: admin.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	tests := []struct {
+		description    string
+		endpoint       string
+		payload        map[string]any
+		jwt            string
+		expectedStatus int
+		activeStatus   types.UrlStatus
+		disabledStatus types.UrlStatus
+	}{
+		{
+			description:    "Should suspend the links of a suspended user",
+			endpoint:       fmt.Sprintf("%s/suspend_user/%s", server.URL, userId),
+			payload:        moderation,
+			jwt:            adminJwt,
+			expectedStatus: 200,
+			activeStatus:   constant.SuspendedUrl,
+			disabledStatus: constant.DisabledUrl,
+		},
+		{
+			description:    "Should return a 403 status code when a suspended user calls an admin route",
+			endpoint:       fmt.Sprintf("%s/unsuspend_user/%s", server.URL, userId),
+			payload:        moderation,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: userId}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			expectedStatus: 403,
+			activeStatus:   constant.SuspendedUrl,
+			disabledStatus: constant.DisabledUrl,
+		},
+		{
+			description:    "Should return a 409 status code when enabling a link of a suspended user",
+			endpoint:       fmt.Sprintf("%s/enable_url/%s", server.URL, activeId),
+			payload:        moderation,
+			jwt:            adminJwt,
+			expectedStatus: 409,
+			activeStatus:   constant.SuspendedUrl,
+			disabledStatus: constant.DisabledUrl,
+		},
+		{
+			description:    "Should keep a link suspended with its owner when its reports are dismissed",
+			endpoint:       fmt.Sprintf("%s/review_report/%s", server.URL, reportId),
+			payload:        map[string]any{"state": constant.DismissedReport, "reason": "not phishing"},
+			jwt:            adminJwt,
+			expectedStatus: 200,
+			activeStatus:   constant.SuspendedUrl,
+			disabledStatus: constant.DisabledUrl,
+		},
+		{
+			description:    "Should only restore the links suspended with the user",
+			endpoint:       fmt.Sprintf("%s/unsuspend_user/%s", server.URL, userId),
+			payload:        moderation,
+			jwt:            adminJwt,
+			expectedStatus: 200,
+			activeStatus:   constant.ActiveUrl,
+			disabledStatus: constant.DisabledUrl,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			client := &http.Client{}
+			payload, err := helpers.AnyTypeToReader(test.payload)
+			req, err := http.NewRequest(http.MethodPatch, test.endpoint, payload)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", os.Getenv("CLIENT_KEY")))
+			req.Header.Add("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: test.jwt})
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+
+			for id, expected := range map[any]types.UrlStatus{active.InsertedID: test.activeStatus, disabled.InsertedID: test.disabledStatus} {
+				var url urlModel.Url
+				_ = urlCollection.FindOne(context.TODO(), map[string]any{"_id": id}).Decode(&url)
+				if url.Status != expected {
+					t.Errorf("expected %v got %v", expected, url.Status)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,224 @@
+package bio_module_test
+
+import (
+	"codedln/bio_module/controller"
+	"codedln/bio_module/model"
+	"codedln/bio_module/repository"
+	"codedln/bio_module/service"
+	"codedln/shared/analytics"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	urlModel "codedln/url_module/model"
+	urlRepository "codedln/url_module/repository"
+	urlService "codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	neturl "net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBioPage(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+	bioPageCollection := db.Collection(constant.BioPageCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "picture": "https://example.com/martin.png", "verified": true})
+	other, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Ada", "lastname": "Lovelace", "email": "ada@example.com", "verified": true})
+	blog, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://example.com/blog", "alias": "Bioblog", "title": "My blog", "status": constant.ActiveUrl, "clicks": 0})
+	shop, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://example.com/shop", "alias": "Bioshop", "status": constant.ActiveUrl, "clicks": 0})
+	banned, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://example.com/old", "alias": "Biooldx", "title": "Old", "status": constant.DisabledUrl, "clicks": 0})
+	foreign, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": other.InsertedID, "originalUrl": "https://example.org/", "alias": "Biofrgn", "status": constant.ActiveUrl, "clicks": 0})
+	_, _ = bioPageCollection.InsertOne(context.TODO(), map[string]any{"userId": other.InsertedID, "handle": "ada", "theme": constant.LightBioTheme, "links": []any{}})
+
+	blogId := blog.InsertedID.(primitive.ObjectID).Hex()
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = bioPageCollection.DeleteMany(context.TODO(), map[string]string{})
+		_ = rClient.Del(context.TODO(), constant.VisitorsKeyPrefix+blogId, constant.VisitorsKeyPrefix+blogId+":"+today, constant.DailyClicksKeyPrefix+blogId, constant.ReferralsKeyPrefix+blogId).Err()
+	}()
+
+	bioRepo := repository.New(bioPageCollection, urlCollection, userCollection)
+	if err := bioRepo.CreateIndexes(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	bioController := controller.New(service.New(bioRepo))
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   100,
+		Burst:  50,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/page", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		bioController.SaveBioPage,
+		middleware.PayloadValidationMiddleware(model.NewSaveBioPageSchema),
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPut)
+	router.HandleFunc("/p/{handle}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		bioController.ViewBioPage,
+		limit,
+	))).Methods(http.MethodGet)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	link := func(id any, title string) map[string]any {
+		return map[string]any{"urlId": id.(primitive.ObjectID).Hex(), "title": title}
+	}
+
+	tests := []struct {
+		description    string
+		method         string
+		endpoint       string
+		payload        any
+		expectedStatus int
+		expectedBody   []string
+		unexpectedBody string
+	}{
+		{
+			description:    "Should return a 400 status code with an invalid handle",
+			method:         "PUT",
+			endpoint:       "/page",
+			payload:        map[string]any{"handle": "no spaces"},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 400 status code with another user's link",
+			method:         "PUT",
+			endpoint:       "/page",
+			payload:        map[string]any{"handle": "martin", "links": []any{link(foreign.InsertedID, "")}},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 409 status code when the handle is taken",
+			method:         "PUT",
+			endpoint:       "/page",
+			payload:        map[string]any{"handle": "Ada"},
+			expectedStatus: 409,
+		},
+		{
+			description:    "Should save the page",
+			method:         "PUT",
+			endpoint:       "/page",
+			payload:        map[string]any{"handle": "Martin", "bio": "Writing about Go", "theme": constant.DarkBioTheme, "links": []any{link(shop.InsertedID, "Shop"), link(banned.InsertedID, ""), link(blog.InsertedID, "")}},
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should render the page with the profile picture and links in order",
+			method:         "GET",
+			endpoint:       "/p/martin",
+			expectedStatus: 200,
+			expectedBody:   []string{"https://example.com/martin.png", "Martin Alemajoh", "Writing about Go", "theme-dark", `<a href="/Bioshop"><span>Shop</span>`, `<a href="/Bioblog"><span>My blog</span>`},
+			unexpectedBody: "Biooldx",
+		},
+		{
+			description:    "Should return a 404 status code for an unknown handle",
+			method:         "GET",
+			endpoint:       "/p/nobody",
+			expectedStatus: 404,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			body, _ := helpers.AnyTypeToReader(test.payload)
+			req, _ := http.NewRequest(test.method, server.URL+test.endpoint, body)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+			req.Header.Add("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+
+			page, _ := io.ReadAll(resp.Body)
+			// Links are listed in the order the owner saved them
+			previous := -1
+			for _, expected := range test.expectedBody {
+				index := strings.Index(string(page), expected)
+				if index == -1 {
+					t.Errorf("expected the page to contain %q", expected)
+				} else if index < previous {
+					t.Errorf("expected %q to come later in the page", expected)
+				}
+				previous = index
+			}
+			if test.unexpectedBody != "" && strings.Contains(string(page), test.unexpectedBody) {
+				t.Errorf("expected the page not to contain %q", test.unexpectedBody)
+			}
+		})
+	}
+
+	t.Run("Should attribute clicks from the page as referrals", func(t *testing.T) {
+		urlRepo := urlRepository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+		bots, err := analytics.NewBotClassifier("")
+		if err != nil {
+			t.Fatal(err)
+		}
+		links := urlService.New(urlRepo, nil, nil, analytics.New(rClient, bots, cfg.Links.VisitorHashSalt), nil, nil, nil)
+
+		host, _ := neturl.Parse(server.URL)
+		visit := urlModel.Visit{IP: "10.0.0.1", UserAgent: "Firefox", Referrer: server.URL + "/p/martin", Host: host.Host}
+		if _, err := links.Redirect(context.TODO(), "Bioblog", visit); err != nil {
+			t.Fatal(err)
+		}
+
+		stats, err := links.GetStats(context.TODO(), blogId, user.InsertedID.(primitive.ObjectID).Hex(), time.Now(), time.Now(), false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stats.Referrals[constant.BioReferralPrefix+"martin"] != 1 {
+			t.Errorf("expected %v referral from the bio page got %v", 1, stats.Referrals)
+		}
+	})
+}
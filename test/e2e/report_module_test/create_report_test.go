@@ -0,0 +1,258 @@
+package report_module_test
+
+import (
+	"codedln/report_module/controller"
+	"codedln/report_module/model"
+	"codedln/report_module/repository"
+	"codedln/report_module/service"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	urlModel "codedln/url_module/model"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestCreateReport(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	urlCollection := db.Collection(constant.UrlCollection)
+	reportCollection := db.Collection(constant.ReportCollection)
+
+	url, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://phishing.example", "alias": "Kjhyui", "status": constant.ActiveUrl})
+	raced, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://spam.example", "alias": "Rcdkjh", "status": constant.ActiveUrl})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://gone.example", "alias": "Trshed", "status": constant.ActiveUrl, "deletedAt": time.Now().UTC()})
+
+	defer func() {
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = reportCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	reportRepo := repository.New(reportCollection, urlCollection)
+	if indexErr := reportRepo.CreateIndexes(context.TODO()); indexErr != nil {
+		t.Fatal(indexErr)
+	}
+	reportService := service.New(reportRepo, cfg.Reports.Threshold)
+	reportController := controller.New(reportService)
+
+	// The test client connects over loopback, which stands in for the proxy that sets X-Forwarded-For
+	router := mux.NewRouter()
+	router.Use(middleware.ClientIPMiddleware([]netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}))
+	router.HandleFunc("/report/{alias}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			reportController.CreateReport,
+			middleware.PayloadValidationMiddleware(model.NewCreateReportSchema),
+			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+				Rate:   100,
+				Burst:  50,
+				Period: time.Minute * 2,
+			}),
+		))).Methods(http.MethodPost)
+
+	// Allows a single report per visitor and link
+	router.HandleFunc("/limited/{alias}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			reportController.CreateReport,
+			middleware.PayloadValidationMiddleware(model.NewCreateReportSchema),
+			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+				Rate:   1,
+				Burst:  1,
+				Period: time.Minute * 2,
+			}),
+		))).Methods(http.MethodPost)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	tests := []struct {
+		description    string
+		endpoint       string
+		method         string
+		clientIP       string
+		payload        map[string]any
+		expectedStatus int
+	}{
+		{
+			description:    "Should return a 400 status code with an invalid category",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/report/Kjhyui"),
+			method:         "POST",
+			clientIP:       "10.0.0.1",
+			payload:        map[string]any{"category": "boring"},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 404 status code with an alias that does not exist",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/report/Nopexy"),
+			method:         "POST",
+			clientIP:       "10.0.0.1",
+			payload:        map[string]any{"category": "phishing"},
+			expectedStatus: 404,
+		},
+		{
+			description:    "Should return a 404 status code with a trashed alias",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/report/Trshed"),
+			method:         "POST",
+			clientIP:       "10.0.0.1",
+			payload:        map[string]any{"category": "phishing"},
+			expectedStatus: 404,
+		},
+		{
+			description:    "Should return a 201 status code with a valid report",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/report/Kjhyui"),
+			method:         "POST",
+			clientIP:       "10.0.0.1",
+			payload:        map[string]any{"category": "phishing", "comment": "asks for my bank password"},
+			expectedStatus: 201,
+		},
+		{
+			description:    "Should return a 409 status code when the same visitor reports twice",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/report/Kjhyui"),
+			method:         "POST",
+			clientIP:       "10.0.0.1",
+			payload:        map[string]any{"category": "phishing"},
+			expectedStatus: 409,
+		},
+		{
+			description:    "Should return a 409 status code when the same visitor forges X-Forwarded-For",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/report/Kjhyui"),
+			method:         "POST",
+			clientIP:       "10.0.0.9, 10.0.0.1",
+			payload:        map[string]any{"category": "phishing"},
+			expectedStatus: 409,
+		},
+	}
+
+	for i := 2; i <= constant.DefaultReportThreshold; i++ {
+		tests = append(tests, struct {
+			description    string
+			endpoint       string
+			method         string
+			clientIP       string
+			payload        map[string]any
+			expectedStatus int
+		}{
+			description:    fmt.Sprintf("Should return a 201 status code for report %d from another visitor", i),
+			endpoint:       fmt.Sprintf("%s", server.URL+"/report/Kjhyui"),
+			method:         "POST",
+			clientIP:       fmt.Sprintf("10.0.0.%d", i),
+			payload:        map[string]any{"category": "malware"},
+			expectedStatus: 201,
+		})
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			client := &http.Client{}
+			payload, err := helpers.AnyTypeToReader(test.payload)
+			req, err := http.NewRequest(test.method, test.endpoint, payload)
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("X-Forwarded-For", test.clientIP)
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	t.Run("Should count concurrent reports from the same visitor once", func(t *testing.T) {
+		statuses := make(chan int, 5)
+		var wg sync.WaitGroup
+		for i := 0; i < cap(statuses); i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				payload, _ := helpers.AnyTypeToReader(map[string]any{"category": "spam"})
+				req, _ := http.NewRequest("POST", server.URL+"/report/Rcdkjh", payload)
+				req.Header.Add("Content-Type", "application/json")
+				req.Header.Add("X-Forwarded-For", "10.0.1.1")
+				resp, err := http.DefaultClient.Do(req)
+				if err != nil {
+					log.Fatal(err)
+				}
+				statuses <- resp.StatusCode
+			}()
+		}
+		wg.Wait()
+		close(statuses)
+
+		created := 0
+		for status := range statuses {
+			switch status {
+			case http.StatusCreated:
+				created++
+			case http.StatusConflict:
+			default:
+				t.Errorf("expected %v or %v got %v", http.StatusCreated, http.StatusConflict, status)
+			}
+		}
+		if created != 1 {
+			t.Errorf("expected %v created report got %v", 1, created)
+		}
+
+		count, _ := reportCollection.CountDocuments(context.TODO(), map[string]any{"urlId": raced.InsertedID})
+		if count != 1 {
+			t.Errorf("expected %v report got %v", 1, count)
+		}
+	})
+
+	t.Run("Should rate limit a visitor reporting the same link", func(t *testing.T) {
+		for _, status := range []int{404, 429} {
+			payload, _ := helpers.AnyTypeToReader(map[string]any{"category": "spam"})
+			req, _ := http.NewRequest("POST", server.URL+"/limited/Nopexa", payload)
+			req.Header.Add("Content-Type", "application/json")
+			req.Header.Add("X-Forwarded-For", "10.0.2.1")
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != status {
+				t.Errorf("expected %v got %v", status, resp.StatusCode)
+			}
+		}
+	})
+
+	var reported urlModel.Url
+	_ = urlCollection.FindOne(context.TODO(), map[string]any{"_id": url.InsertedID}).Decode(&reported)
+	if reported.Status != constant.SuspendedUrl {
+		t.Errorf("expected %v got %v", constant.SuspendedUrl, reported.Status)
+	}
+}
@@ -1,6 +1,7 @@
 package url_module_test
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
 	"codedln/shared/redis"
@@ -13,36 +14,28 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis_rate/v10"
-	"github.com/joho/godotenv"
 	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"testing"
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		wd, _ := os.Getwd()
-		dotErr := godotenv.Load(filepath.Join(wd, "../../../", ".env"))
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
-	}
-}
-
 func TestCheckAlias(t *testing.T) {
 	t.Parallel()
-	client := mongodb.ConnectToDatabase()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
@@ -71,20 +64,19 @@ func TestCheckAlias(t *testing.T) {
 		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
 	}()
 
-	urlRepo := repository.New(urlCollection)
-	urlService := service.New(urlRepo)
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
 	urlController := controller.New(urlService)
 
 	server := httptest.NewServer(middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 		urlController.CheckAliasExistence,
 		middleware.PayloadValidationMiddleware(model.NewCheckAliasSchema),
-		middleware.CorsMiddleware,
 		middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
 			Rate:   10,
 			Burst:  5,
 			Period: time.Minute * 2,
 		}),
-		middleware.ValidateAPIKeyMiddleware,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 	)))
 
 	defer server.Close()
@@ -109,7 +101,7 @@ func TestCheckAlias(t *testing.T) {
 			description:    "Should return a 400 status code with no payload",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/check_alias"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"invalid_key": "hikxys"},
 			expectedStatus: 400,
 		},
@@ -118,7 +110,7 @@ func TestCheckAlias(t *testing.T) {
 			description:    "Should return a 400 status code with invalid payload",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/check_alias"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"invalid_key": "hikxys"},
 			expectedStatus: 400,
 		},
@@ -127,16 +119,34 @@ func TestCheckAlias(t *testing.T) {
 			description:    "Should return a 200 status code with alias that does not exist",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/check_alias"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"alias": "hikxys"},
 			expectedStatus: 200,
 		},
 
+		{
+			description:    "Should return a 400 status code with an alias containing a reserved character",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/check_alias"),
+			method:         "POST",
+			clientKey:      cfg.Auth.ClientKey,
+			payload:        map[string]any{"alias": "hik?xys"},
+			expectedStatus: 400,
+		},
+
+		{
+			description:    "Should return a 400 status code with a reserved alias",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/check_alias"),
+			method:         "POST",
+			clientKey:      cfg.Auth.ClientKey,
+			payload:        map[string]any{"alias": "healthz"},
+			expectedStatus: 400,
+		},
+
 		{
 			description:    "Should return a 400 status code with alias that exist",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/check_alias"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"alias": "Huixyk"},
 			expectedStatus: 400,
 		},
@@ -145,7 +155,7 @@ func TestCheckAlias(t *testing.T) {
 			description:    "Should return a 400 status code with alias that exist",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/check_alias"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"alias": "Uinxhj"},
 			expectedStatus: 400,
 		},
@@ -1,6 +1,7 @@
 package url_module_test
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
 	"codedln/shared/redis"
@@ -14,37 +15,29 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis_rate/v10"
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"testing"
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		wd, _ := os.Getwd()
-		dotErr := godotenv.Load(filepath.Join(wd, "../../../", ".env"))
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
-	}
-}
-
 func TestCreateUrl(t *testing.T) {
 	t.Parallel()
-	client := mongodb.ConnectToDatabase()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
@@ -73,22 +66,21 @@ func TestCreateUrl(t *testing.T) {
 		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
 	}()
 
-	urlRepo := repository.New(urlCollection)
-	urlService := service.New(urlRepo)
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
 	urlController := controller.New(urlService)
 
 	server := httptest.NewServer(
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.CreateUrl,
-			middleware.AuthenticationMiddleware,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
 			middleware.PayloadValidationMiddleware(model.NewCreateUrlSchema),
-			middleware.CorsMiddleware,
 			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
 				Rate:   100,
 				Burst:  50,
 				Period: time.Minute * 2,
 			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		)))
 
 	defer server.Close()
@@ -116,7 +108,7 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      false,
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			jwt:            "",
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "Huixyk"},
 			expectedStatus: 401,
@@ -126,7 +118,7 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			jwt:            "invalid_token",
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "Huixyk"},
 			expectedStatus: 401,
@@ -136,8 +128,8 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "Huixyk"},
 			expectedStatus: 400,
 		},
@@ -146,8 +138,8 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, -1),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, -1, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "Huixyk"},
 			expectedStatus: 401,
 		},
@@ -156,8 +148,8 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        nil,
 			expectedStatus: 400,
 		},
@@ -167,8 +159,8 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"alias": ""},
 			expectedStatus: 400,
 		},
@@ -178,19 +170,30 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"originalUrl": "https://facebook.com"},
 			expectedStatus: 201,
 		},
 
+		{
+			description:    "Should return a 200 status code when reusing an existing link to the same destination",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
+			method:         "POST",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"originalUrl": "HTTPS://Facebook.com:443", "reuseExisting": true},
+			expectedStatus: 200,
+		},
+
 		{
 			description:    "Should return a 201 status code with valid url and valid alias",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "jumba"},
 			expectedStatus: 201,
 		},
@@ -200,8 +203,8 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "jumbalaskdjlfaksjdlfal"},
 			expectedStatus: 400,
 		},
@@ -211,30 +214,85 @@ func TestCreateUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "ju"},
 			expectedStatus: 400,
 		},
 
+		{
+			description:    "Should return a 400 status code with an alias that is not a single path segment",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
+			method:         "POST",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "ju/mb+a"},
+			expectedStatus: 400,
+		},
+
+		{
+			description:    "Should return a 400 status code with an alias taken by another route",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
+			method:         "POST",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "Admin"},
+			expectedStatus: 400,
+		},
+
 		{
 			description:    "Should return a 400 status code with invalid url",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"originalUrl": "today-is-a-bad-day"},
 			expectedStatus: 400,
 		},
 
+		{
+			description:    "Should return a 201 status code with an internationalized domain, port and fragment",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
+			method:         "POST",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"originalUrl": "https://bücher.example:8443/~docs/%7Eold#intro"},
+			expectedStatus: 201,
+		},
+
+		{
+			description:    "Should return a 201 status code with no scheme and a url in the query",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
+			method:         "POST",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"originalUrl": "example.com/login?next=https://x.y"},
+			expectedStatus: 201,
+		},
+
+		{
+			description:    "Should return a 400 status code with an unsupported scheme",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
+			method:         "POST",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			payload:        map[string]any{"originalUrl": "ftp://files.example.com"},
+			expectedStatus: 400,
+		},
+
 		{
 			description:    "Should return a 400 status code with alias that already exist",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/create_url"),
 			method:         "POST",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "Huixyk"},
 			expectedStatus: 400,
 		},
@@ -1,6 +1,7 @@
 package url_module_test
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
 	"codedln/shared/redis"
@@ -13,37 +14,29 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis_rate/v10"
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"testing"
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		wd, _ := os.Getwd()
-		dotErr := godotenv.Load(filepath.Join(wd, "../../../", ".env"))
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
-	}
-}
-
 func TestDeleteUrl(t *testing.T) {
 	t.Parallel()
-	client := mongodb.ConnectToDatabase()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
@@ -72,21 +65,20 @@ func TestDeleteUrl(t *testing.T) {
 		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
 	}()
 
-	urlRepo := repository.New(urlCollection)
-	urlService := service.New(urlRepo)
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
 	urlController := controller.New(urlService)
 
 	server := httptest.NewServer(
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.DeleteUrl,
-			middleware.AuthenticationMiddleware,
-			middleware.CorsMiddleware,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
 			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
 				Rate:   100,
 				Burst:  50,
 				Period: time.Minute * 2,
 			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		)))
 
 	defer server.Close()
@@ -112,7 +104,7 @@ func TestDeleteUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/delete_url/23578695"),
 			method:         "DELETE",
 			setCookie:      false,
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			jwt:            "",
 			expectedStatus: 401,
 		},
@@ -121,7 +113,7 @@ func TestDeleteUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/delete_url/23578695"),
 			method:         "DELETE",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			jwt:            "invalid_token",
 			expectedStatus: 401,
 		},
@@ -130,8 +122,8 @@ func TestDeleteUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/delete_url/23578695"),
 			method:         "DELETE",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			expectedStatus: 400,
 		},
 		{
@@ -139,8 +131,8 @@ func TestDeleteUrl(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/delete_url/12233445"),
 			method:         "DELETE",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, -1),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, -1, cfg.Auth.JwtSecret),
 			expectedStatus: 401,
 		},
 	}
@@ -1,6 +1,7 @@
 package url_module_test
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
 	"codedln/shared/redis"
@@ -13,37 +14,29 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis_rate/v10"
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"testing"
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		wd, _ := os.Getwd()
-		dotErr := godotenv.Load(filepath.Join(wd, "../../../", ".env"))
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
-	}
-}
-
 func TestGetUrls(t *testing.T) {
 	t.Parallel()
-	client := mongodb.ConnectToDatabase()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
@@ -64,6 +57,7 @@ func TestGetUrls(t *testing.T) {
 	}
 
 	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	suspended, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Jane", "lastname": "Doe", "email": "jane@codedln.com", "verified": true, "suspended": true})
 	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://google.com", "alias": "Huixyk"})
 	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://facebook.com", "alias": "Uinxhj"})
 
@@ -72,21 +66,20 @@ func TestGetUrls(t *testing.T) {
 		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
 	}()
 
-	urlRepo := repository.New(urlCollection)
-	urlService := service.New(urlRepo)
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
 	urlController := controller.New(urlService)
 
 	server := httptest.NewServer(
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.GetUrls,
-			middleware.AuthenticationMiddleware,
-			middleware.CorsMiddleware,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
 			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
 				Rate:   1000,
 				Burst:  500,
 				Period: time.Minute * 2,
 			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		)))
 
 	defer server.Close()
@@ -112,7 +105,7 @@ func TestGetUrls(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/get_urls"),
 			method:         "GET",
 			setCookie:      false,
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			jwt:            "",
 			expectedStatus: 401,
 		},
@@ -121,7 +114,7 @@ func TestGetUrls(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/get_urls"),
 			method:         "GET",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			jwt:            "invalid_token",
 			expectedStatus: 401,
 		},
@@ -130,10 +123,37 @@ func TestGetUrls(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL+"/get_urls"),
 			method:         "GET",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, -1),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, -1, cfg.Auth.JwtSecret),
+			expectedStatus: 401,
+		},
+		{
+			description:    "Should return a 401 status code when the user no longer exists",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/get_urls"),
+			method:         "GET",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: primitive.NewObjectID().Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			expectedStatus: 401,
 		},
+		{
+			description:    "Should return a 403 status code when the user was suspended after signing in",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/get_urls"),
+			method:         "GET",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: suspended.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			expectedStatus: 403,
+		},
+		{
+			description:    "Should return a 200 status code with valid jwt",
+			endpoint:       fmt.Sprintf("%s", server.URL+"/get_urls"),
+			method:         "GET",
+			setCookie:      true,
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
+			expectedStatus: 200,
+		},
 	}
 
 	for _, test := range tests {
@@ -1,6 +1,7 @@
 package url_module_test
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
 	"codedln/shared/redis"
@@ -13,36 +14,28 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis_rate/v10"
-	"github.com/joho/godotenv"
 	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"testing"
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		wd, _ := os.Getwd()
-		dotErr := godotenv.Load(filepath.Join(wd, "../../../", ".env"))
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
-	}
-}
-
 func TestGuestCreateUrl(t *testing.T) {
 	t.Parallel()
-	client := mongodb.ConnectToDatabase()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
@@ -71,20 +64,19 @@ func TestGuestCreateUrl(t *testing.T) {
 		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
 	}()
 
-	urlRepo := repository.New(urlCollection)
-	urlService := service.New(urlRepo)
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
 	urlController := controller.New(urlService)
 
 	server := httptest.NewServer(middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 		urlController.CreateUrl,
 		middleware.PayloadValidationMiddleware(model.NewCreateUrlSchema),
-		middleware.CorsMiddleware,
 		middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
 			Rate:   100,
 			Burst:  50,
 			Period: time.Minute * 2,
 		}),
-		middleware.ValidateAPIKeyMiddleware,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 	)))
 
 	defer server.Close()
@@ -109,7 +101,7 @@ func TestGuestCreateUrl(t *testing.T) {
 			description:    "Should return a 400 status code with no payload",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/guest"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        nil,
 			expectedStatus: 400,
 		},
@@ -118,7 +110,7 @@ func TestGuestCreateUrl(t *testing.T) {
 			description:    "Should return a 400 status code with no original url",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/guest"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"alias": ""},
 			expectedStatus: 400,
 		},
@@ -127,7 +119,7 @@ func TestGuestCreateUrl(t *testing.T) {
 			description:    "Should return a 201 status code with valid url and no alias",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/guest"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"originalUrl": "https://facebook.com"},
 			expectedStatus: 201,
 		},
@@ -136,7 +128,7 @@ func TestGuestCreateUrl(t *testing.T) {
 			description:    "Should return a 201 status code with valid url and valid alias",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/guest"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "jumba"},
 			expectedStatus: 201,
 		},
@@ -145,7 +137,7 @@ func TestGuestCreateUrl(t *testing.T) {
 			description:    "Should return a 400 status code with valid url and alias exceeding max characters",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/guest"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "jumbalaskdjlfaksjdlfal"},
 			expectedStatus: 400,
 		},
@@ -154,7 +146,7 @@ func TestGuestCreateUrl(t *testing.T) {
 			description:    "Should return a 400 status code with valid url and alias below min characters",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/guest"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "ju"},
 			expectedStatus: 400,
 		},
@@ -163,7 +155,7 @@ func TestGuestCreateUrl(t *testing.T) {
 			description:    "Should return a 400 status code with invalid url",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/guest"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"originalUrl": "today-is-a-bad-day"},
 			expectedStatus: 400,
 		},
@@ -172,7 +164,7 @@ func TestGuestCreateUrl(t *testing.T) {
 			description:    "Should return a 400 status code with alias that already exist",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/guest"),
 			method:         "POST",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			payload:        map[string]any{"originalUrl": "https://facebook.com", "alias": "Huixyk"},
 			expectedStatus: 400,
 		},
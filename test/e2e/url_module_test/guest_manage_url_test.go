@@ -0,0 +1,209 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestGuestManageUrl(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   100,
+		Burst:  50,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/guest", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.CreateUrl,
+		middleware.PayloadValidationMiddleware(model.NewCreateUrlSchema),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPost)
+	router.HandleFunc("/get_guest_url/{alias}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.GetGuestUrl,
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/delete_guest_url/{alias}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.DeleteGuestUrl,
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodDelete)
+	router.HandleFunc("/claim_urls", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.ClaimGuestUrls,
+		middleware.PayloadValidationMiddleware(model.NewClaimUrlsSchema),
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPost)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	send := func(method string, endpoint string, token string, jwt string, payload any) *http.Response {
+		body, _ := helpers.AnyTypeToReader(payload)
+		req, _ := http.NewRequest(method, server.URL+endpoint, body)
+		req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+		req.Header.Add("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Add(constant.ManagementTokenHeader, token)
+		}
+		if jwt != "" {
+			req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return resp
+	}
+
+	create := func() model.Url {
+		resp := send("POST", "/guest", "", "", map[string]any{"originalUrl": "https://facebook.com"})
+		var body struct {
+			StatusCode int       `json:"status_code"`
+			Data       model.Url `json:"data"`
+		}
+		_ = helpers.JSONDecode(resp.Body, &body)
+		if resp.StatusCode != 201 || body.Data.ManagementToken == "" {
+			t.Fatalf("expected a guest url with a management token got %v", resp.StatusCode)
+		}
+		return body.Data
+	}
+
+	first := create()
+	second := create()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	tests := []struct {
+		description    string
+		method         string
+		endpoint       string
+		token          string
+		jwt            string
+		payload        any
+		expectedStatus int
+	}{
+		{
+			description:    "Should return a 401 status code without a management token",
+			method:         "GET",
+			endpoint:       "/get_guest_url/" + first.Alias,
+			expectedStatus: 401,
+		},
+		{
+			description:    "Should return a 404 status code with the wrong management token",
+			method:         "GET",
+			endpoint:       "/get_guest_url/" + first.Alias,
+			token:          second.ManagementToken,
+			expectedStatus: 404,
+		},
+		{
+			description:    "Should return a 200 status code with the management token",
+			method:         "GET",
+			endpoint:       "/get_guest_url/" + first.Alias,
+			token:          first.ManagementToken,
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should return a 200 status code when deleting with the management token",
+			method:         "DELETE",
+			endpoint:       "/delete_guest_url/" + first.Alias,
+			token:          first.ManagementToken,
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should return a 401 status code when claiming without signing in",
+			method:         "POST",
+			endpoint:       "/claim_urls",
+			payload:        map[string]any{"tokens": []string{second.ManagementToken}},
+			expectedStatus: 401,
+		},
+		{
+			description:    "Should return a 200 status code when claiming guest urls",
+			method:         "POST",
+			endpoint:       "/claim_urls",
+			jwt:            jwt,
+			payload:        map[string]any{"tokens": []string{second.ManagementToken}},
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should return a 404 status code once the guest url has been claimed",
+			method:         "GET",
+			endpoint:       "/get_guest_url/" + second.Alias,
+			token:          second.ManagementToken,
+			expectedStatus: 404,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			resp := send(test.method, test.endpoint, test.token, test.jwt, test.payload)
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	count, _ := urlCollection.CountDocuments(context.TODO(), map[string]any{"userId": user.InsertedID})
+	if count != 1 {
+		t.Errorf("expected %v claimed url got %v", 1, count)
+	}
+}
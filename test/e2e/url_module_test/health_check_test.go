@@ -0,0 +1,227 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/healthcheck"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestHealthCheck(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	// Destinations the checker visits instead of real sites
+	destinations := http.NewServeMux()
+	destinations.HandleFunc("/ok", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	destinations.HandleFunc("/gone", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	destinations.HandleFunc("/moved", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/ok", http.StatusMovedPermanently)
+	})
+	destinations.HandleFunc("/get_only", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodHead {
+			w.WriteHeader(http.StatusMethodNotAllowed)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	destinationServer := httptest.NewServer(destinations)
+	defer destinationServer.Close()
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	lastWeek := time.Now().UTC().AddDate(0, 0, -7)
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": destinationServer.URL + "/ok", "alias": "Healthy", "status": constant.ActiveUrl, "clicks": 0})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": destinationServer.URL + "/moved", "alias": "Movedxx", "status": constant.ActiveUrl, "clicks": 0})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": destinationServer.URL + "/get_only", "alias": "Getonly", "status": constant.ActiveUrl, "clicks": 0})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": destinationServer.URL + "/gone", "alias": "Gonexxx", "status": constant.ActiveUrl, "clicks": 0})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": destinationServer.URL + "/gone", "alias": "Deadxxx", "status": constant.ActiveUrl, "clicks": 0, "health": map[string]any{"statusCode": 404, "checkedAt": lastWeek, "consecutiveFailures": constant.BrokenLinkThreshold - 1, "failingSince": lastWeek}})
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, healthcheck.New(destinationServer.Client(), 0, 0), nil, nil)
+	urlController := controller.New(urlService)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Minute)
+	defer cancel()
+	if err := urlService.CheckUrlHealth(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	healthTests := []struct {
+		description      string
+		alias            string
+		expectedStatus   int
+		expectedChain    int
+		expectedFailures int
+		expectedBroken   bool
+	}{
+		{
+			description:    "Should record a healthy destination",
+			alias:          "Healthy",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should follow redirects and record the chain",
+			alias:          "Movedxx",
+			expectedStatus: 200,
+			expectedChain:  1,
+		},
+		{
+			description:    "Should fall back to GET when HEAD is not allowed",
+			alias:          "Getonly",
+			expectedStatus: 200,
+		},
+		{
+			description:      "Should not flag a link after a single failure",
+			alias:            "Gonexxx",
+			expectedStatus:   404,
+			expectedFailures: 1,
+		},
+		{
+			description:      "Should flag a link that keeps failing",
+			alias:            "Deadxxx",
+			expectedStatus:   404,
+			expectedFailures: constant.BrokenLinkThreshold,
+			expectedBroken:   true,
+		},
+	}
+
+	for _, test := range healthTests {
+		t.Run(test.description, func(t *testing.T) {
+			url, _ := urlRepo.GetUrl(context.TODO(), bson.D{{"alias", test.alias}})
+			if url == nil || url.Health == nil {
+				t.Fatalf("expected %v to have been checked", test.alias)
+			}
+
+			health := url.Health
+			if health.StatusCode != test.expectedStatus {
+				t.Errorf("expected status %v got %v", test.expectedStatus, health.StatusCode)
+			}
+			if len(health.RedirectChain) != test.expectedChain {
+				t.Errorf("expected %v redirects got %v", test.expectedChain, health.RedirectChain)
+			}
+			if health.ConsecutiveFailures != test.expectedFailures || health.Broken != test.expectedBroken {
+				t.Errorf("expected %v failures and broken %v got %+v", test.expectedFailures, test.expectedBroken, health)
+			}
+		})
+	}
+
+	t.Run("Should keep the time a link started failing", func(t *testing.T) {
+		url, _ := urlRepo.GetUrl(context.TODO(), bson.D{{"alias", "Deadxxx"}})
+		if url.Health.FailingSince == nil || !url.Health.FailingSince.Equal(lastWeek.Truncate(time.Millisecond)) {
+			t.Errorf("expected the link to be failing since %v got %v", lastWeek, url.Health.FailingSince)
+		}
+	})
+
+	server := httptest.NewServer(
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetUrls,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+				Rate:   1000,
+				Burst:  500,
+				Period: time.Minute * 2,
+			}),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		)))
+
+	defer server.Close()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	listTests := []struct {
+		description     string
+		endpoint        string
+		expectedAliases []string
+	}{
+		{
+			description:     "Should list only broken links with the broken filter",
+			endpoint:        "/get_urls?broken=true",
+			expectedAliases: []string{"Deadxxx"},
+		},
+		{
+			description:     "Should list every link without the filter",
+			endpoint:        "/get_urls?sort=alias&order=asc",
+			expectedAliases: []string{"Deadxxx", "Getonly", "Gonexxx", "Healthy", "Movedxx"},
+		},
+	}
+
+	for _, test := range listTests {
+		t.Run(test.description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", server.URL+test.endpoint, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+			req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != 200 {
+				t.Fatalf("expected %v got %v", 200, resp.StatusCode)
+			}
+
+			var body struct {
+				Data types.CursorResult[model.Url] `json:"data"`
+			}
+			_ = helpers.JSONDecode(resp.Body, &body)
+
+			var aliases []string
+			for _, url := range body.Data.Data {
+				aliases = append(aliases, url.Alias)
+			}
+			if !slices.Equal(aliases, test.expectedAliases) {
+				t.Errorf("expected %v got %v", test.expectedAliases, aliases)
+			}
+		})
+	}
+}
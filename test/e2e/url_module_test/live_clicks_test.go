@@ -0,0 +1,181 @@
+package url_module_test
+
+import (
+	"bufio"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/pubsub"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLiveClicks(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	url, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://google.com/", "alias": "Livexy", "status": constant.ActiveUrl})
+	other, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://facebook.com/", "alias": "Otherx", "status": constant.ActiveUrl})
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, pubsub.New(rClient), nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   100,
+		Burst:  50,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/live", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.LiveUserClicks,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/{urlId}/live", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.LiveUrlClicks,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+	))).Methods(http.MethodGet)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	// listen opens a stream and returns the lines it receives until the request is cancelled
+	listen := func(ctx context.Context, endpoint string) (int, <-chan string) {
+		req, _ := http.NewRequestWithContext(ctx, "GET", server.URL+endpoint, nil)
+		req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+
+		lines := make(chan string)
+		go func() {
+			defer close(lines)
+			defer func() {
+				_ = resp.Body.Close()
+			}()
+			scanner := bufio.NewScanner(resp.Body)
+			for scanner.Scan() {
+				lines <- scanner.Text()
+			}
+		}()
+		return resp.StatusCode, lines
+	}
+
+	// waitFor returns the first data line of a click event, or an empty string if none arrives in time
+	waitFor := func(lines <-chan string) string {
+		timeout := time.After(3 * time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					return ""
+				}
+				if strings.HasPrefix(line, "data: ") {
+					return line
+				}
+			case <-timeout:
+				return ""
+			}
+		}
+	}
+
+	tests := []struct {
+		description string
+		endpoint    string
+		alias       string
+	}{
+		{
+			description: "Should stream clicks on a single url",
+			endpoint:    "/" + url.InsertedID.(primitive.ObjectID).Hex() + "/live",
+			alias:       "Livexy",
+		},
+		{
+			description: "Should stream clicks on all of the user's urls",
+			endpoint:    "/live",
+			alias:       "Livexy",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+
+			status, lines := listen(ctx, test.endpoint)
+			if status != 200 {
+				t.Fatalf("expected %v got %v", 200, status)
+			}
+
+			// The stream is subscribed before the headers are sent, so the click cannot be missed
+			if _, err := urlService.Redirect(context.TODO(), test.alias, model.Visit{UserAgent: "e2e"}); err != nil {
+				t.Fatal(err)
+			}
+
+			if line := waitFor(lines); !strings.Contains(line, test.alias) {
+				t.Errorf("expected a click on %v got %q", test.alias, line)
+			}
+		})
+	}
+
+	t.Run("Should return a 404 status code for another user's url", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		status, _ := listen(ctx, "/"+other.InsertedID.(primitive.ObjectID).Hex()+"/live")
+		if status != 404 {
+			t.Errorf("expected %v got %v", 404, status)
+		}
+	})
+}
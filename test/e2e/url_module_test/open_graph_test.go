@@ -0,0 +1,234 @@
+package url_module_test
+
+import (
+	"codedln/shared/analytics"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/opengraph"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenGraph(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	// The destination page whose metadata is read when the link is created
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_, _ = io.WriteString(w, `<!DOCTYPE html><html><head><title>Spring sale</title>
+<meta property="og:description" content="Everything half price">
+<meta property="og:image" content="/banner.png"></head><body>Welcome</body></html>`)
+	}))
+	defer destination.Close()
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	var urlId string
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		for _, segment := range []string{"", constant.BotKeySegment} {
+			_ = rClient.Del(context.TODO(), constant.VisitorsKeyPrefix+segment+urlId, constant.VisitorsKeyPrefix+segment+urlId+":"+today, constant.DailyClicksKeyPrefix+segment+urlId).Err()
+		}
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	bots, err := analytics.NewBotClassifier("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	urlService := service.New(urlRepo, nil, nil, analytics.New(rClient, bots, cfg.Links.VisitorHashSalt), nil, opengraph.New(destination.Client(), 0), nil)
+	urlController := controller.New(urlService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   100,
+		Burst:  50,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/create_url", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.CreateUrl,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		middleware.PayloadValidationMiddleware(model.NewCreateUrlSchema),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPost)
+	router.HandleFunc("/update_social_preview/{urlId}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.UpdateSocialPreview,
+		middleware.PayloadValidationMiddleware(model.NewOpenGraphSchema),
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPatch)
+	router.HandleFunc("/{alias}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.Visit,
+		limit,
+	))).Methods(http.MethodGet)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	// Redirects are checked rather than followed
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	send := func(method string, endpoint string, payload any, userAgent string) *http.Response {
+		body, _ := helpers.AnyTypeToReader(payload)
+		req, _ := http.NewRequest(method, server.URL+endpoint, body)
+		req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+		req.Header.Add("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := send("POST", "/create_url", map[string]any{"originalUrl": destination.URL + "/sale", "alias": "Unfurlx", "fetchMetadata": true}, "e2e")
+	if resp.StatusCode != 201 {
+		t.Fatalf("expected %v got %v when creating the link", 201, resp.StatusCode)
+	}
+	var created struct {
+		Data model.Url `json:"data"`
+	}
+	_ = helpers.JSONDecode(resp.Body, &created)
+	urlId = created.Data.ID.Hex()
+
+	// The metadata is read in the background after the link is returned
+	var metadata *model.LinkMetadata
+	for i := 0; i < 50 && metadata == nil; i++ {
+		time.Sleep(100 * time.Millisecond)
+		if url, _ := urlRepo.GetUrl(context.TODO(), bson.D{{"alias", "Unfurlx"}}); url != nil {
+			metadata = url.Metadata
+		}
+	}
+
+	t.Run("Should read the destination's metadata after creating the link", func(t *testing.T) {
+		if metadata == nil {
+			t.Fatal("expected the metadata to have been fetched")
+		}
+		if metadata.Title != "Spring sale" || metadata.Description != "Everything half price" || metadata.Image != destination.URL+"/banner.png" {
+			t.Errorf("unexpected metadata %+v", metadata)
+		}
+	})
+
+	tests := []struct {
+		description    string
+		method         string
+		endpoint       string
+		payload        any
+		userAgent      string
+		expectedStatus int
+		expectedBody   []string
+	}{
+		{
+			description:    "Should serve the fetched tags to link unfurlers",
+			method:         "GET",
+			endpoint:       "/Unfurlx",
+			userAgent:      "Slackbot-LinkExpanding 1.0 (+https://api.slack.com/robots)",
+			expectedStatus: 200,
+			expectedBody:   []string{`<meta property="og:title" content="Spring sale">`, `<meta property="og:description" content="Everything half price">`},
+		},
+		{
+			description:    "Should still redirect browsers",
+			method:         "GET",
+			endpoint:       "/Unfurlx",
+			userAgent:      "Mozilla/5.0 (Windows NT 10.0; Win64; x64) Firefox/124.0",
+			expectedStatus: 302,
+		},
+		{
+			description:    "Should return a 400 status code with an invalid preview image",
+			method:         "PATCH",
+			endpoint:       "/update_social_preview/" + urlId,
+			payload:        map[string]any{"image": "javascript:alert(1)"},
+			userAgent:      "e2e",
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should save the owner's social preview",
+			method:         "PATCH",
+			endpoint:       "/update_social_preview/" + urlId,
+			payload:        map[string]any{"title": "Half price, this week only"},
+			userAgent:      "e2e",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should prefer the owner's values and keep the fetched ones they left out",
+			method:         "GET",
+			endpoint:       "/Unfurlx",
+			userAgent:      "facebookexternalhit/1.1",
+			expectedStatus: 200,
+			expectedBody:   []string{`<meta property="og:title" content="Half price, this week only">`, `<meta property="og:description" content="Everything half price">`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			resp := send(test.method, test.endpoint, test.payload, test.userAgent)
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			for _, expected := range test.expectedBody {
+				if !strings.Contains(string(body), expected) {
+					t.Errorf("expected the page to contain %q", expected)
+				}
+			}
+		})
+	}
+}
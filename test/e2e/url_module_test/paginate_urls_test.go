@@ -0,0 +1,191 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPaginateUrls(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+
+	aliases := []string{"Pagee", "Pagea", "Paged", "Pageb", "Pagec"}
+	createdAt := time.Now().UTC().Truncate(time.Second).Add(-time.Hour)
+	for i, alias := range aliases {
+		// Two links share a click count to check that ties are broken by id
+		_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{
+			"userId":      user.InsertedID,
+			"originalUrl": "https://google.com/" + alias,
+			"alias":       alias,
+			"clicks":      int64(i % 4),
+			"createdAt":   createdAt.Add(time.Duration(i) * time.Minute),
+			"updatedAt":   createdAt.Add(time.Duration(i) * time.Minute),
+		})
+	}
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	server := httptest.NewServer(
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetUrls,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+				Rate:   100,
+				Burst:  50,
+				Period: time.Minute * 2,
+			}),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		)))
+
+	defer server.Close()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	get := func(query string) (int, types.CursorResult[model.Url]) {
+		req, _ := http.NewRequest("GET", server.URL+"/get_urls?"+query, nil)
+		req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+		req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var body struct {
+			Data types.CursorResult[model.Url] `json:"data"`
+		}
+		_ = helpers.JSONDecode(resp.Body, &body)
+		return resp.StatusCode, body.Data
+	}
+
+	// walk follows the cursors until the last page and returns the aliases in the order they were served
+	walk := func(query string) []string {
+		var seen []string
+		cursor := ""
+		for page := 0; page < len(aliases); page++ {
+			status, result := get(query + "&limit=2&cursor=" + cursor)
+			if status != 200 {
+				t.Fatalf("expected %v got %v", 200, status)
+			}
+			for _, url := range result.Data {
+				seen = append(seen, url.Alias)
+			}
+			if result.NextCursor == "" {
+				break
+			}
+			cursor = result.NextCursor
+		}
+		return seen
+	}
+
+	tests := []struct {
+		description string
+		query       string
+		expected    []string
+	}{
+		{
+			description: "Should page through urls newest first by default",
+			query:       "",
+			expected:    []string{"Pagec", "Pageb", "Paged", "Pagea", "Pagee"},
+		},
+		{
+			description: "Should page through urls by alias",
+			query:       "sort=alias&order=asc",
+			expected:    []string{"Pagea", "Pageb", "Pagec", "Paged", "Pagee"},
+		},
+		{
+			description: "Should page through urls by clicks breaking ties by id",
+			query:       "sort=clicks&order=desc",
+			expected:    []string{"Pageb", "Paged", "Pagea", "Pagec", "Pagee"},
+		},
+		{
+			description: "Should only return urls created within the range",
+			query:       "created_from=" + createdAt.Add(time.Minute).Format(time.RFC3339) + "&created_to=" + createdAt.Add(3*time.Minute).Format(time.RFC3339),
+			expected:    []string{"Pageb", "Paged", "Pagea"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			seen := walk(test.query)
+			if strings.Join(seen, ",") != strings.Join(test.expected, ",") {
+				t.Errorf("expected %v got %v", test.expected, seen)
+			}
+		})
+	}
+
+	t.Run("Should only count the total when asked", func(t *testing.T) {
+		_, result := get("")
+		if result.Total != nil {
+			t.Errorf("expected no total got %v", *result.Total)
+		}
+		_, result = get("include_total=true")
+		if result.Total == nil || *result.Total != int64(len(aliases)) {
+			t.Errorf("expected %v total", len(aliases))
+		}
+	})
+
+	t.Run("Should return a 400 status code with an invalid sort", func(t *testing.T) {
+		status, _ := get("sort=popularity")
+		if status != 400 {
+			t.Errorf("expected %v got %v", 400, status)
+		}
+	})
+
+	t.Run("Should return a 400 status code with a cursor from another sort", func(t *testing.T) {
+		_, result := get("sort=alias&limit=2")
+		status, _ := get("sort=clicks&cursor=" + result.NextCursor)
+		if status != 400 {
+			t.Errorf("expected %v got %v", 400, status)
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"context"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPassthrough(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://docs.example.com/v2/?lang=en", "alias": "Passall", "status": constant.ActiveUrl, "clicks": 0, "forwardQuery": true, "forwardPath": true})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://example.com/landing", "alias": "Passqry", "status": constant.ActiveUrl, "clicks": 0, "forwardQuery": true})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://example.com/plain", "alias": "Passnon", "status": constant.ActiveUrl, "clicks": 0})
+
+	defer func() {
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   1000,
+		Burst:  500,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{alias}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.Visit,
+		limit,
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/{alias}/{rest:.*}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.Visit,
+		limit,
+	))).Methods(http.MethodGet)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	// Redirects are checked rather than followed
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	tests := []struct {
+		description      string
+		endpoint         string
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			description:      "Should append the path and merge the query without overwriting configured parameters",
+			endpoint:         "/Passall/guides/setup?ref=newsletter&lang=fr",
+			expectedStatus:   302,
+			expectedLocation: "https://docs.example.com/v2/guides/setup?lang=en&ref=newsletter",
+		},
+		{
+			description:      "Should keep a trailing slash on the forwarded path",
+			endpoint:         "/Passall/guides/",
+			expectedStatus:   302,
+			expectedLocation: "https://docs.example.com/v2/guides/?lang=en",
+		},
+		{
+			description:      "Should forward the query on a link that only forwards queries",
+			endpoint:         "/Passqry?ref=newsletter",
+			expectedStatus:   302,
+			expectedLocation: "https://example.com/landing?ref=newsletter",
+		},
+		{
+			description:    "Should return a 404 status code for a path under a link that does not forward paths",
+			endpoint:       "/Passqry/extra",
+			expectedStatus: 404,
+		},
+		{
+			description:      "Should drop the query on a link that does not forward it",
+			endpoint:         "/Passnon?ref=newsletter",
+			expectedStatus:   302,
+			expectedLocation: "https://example.com/plain",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			resp, err := httpClient.Get(server.URL + test.endpoint)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+			if location := resp.Header.Get("Location"); location != test.expectedLocation {
+				t.Errorf("expected location %v got %v", test.expectedLocation, location)
+			}
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"context"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPreviewUrl(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	now := time.Now().UTC()
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://example.com/docs", "alias": "Previex", "title": "Example docs", "status": constant.ActiveUrl, "clicks": 7, "createdAt": now})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://example.org/download", "alias": "Warnxxx", "status": constant.ActiveUrl, "clicks": 0, "showInterstitial": true, "createdAt": now})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://example.net/secret", "alias": "Embargo", "status": constant.ActiveUrl, "clicks": 0, "activeFrom": now.Add(time.Hour), "createdAt": now})
+
+	defer func() {
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   1000,
+		Burst:  500,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/{alias}+", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.Preview,
+		limit,
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/{alias}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.Visit,
+		limit,
+	))).Methods(http.MethodGet)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	// Redirects are checked rather than followed
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	tests := []struct {
+		description    string
+		endpoint       string
+		expectedStatus int
+		expectedBody   []string
+		unexpectedBody string
+	}{
+		{
+			description:    "Should preview the destination without redirecting",
+			endpoint:       "/Previex+",
+			expectedStatus: 200,
+			expectedBody:   []string{"https://example.com/docs", "Example docs", "<dd>7</dd>"},
+		},
+		{
+			description:    "Should return a 404 status code when previewing an unknown alias",
+			endpoint:       "/Unknown+",
+			expectedStatus: 404,
+		},
+		{
+			description:    "Should not reveal the destination of a link that is not active yet",
+			endpoint:       "/Embargo+",
+			expectedStatus: 404,
+			unexpectedBody: "example.net",
+		},
+		{
+			description:    "Should show a warning before leaving when the link asks for one",
+			endpoint:       "/Warnxxx",
+			expectedStatus: 200,
+			expectedBody:   []string{"You are leaving", "https://example.org/download"},
+		},
+		{
+			description:    "Should redirect links without an interstitial",
+			endpoint:       "/Previex",
+			expectedStatus: 302,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			resp, err := httpClient.Get(server.URL + test.endpoint)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+
+			body, _ := io.ReadAll(resp.Body)
+			for _, expected := range test.expectedBody {
+				if !strings.Contains(string(body), expected) {
+					t.Errorf("expected the page to contain %q", expected)
+				}
+			}
+			if test.unexpectedBody != "" && strings.Contains(string(body), test.unexpectedBody) {
+				t.Errorf("expected the page not to contain %q", test.unexpectedBody)
+			}
+		})
+	}
+
+	// Only the redirect is counted, the preview is not
+	preview, _ := urlRepo.GetUrl(context.TODO(), bson.D{{"alias", "Previex"}})
+	if preview.Clicks != 8 {
+		t.Errorf("expected %v clicks got %v", 8, preview.Clicks)
+	}
+}
@@ -1,6 +1,7 @@
 package url_module_test
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
 	"codedln/shared/redis"
@@ -11,36 +12,28 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis_rate/v10"
-	"github.com/joho/godotenv"
 	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"testing"
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		wd, _ := os.Getwd()
-		dotErr := godotenv.Load(filepath.Join(wd, "../../../", ".env"))
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
-	}
-}
-
 func TestRedirect(t *testing.T) {
 	t.Parallel()
-	client := mongodb.ConnectToDatabase()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
@@ -69,19 +62,18 @@ func TestRedirect(t *testing.T) {
 		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
 	}()
 
-	urlRepo := repository.New(urlCollection)
-	urlService := service.New(urlRepo)
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
 	urlController := controller.New(urlService)
 
 	server := httptest.NewServer(middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 		urlController.Redirect,
-		middleware.CorsMiddleware,
 		middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
 			Rate:   1000,
 			Burst:  500,
 			Period: time.Minute * 2,
 		}),
-		middleware.ValidateAPIKeyMiddleware,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 	)))
 
 	defer server.Close()
@@ -104,7 +96,7 @@ func TestRedirect(t *testing.T) {
 			description:    "Should return a 400 status code with invalid alias",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/redirect"),
 			method:         "GET",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			expectedStatus: 400,
 		},
 
@@ -112,7 +104,7 @@ func TestRedirect(t *testing.T) {
 			description:    "Should return a 400 status code with invalid alias",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/redirect?alias=eyhsjn"),
 			method:         "GET",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			expectedStatus: 400,
 		},
 
@@ -120,7 +112,7 @@ func TestRedirect(t *testing.T) {
 			description:    "Should return a 200 status code with valid alias",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/redirect?alias=Huixyk"),
 			method:         "GET",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			expectedStatus: 200,
 		},
 
@@ -128,7 +120,7 @@ func TestRedirect(t *testing.T) {
 			description:    "Should return a 200 status code with valid alias",
 			endpoint:       fmt.Sprintf("%s", server.URL+"/redirect?alias=Uinxhj"),
 			method:         "GET",
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			expectedStatus: 200,
 		},
 	}
@@ -0,0 +1,178 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestScheduleUrl(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	now := time.Now().UTC()
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	pending, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://example.com/launch", "alias": "Launchx", "status": constant.ActiveUrl, "clicks": 0, "activeFrom": now.Add(time.Hour), "inactiveMessage": "Launching at noon"})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://example.com/sale", "alias": "Salexxx", "status": constant.ActiveUrl, "clicks": 0, "activeUntil": now.Add(-time.Hour)})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://example.com/promo", "alias": "Promoxx", "status": constant.ActiveUrl, "clicks": 0, "activeUntil": now.Add(-time.Hour), "fallbackUrl": "https://example.com/"})
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   100,
+		Burst:  50,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/update_schedule/{urlId}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.UpdateSchedule,
+		middleware.PayloadValidationMiddleware(model.NewScheduleSchema),
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPatch)
+	router.HandleFunc("/{alias}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.Visit,
+		limit,
+	))).Methods(http.MethodGet)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+	pendingId := pending.InsertedID.(primitive.ObjectID).Hex()
+
+	// Redirects are checked rather than followed
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	tests := []struct {
+		description      string
+		method           string
+		endpoint         string
+		payload          any
+		expectedStatus   int
+		expectedLocation string
+	}{
+		{
+			description:    "Should show the coming soon page before the link is active",
+			method:         "GET",
+			endpoint:       "/Launchx",
+			expectedStatus: 404,
+		},
+		{
+			description:    "Should show the expired page after the link stopped",
+			method:         "GET",
+			endpoint:       "/Salexxx",
+			expectedStatus: 410,
+		},
+		{
+			description:      "Should redirect to the fallback url outside the window",
+			method:           "GET",
+			endpoint:         "/Promoxx",
+			expectedStatus:   302,
+			expectedLocation: "https://example.com/",
+		},
+		{
+			description:    "Should return a 400 status code when the window ends before it starts",
+			method:         "PATCH",
+			endpoint:       "/update_schedule/" + pendingId,
+			payload:        map[string]any{"activeFrom": now.Add(time.Hour), "activeUntil": now},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should open the window without recreating the link",
+			method:         "PATCH",
+			endpoint:       "/update_schedule/" + pendingId,
+			payload:        map[string]any{"activeFrom": now.Add(-time.Minute), "activeUntil": now.Add(time.Hour)},
+			expectedStatus: 200,
+		},
+		{
+			description:      "Should redirect once the window is open",
+			method:           "GET",
+			endpoint:         "/Launchx",
+			expectedStatus:   302,
+			expectedLocation: "https://example.com/launch",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			body, _ := helpers.AnyTypeToReader(test.payload)
+			req, _ := http.NewRequest(test.method, server.URL+test.endpoint, body)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+			req.Header.Add("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+			if location := resp.Header.Get("Location"); location != test.expectedLocation {
+				t.Errorf("expected location %v got %v", test.expectedLocation, location)
+			}
+		})
+	}
+
+	// Fields left out of an update are cleared
+	updated, _ := urlService.GetUrl(context.TODO(), pendingId, user.InsertedID.(primitive.ObjectID).Hex())
+	if updated.InactiveMessage != "" || updated.ActiveUntil == nil {
+		t.Errorf("expected the schedule to be replaced got %+v", updated)
+	}
+}
@@ -0,0 +1,200 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/shared/screening"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestScreenUrl(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	urlCollection := db.Collection(constant.UrlCollection)
+	if urlCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UrlCollection)
+	}
+
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"originalUrl": "https://later.example", "alias": "Laterx", "status": constant.ActiveUrl})
+
+	defer func() {
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	blocklistPath := filepath.Join(t.TempDir(), "blocklist.json")
+	_ = os.WriteFile(blocklistPath, []byte(`{
+		"domains": ["evil.com", "bücher.example"],
+		"suffixes": ["badtld"],
+		"patterns": ["(?i)verify-your-account"],
+		"cidrs": ["203.0.113.0/24"]
+	}`), 0o644)
+
+	blocklist, err := screening.LoadBlocklist(blocklistPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Local fake of the safe browsing threatMatches:find api
+	safeBrowsing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]any{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		raw, _ := json.Marshal(body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.Contains(string(raw), "malware.example") {
+			_, _ = w.Write([]byte(`{"matches":[{"threatType":"MALWARE","threat":{"url":"https://malware.example"}}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer safeBrowsing.Close()
+
+	screener := screening.New(blocklist, screening.NewSafeBrowsingClient(safeBrowsing.URL, "test_key", nil))
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, screener, nil, nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	server := httptest.NewServer(
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.CreateUrl,
+			middleware.PayloadValidationMiddleware(model.NewCreateUrlSchema),
+			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+				Rate:   100,
+				Burst:  50,
+				Period: time.Minute * 2,
+			}),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		)))
+
+	defer server.Close()
+
+	tests := []struct {
+		description    string
+		payload        map[string]any
+		expectedStatus int
+	}{
+		{
+			description:    "Should return a 400 status code with a blocked domain",
+			payload:        map[string]any{"originalUrl": "https://evil.com/login"},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 400 status code with a blocked unicode domain given in punycode",
+			payload:        map[string]any{"originalUrl": "https://xn--bcher-kva.example/"},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 400 status code with a blocked domain suffix",
+			payload:        map[string]any{"originalUrl": "https://shop.cheap.badtld"},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 400 status code with a blocked pattern",
+			payload:        map[string]any{"originalUrl": "https://bank.example.com/verify-your-account"},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 400 status code with a blocked ip range",
+			payload:        map[string]any{"originalUrl": "http://203.0.113.7/payload"},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 400 status code with a destination flagged by the threat lookup",
+			payload:        map[string]any{"originalUrl": "https://malware.example"},
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 201 status code with a clean destination",
+			payload:        map[string]any{"originalUrl": "https://google.com"},
+			expectedStatus: 201,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			client := &http.Client{}
+			payload, err := helpers.AnyTypeToReader(test.payload)
+			req, err := http.NewRequest("POST", server.URL+"/guest", payload)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+			req.Header.Add("Content-Type", "application/json")
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+		})
+	}
+
+	t.Run("Should block existing links when the blocklist changes", func(t *testing.T) {
+		_ = os.WriteFile(blocklistPath, []byte(`{"domains": ["evil.com", "later.example"]}`), 0o644)
+		// Make sure the modification time moves even on filesystems with coarse timestamps
+		_ = os.Chtimes(blocklistPath, time.Now().Add(time.Minute), time.Now().Add(time.Minute))
+
+		// The watcher is stopped before the deferred disconnect so it never rescans against a closed client
+		ctx, cancel := context.WithCancel(context.TODO())
+		stopped := make(chan struct{})
+		t.Cleanup(func() {
+			cancel()
+			<-stopped
+		})
+		go func() {
+			defer close(stopped)
+			blocklist.Watch(ctx, 10*time.Millisecond, func() {
+				_ = urlService.RescanUrls(ctx)
+			})
+		}()
+
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			var url model.Url
+			_ = urlCollection.FindOne(context.TODO(), map[string]any{"alias": "Laterx"}).Decode(&url)
+			if url.Status == constant.BlockedUrl {
+				return
+			}
+			time.Sleep(20 * time.Millisecond)
+		}
+		t.Errorf("expected %v got %v", constant.BlockedUrl, "active")
+	})
+}
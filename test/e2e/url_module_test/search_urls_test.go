@@ -0,0 +1,203 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchUrls(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	if err := urlRepo.CreateIndexes(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	links := []map[string]any{
+		{"alias": "Launchx", "title": "Product launch", "tags": []string{"marketing"}, "originalUrl": "https://example.com/launch"},
+		{"alias": "Betalx", "title": "Beta launch notes", "tags": []string{"beta"}, "originalUrl": "https://docs.example.com/beta"},
+		{"alias": "Salexy", "title": "Spring sale", "tags": []string{"marketing"}, "originalUrl": "https://shop.other.org/sale"},
+		{"alias": "Huixyk", "title": "Homepage", "originalUrl": "https://google.com/"},
+	}
+	for i, link := range links {
+		link["userId"] = user.InsertedID
+		link["clicks"] = 0
+		link["createdAt"] = time.Now().UTC().Add(time.Duration(i) * time.Second)
+		_, _ = urlCollection.InsertOne(context.TODO(), link)
+	}
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	server := httptest.NewServer(
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetUrls,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+				Rate:   100,
+				Burst:  50,
+				Period: time.Minute * 2,
+			}),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		)))
+
+	defer server.Close()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	tests := []struct {
+		description    string
+		query          string
+		ordered        bool
+		expectedStatus int
+		expected       []string
+	}{
+		{
+			description:    "Should rank text matches by relevance",
+			query:          "launch",
+			ordered:        true,
+			expectedStatus: 200,
+			expected:       []string{"Launchx", "Betalx"},
+		},
+		{
+			description:    "Should leave out excluded words",
+			query:          "launch -beta",
+			expectedStatus: 200,
+			expected:       []string{"Launchx"},
+		},
+		{
+			description:    "Should match quoted phrases",
+			query:          `"spring sale"`,
+			expectedStatus: 200,
+			expected:       []string{"Salexy"},
+		},
+		{
+			description:    "Should filter by tag",
+			query:          "tag:Marketing",
+			expectedStatus: 200,
+			expected:       []string{"Launchx", "Salexy"},
+		},
+		{
+			description:    "Should filter by domain and its subdomains",
+			query:          "domain:example.com",
+			expectedStatus: 200,
+			expected:       []string{"Launchx", "Betalx"},
+		},
+		{
+			description:    "Should exclude a domain",
+			query:          "-domain:example.com",
+			expectedStatus: 200,
+			expected:       []string{"Salexy", "Huixyk"},
+		},
+		{
+			description:    "Should fall back to a substring match when the text index finds nothing",
+			query:          "uixy",
+			expectedStatus: 200,
+			expected:       []string{"Huixyk"},
+		},
+		{
+			description:    "Should treat regular expression characters literally",
+			query:          "(a+)+$",
+			expectedStatus: 200,
+			expected:       []string{},
+		},
+		{
+			description:    "Should return a 400 status code when sorting by relevance without search text",
+			query:          "tag:beta&sort=relevance",
+			expectedStatus: 400,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			query := url.QueryEscape(test.query)
+			if strings.Contains(test.query, "&") {
+				parts := strings.SplitN(test.query, "&", 2)
+				query = url.QueryEscape(parts[0]) + "&" + parts[1]
+			}
+			req, _ := http.NewRequest("GET", server.URL+"/get_urls?query="+query, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+			req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+			if test.expected == nil {
+				return
+			}
+
+			var body struct {
+				Data types.CursorResult[model.Url] `json:"data"`
+			}
+			_ = helpers.JSONDecode(resp.Body, &body)
+			got := []string{}
+			for _, url := range body.Data.Data {
+				got = append(got, url.Alias)
+			}
+
+			expected := slices.Clone(test.expected)
+			if !test.ordered {
+				slices.Sort(got)
+				slices.Sort(expected)
+			}
+			if !slices.Equal(got, expected) {
+				t.Errorf("expected %v got %v", expected, got)
+			}
+		})
+	}
+}
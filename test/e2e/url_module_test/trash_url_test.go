@@ -0,0 +1,191 @@
+package url_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestTrashUrl(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+	reservedAliasCollection := db.Collection(constant.ReservedAliasCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	url, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://google.com/", "alias": "Trashy", "status": constant.ActiveUrl})
+	_, _ = urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://google.com/old", "alias": "Oldtrash", "deletedAt": time.Now().UTC().AddDate(0, 0, -constant.DefaultTrashRetentionDays-1)})
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = reservedAliasCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	urlRepo := repository.New(urlCollection, reservedAliasCollection, db.Collection(constant.UtmTemplateCollection))
+	urlService := service.New(urlRepo, nil, nil, nil, nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   100,
+		Burst:  50,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/delete_url/{urlId}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.DeleteUrl,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodDelete)
+	router.HandleFunc("/trash", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.GetTrashedUrls,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/restore_url/{urlId}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.RestoreUrl,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPatch)
+	router.HandleFunc("/redirect", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.Redirect,
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodGet)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	urlId := url.InsertedID.(primitive.ObjectID).Hex()
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	tests := []struct {
+		description    string
+		method         string
+		endpoint       string
+		expectedStatus int
+		expectedTotal  int64
+	}{
+		{
+			description:    "Should return a 200 status code when moving a url to the trash",
+			method:         "DELETE",
+			endpoint:       "/delete_url/" + urlId,
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should return a 400 status code when redirecting a trashed url",
+			method:         "GET",
+			endpoint:       "/redirect?alias=Trashy",
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 200 status code listing the trash",
+			method:         "GET",
+			endpoint:       "/trash",
+			expectedStatus: 200,
+			expectedTotal:  2,
+		},
+		{
+			description:    "Should return a 200 status code when restoring a trashed url",
+			method:         "PATCH",
+			endpoint:       "/restore_url/" + urlId,
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should return a 404 status code when restoring a url that is not in the trash",
+			method:         "PATCH",
+			endpoint:       "/restore_url/" + urlId,
+			expectedStatus: 404,
+		},
+		{
+			description:    "Should return a 200 status code when redirecting a restored url",
+			method:         "GET",
+			endpoint:       "/redirect?alias=Trashy",
+			expectedStatus: 200,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			client := &http.Client{}
+			req, err := http.NewRequest(test.method, server.URL+test.endpoint, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+			req.Header.Add("Content-Type", "application/json")
+			req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+			resp, err := client.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Errorf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+
+			if test.expectedTotal > 0 {
+				var body struct {
+					Data types.PaginationResult[any] `json:"data"`
+				}
+				_ = helpers.JSONDecode(resp.Body, &body)
+				if body.Data.Total != test.expectedTotal {
+					t.Errorf("expected %v trashed urls got %v", test.expectedTotal, body.Data.Total)
+				}
+			}
+		})
+	}
+
+	t.Run("Should purge expired trash and keep the alias reserved", func(t *testing.T) {
+		purged, err := urlService.PurgeTrash(context.TODO(), cfg.Links.TrashRetentionDays, cfg.Links.AliasCooldownDays)
+		if err != nil || purged != 1 {
+			t.Fatalf("expected %v purged url got %v %v", 1, purged, err)
+		}
+
+		exist, err := urlService.AliasExist(context.TODO(), "Oldtrash")
+		if err != nil || !exist {
+			t.Errorf("expected purged alias to stay reserved")
+		}
+	})
+}
@@ -0,0 +1,227 @@
+package url_module_test
+
+import (
+	"codedln/shared/analytics"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestUrlStats(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	url, _ := urlCollection.InsertOne(context.TODO(), map[string]any{"userId": user.InsertedID, "originalUrl": "https://google.com/", "alias": "Statsx", "status": constant.ActiveUrl, "clicks": 0})
+	urlId := url.InsertedID.(primitive.ObjectID).Hex()
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		for _, segment := range []string{"", constant.BotKeySegment} {
+			_ = rClient.Del(context.TODO(), constant.VisitorsKeyPrefix+segment+urlId, constant.VisitorsKeyPrefix+segment+urlId+":"+today, constant.DailyClicksKeyPrefix+segment+urlId).Err()
+		}
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	bots, err := analytics.NewBotClassifier("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	urlService := service.New(urlRepo, nil, nil, analytics.New(rClient, bots, cfg.Links.VisitorHashSalt), nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	// The test client connects over loopback, which stands in for the proxy that sets X-Forwarded-For
+	router := mux.NewRouter()
+	router.Use(middleware.ClientIPMiddleware([]netip.Prefix{netip.MustParsePrefix("127.0.0.0/8"), netip.MustParsePrefix("::1/128")}))
+	router.HandleFunc("/get_stats/{urlId}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.GetStats,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+			Rate:   100,
+			Burst:  50,
+			Period: time.Minute * 2,
+		}),
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/{alias}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.Visit,
+		middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+			Rate:   100,
+			Burst:  50,
+			Period: time.Minute * 2,
+		}),
+	))).Methods(http.MethodGet, http.MethodHead)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	// Redirects are checked rather than followed
+	httpClient := &http.Client{
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+
+	// The same visitor refreshing counts as one unique visitor, and link unfurlers and link checkers are not counted by default
+	visits := []struct {
+		ip        string
+		userAgent string
+		method    string
+	}{
+		{"10.0.0.1", "Firefox", http.MethodGet},
+		{"10.0.0.1", "Firefox", http.MethodGet},
+		{"10.0.0.2", "Safari", http.MethodGet},
+		{"10.0.0.3", "Slackbot-LinkExpanding 1.0", http.MethodGet},
+		{"10.0.0.4", "Chrome", http.MethodHead},
+	}
+	for _, visit := range visits {
+		req, _ := http.NewRequest(visit.method, server.URL+"/Statsx", nil)
+		req.Header.Set("User-Agent", visit.userAgent)
+		req.Header.Set("X-Forwarded-For", visit.ip)
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_ = resp.Body.Close()
+
+		if resp.StatusCode != http.StatusFound {
+			t.Fatalf("expected %v for a %s visit got %v", http.StatusFound, visit.method, resp.StatusCode)
+		}
+	}
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	tests := []struct {
+		description      string
+		query            string
+		expectedStatus   int
+		expectedDays     int
+		expectedClicks   int64
+		expectedVisitors int64
+	}{
+		{
+			description:      "Should return the last 30 days by default",
+			expectedStatus:   200,
+			expectedDays:     constant.DefaultStatsRangeDays,
+			expectedClicks:   3,
+			expectedVisitors: 2,
+		},
+		{
+			description:      "Should return a single day range",
+			query:            "?from=" + today + "&to=" + today,
+			expectedStatus:   200,
+			expectedDays:     1,
+			expectedClicks:   3,
+			expectedVisitors: 2,
+		},
+		{
+			description:      "Should count bot clicks when asked to",
+			query:            "?include_bots=true",
+			expectedStatus:   200,
+			expectedDays:     constant.DefaultStatsRangeDays,
+			expectedClicks:   5,
+			expectedVisitors: 4,
+		},
+		{
+			description:    "Should return a 400 status code with an invalid date",
+			query:          "?from=yesterday",
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 400 status code when from is after to",
+			query:          "?from=2024-02-02&to=2024-02-01",
+			expectedStatus: 400,
+		},
+		{
+			description:    "Should return a 400 status code with a range over a year",
+			query:          "?from=2022-01-01&to=2024-01-01",
+			expectedStatus: 400,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.description, func(t *testing.T) {
+			req, _ := http.NewRequest("GET", server.URL+"/get_stats/"+urlId+test.query, nil)
+			req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+			req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				log.Fatal(err)
+			}
+
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+			if test.expectedStatus != 200 {
+				return
+			}
+
+			var body struct {
+				Data model.UrlStats `json:"data"`
+			}
+			_ = helpers.JSONDecode(resp.Body, &body)
+			stats := body.Data
+
+			if stats.Clicks != test.expectedClicks || stats.UniqueVisitors != test.expectedVisitors {
+				t.Errorf("expected %v clicks and %v unique visitors got %v and %v", test.expectedClicks, test.expectedVisitors, stats.Clicks, stats.UniqueVisitors)
+			}
+			if stats.BotClicks != 2 {
+				t.Errorf("expected %v bot clicks got %v", 2, stats.BotClicks)
+			}
+			if len(stats.Range.Days) != test.expectedDays {
+				t.Fatalf("expected %v days got %v", test.expectedDays, len(stats.Range.Days))
+			}
+			if last := stats.Range.Days[len(stats.Range.Days)-1]; last.Date != today || last.Clicks != test.expectedClicks || last.UniqueVisitors != test.expectedVisitors {
+				t.Errorf("expected today to have %v clicks and %v unique visitors got %+v", test.expectedClicks, test.expectedVisitors, last)
+			}
+			if stats.Range.Clicks != test.expectedClicks || stats.Range.UniqueVisitors != test.expectedVisitors {
+				t.Errorf("expected the range to have %v clicks and %v unique visitors got %+v", test.expectedClicks, test.expectedVisitors, stats.Range)
+			}
+		})
+	}
+}
@@ -0,0 +1,279 @@
+package url_module_test
+
+import (
+	"codedln/shared/analytics"
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/url_module/controller"
+	"codedln/url_module/model"
+	"codedln/url_module/repository"
+	"codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"context"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestUtmCampaigns(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+	utmTemplateCollection := db.Collection(constant.UtmTemplateCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	today := time.Now().UTC().Format(time.DateOnly)
+
+	var created []model.Url
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = utmTemplateCollection.DeleteMany(context.TODO(), map[string]string{})
+		for _, link := range created {
+			urlId := link.ID.Hex()
+			_ = rClient.Del(context.TODO(), constant.VisitorsKeyPrefix+urlId, constant.VisitorsKeyPrefix+urlId+":"+today, constant.DailyClicksKeyPrefix+urlId).Err()
+		}
+	}()
+
+	urlRepo := repository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), utmTemplateCollection)
+	urlService := service.New(urlRepo, nil, nil, analytics.New(rClient, nil, cfg.Links.VisitorHashSalt), nil, nil, nil)
+	urlController := controller.New(urlService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   100,
+		Burst:  50,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/create_url", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.CreateUrl,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		middleware.PayloadValidationMiddleware(model.NewCreateUrlSchema),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPost)
+	router.HandleFunc("/utm_template", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.SaveUtmTemplate,
+		middleware.PayloadValidationMiddleware(model.NewUTMSchema),
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPut)
+	router.HandleFunc("/campaigns", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.GetCampaigns,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/campaign_stats/{campaign}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		urlController.GetCampaignStats,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodGet)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: user.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	send := func(method string, endpoint string, payload any) *http.Response {
+		body, _ := helpers.AnyTypeToReader(payload)
+		req, _ := http.NewRequest(method, server.URL+endpoint, body)
+		req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+		req.Header.Add("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return resp
+	}
+
+	resp := send("PUT", "/utm_template", map[string]any{"source": "newsletter", "medium": "email"})
+	if resp.StatusCode != 200 {
+		t.Fatalf("expected %v got %v when saving the template", 200, resp.StatusCode)
+	}
+
+	createTests := []struct {
+		description    string
+		payload        map[string]any
+		expectedStatus int
+		expectedQuery  url.Values
+		expectedFrag   string
+	}{
+		{
+			description: "Should merge the utm fields and template defaults into the destination",
+			payload: map[string]any{
+				"originalUrl": "https://example.com/pricing?ref=home#plans",
+				"utm":         map[string]any{"campaign": "spring", "source": "twitter"},
+			},
+			expectedStatus: 201,
+			expectedQuery: url.Values{
+				"ref":          {"home"},
+				"utm_source":   {"twitter"},
+				"utm_medium":   {"email"},
+				"utm_campaign": {"spring"},
+			},
+			expectedFrag: "plans",
+		},
+		{
+			description: "Should replace utm parameters already in the destination",
+			payload: map[string]any{
+				"originalUrl": "https://example.com/blog?utm_campaign=old&utm_term=shoes",
+				"utm":         map[string]any{"campaign": "spring"},
+			},
+			expectedStatus: 201,
+			expectedQuery: url.Values{
+				"utm_source":   {"newsletter"},
+				"utm_medium":   {"email"},
+				"utm_campaign": {"spring"},
+				"utm_term":     {"shoes"},
+			},
+		},
+		{
+			description: "Should leave links without utm fields untouched",
+			payload: map[string]any{
+				"originalUrl": "https://example.com/about",
+			},
+			expectedStatus: 201,
+			expectedQuery:  url.Values{},
+		},
+		{
+			description: "Should return a 400 status code with an overlong utm value",
+			payload: map[string]any{
+				"originalUrl": "https://example.com/",
+				"utm":         map[string]any{"campaign": strings.Repeat("a", constant.UtmMaxLength+1)},
+			},
+			expectedStatus: 400,
+		},
+	}
+
+	for _, test := range createTests {
+		t.Run(test.description, func(t *testing.T) {
+			resp := send("POST", "/create_url", test.payload)
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+			if test.expectedStatus != 201 {
+				return
+			}
+
+			var body struct {
+				Data model.Url `json:"data"`
+			}
+			_ = helpers.JSONDecode(resp.Body, &body)
+			created = append(created, body.Data)
+
+			destination, err := url.Parse(body.Data.OriginalUrl)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if destination.Query().Encode() != test.expectedQuery.Encode() {
+				t.Errorf("expected query %v got %v", test.expectedQuery.Encode(), destination.Query().Encode())
+			}
+			if destination.Fragment != test.expectedFrag {
+				t.Errorf("expected fragment %q got %q", test.expectedFrag, destination.Fragment)
+			}
+		})
+	}
+
+	// One visitor clicking both campaign links counts once for the campaign
+	for _, link := range created {
+		if link.UTM == nil {
+			continue
+		}
+		if _, err := urlService.Redirect(context.TODO(), link.Alias, model.Visit{IP: "10.0.0.1", UserAgent: "Firefox"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Should group links by campaign", func(t *testing.T) {
+		resp := send("GET", "/campaigns", nil)
+		if resp.StatusCode != 200 {
+			t.Fatalf("expected %v got %v", 200, resp.StatusCode)
+		}
+
+		var body struct {
+			Data []model.Campaign `json:"data"`
+		}
+		_ = helpers.JSONDecode(resp.Body, &body)
+		if len(body.Data) != 1 || body.Data[0].Campaign != "spring" || body.Data[0].Links != 2 || body.Data[0].Clicks != 2 {
+			t.Errorf("expected one spring campaign with %v links and %v clicks got %+v", 2, 2, body.Data)
+		}
+	})
+
+	statsTests := []struct {
+		description    string
+		campaign       string
+		expectedStatus int
+	}{
+		{
+			description:    "Should combine the stats of the campaign's links",
+			campaign:       "spring",
+			expectedStatus: 200,
+		},
+		{
+			description:    "Should return a 404 status code for an unknown campaign",
+			campaign:       "winter",
+			expectedStatus: 404,
+		},
+	}
+
+	for _, test := range statsTests {
+		t.Run(test.description, func(t *testing.T) {
+			resp := send("GET", "/campaign_stats/"+test.campaign, nil)
+			if resp.StatusCode != test.expectedStatus {
+				t.Fatalf("expected %v got %v", test.expectedStatus, resp.StatusCode)
+			}
+			if test.expectedStatus != 200 {
+				return
+			}
+
+			var body struct {
+				Data model.CampaignStats `json:"data"`
+			}
+			_ = helpers.JSONDecode(resp.Body, &body)
+			stats := body.Data
+			if stats.Clicks != 2 || stats.UniqueVisitors != 1 || stats.Range.Clicks != 2 || stats.Range.UniqueVisitors != 1 {
+				t.Errorf("expected %v clicks and %v unique visitor got %+v", 2, 1, stats)
+			}
+		})
+	}
+}
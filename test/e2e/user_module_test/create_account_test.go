@@ -1,6 +1,7 @@
 package user_module_test
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
 	"codedln/shared/redis"
@@ -13,7 +14,6 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis_rate/v10"
-	"github.com/joho/godotenv"
 	"log"
 	"net/http"
 	"net/http/httptest"
@@ -23,29 +23,23 @@ import (
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		wd, _ := os.Getwd()
-		dotErr := godotenv.Load(filepath.Join(wd, "../../../", ".env"))
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
-	}
-}
-
 // New user should be able to create an account if it does not already exist.
 // The user should be returned with a cookie set.
 // Before Test is being run, a new Google id token must be generated. it's valid for 1 hour
 func TestCreateAccount(t *testing.T) {
 	t.Parallel()
-	client := mongodb.ConnectToDatabase()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
@@ -65,19 +59,18 @@ func TestCreateAccount(t *testing.T) {
 	}()
 
 	userRepo := repository.New(collection)
-	userService := service.New(userRepo)
-	userController := controller.New(userService)
+	userService := service.New(userRepo, nil, cfg.Auth.GoogleClientId)
+	userController := controller.New(userService, cfg.Auth, cfg.Production())
 
 	server := httptest.NewServer(middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 		userController.CreateUser,
 		middleware.PayloadValidationMiddleware(model.NewCreateUserSchema),
-		middleware.CorsMiddleware,
 		middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
 			Rate:   10,
 			Burst:  5,
 			Period: time.Minute * 2,
 		}),
-		middleware.ValidateAPIKeyMiddleware,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 	)))
 
 	defer server.Close()
@@ -107,7 +100,7 @@ func TestCreateAccount(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL),
 			method:         "POST",
 			payload:        map[string]string{"signInWith": "google"},
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			userExist:      false,
 			authenticate:   false,
 			expectedStatus: 400,
@@ -117,7 +110,7 @@ func TestCreateAccount(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL),
 			method:         "POST",
 			payload:        map[string]string{"idToken": "invalidIdToken"},
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			userExist:      false,
 			authenticate:   false,
 			expectedStatus: 400,
@@ -128,7 +121,7 @@ func TestCreateAccount(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL),
 			method:         "POST",
 			payload:        map[string]string{"idToken": os.Getenv("GOOGLE_ID_TOKEN"), "signInWith": "google"},
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			userExist:      false,
 			authenticate:   true,
 			expectedStatus: 201,
@@ -139,7 +132,7 @@ func TestCreateAccount(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL),
 			method:         "POST",
 			payload:        map[string]string{"idToken": os.Getenv("GOOGLE_ID_TOKEN"), "signInWith": "google"},
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			userExist:      true,
 			authenticate:   true,
 			expectedStatus: 201,
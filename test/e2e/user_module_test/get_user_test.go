@@ -1,6 +1,7 @@
 package user_module
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
 	"codedln/shared/mongodb"
 	"codedln/shared/redis"
@@ -14,38 +15,30 @@ import (
 	"context"
 	"fmt"
 	"github.com/go-redis/redis_rate/v10"
-	"github.com/joho/godotenv"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"log"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"path/filepath"
 	"testing"
 	"time"
 )
 
-func init() {
-	if os.Getenv("ENVIRONMENT") == "development" || os.Getenv("ENVIRONMENT") == "" {
-		wd, _ := os.Getwd()
-		dotErr := godotenv.Load(filepath.Join(wd, "../../../", ".env"))
-		if dotErr != nil {
-			log.Fatalf("Error loading .env file: %v", dotErr)
-		}
-	}
-}
-
 func TestGetUser(t *testing.T) {
 
 	t.Parallel()
-	client := mongodb.ConnectToDatabase()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
 
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
 
 	//Connect to redis
-	rClient := redis.ConnectToRedis()
+	rClient := redis.ConnectToRedis(cfg.Redis)
 	defer func() {
 		_ = client.Disconnect(context.TODO())
 	}()
@@ -67,19 +60,18 @@ func TestGetUser(t *testing.T) {
 	}()
 
 	userRepo := repository.New(collection)
-	userService := service.New(userRepo)
-	userController := controller.New(userService)
+	userService := service.New(userRepo, nil, cfg.Auth.GoogleClientId)
+	userController := controller.New(userService, cfg.Auth, cfg.Production())
 
 	server := httptest.NewServer(middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 		userController.GetUser,
-		middleware.AuthenticationMiddleware,
-		middleware.CorsMiddleware,
+		middleware.AuthenticationMiddleware(collection, cfg.Auth.JwtSecret),
 		middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
 			Rate:   10,
 			Burst:  5,
 			Period: time.Minute * 2,
 		}),
-		middleware.ValidateAPIKeyMiddleware,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 	)))
 
 	defer server.Close()
@@ -107,7 +99,7 @@ func TestGetUser(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL),
 			method:         "GET",
 			setCookie:      false,
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			jwt:            "",
 			expectedStatus: 401,
 		},
@@ -116,7 +108,7 @@ func TestGetUser(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL),
 			method:         "GET",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
+			clientKey:      cfg.Auth.ClientKey,
 			jwt:            "invalid_token",
 			expectedStatus: 401,
 		},
@@ -125,8 +117,8 @@ func TestGetUser(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL),
 			method:         "GET",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: r.InsertedID.(primitive.ObjectID).Hex()}, -1),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: r.InsertedID.(primitive.ObjectID).Hex()}, -1, cfg.Auth.JwtSecret),
 			expectedStatus: 401,
 		},
 		{
@@ -134,8 +126,8 @@ func TestGetUser(t *testing.T) {
 			endpoint:       fmt.Sprintf("%s", server.URL),
 			method:         "GET",
 			setCookie:      true,
-			clientKey:      os.Getenv("CLIENT_KEY"),
-			jwt:            helpers.CreateJWT(types.AuthUser{UserId: r.InsertedID.(primitive.ObjectID).Hex()}, constant.AccessTokenTTL),
+			clientKey:      cfg.Auth.ClientKey,
+			jwt:            helpers.CreateJWT(types.AuthUser{UserId: r.InsertedID.(primitive.ObjectID).Hex()}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret),
 			expectedStatus: 200,
 		},
 	}
@@ -0,0 +1,301 @@
+package webhook_module_test
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/redis"
+	"codedln/shared/webhook"
+	urlModel "codedln/url_module/model"
+	urlRepository "codedln/url_module/repository"
+	urlService "codedln/url_module/service"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"codedln/webhook_module/controller"
+	"codedln/webhook_module/model"
+	"codedln/webhook_module/repository"
+	"codedln/webhook_module/service"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// received is a request the receiver accepted, along with whether its signature checked out.
+type received struct {
+	event    model.Event
+	signed   bool
+	delivery string
+}
+
+func TestWebhooks(t *testing.T) {
+	t.Parallel()
+	cfg, cfgErr := config.Load(filepath.Join("..", "..", "..", ".env"))
+	if cfgErr != nil {
+		t.Fatal(cfgErr)
+	}
+	client := mongodb.ConnectToDatabase(cfg.Mongo)
+
+	defer func() {
+		_ = client.Disconnect(context.TODO())
+	}()
+
+	//Connect to redis
+	rClient := redis.ConnectToRedis(cfg.Redis)
+	defer func() {
+		_ = rClient.Close()
+	}()
+
+	//Initialize RateLimiter
+	rateLimiter := redis_rate.NewLimiter(rClient)
+
+	db := client.Database("codedln_test_database")
+
+	userCollection := db.Collection(constant.UserCollection)
+	urlCollection := db.Collection(constant.UrlCollection)
+	endpointCollection := db.Collection(constant.WebhookEndpointCollection)
+	deliveryCollection := db.Collection(constant.WebhookDeliveryCollection)
+
+	user, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Martin", "lastname": "Alemajoh", "email": "alemajohmartin@gmail.com", "verified": true})
+	other, _ := userCollection.InsertOne(context.TODO(), map[string]any{"firstname": "Ada", "lastname": "Lovelace", "email": "ada@example.com", "verified": true})
+	otherEndpoint, _ := endpointCollection.InsertOne(context.TODO(), map[string]any{"userId": other.InsertedID, "url": "https://example.org/hook", "events": []string{"click"}, "secret": "whsec_other"})
+
+	defer func() {
+		_, _ = userCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = urlCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = endpointCollection.DeleteMany(context.TODO(), map[string]string{})
+		_, _ = deliveryCollection.DeleteMany(context.TODO(), map[string]string{})
+	}()
+
+	// The receiver turns the first request away so the retry can be seen, then accepts everything
+	var mu sync.Mutex
+	var secret string
+	var requests int
+	var accepted []received
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+
+		mu.Lock()
+		defer mu.Unlock()
+		requests++
+		if requests == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		var event model.Event
+		_ = json.Unmarshal(body, &event)
+		accepted = append(accepted, received{
+			event:    event,
+			signed:   webhook.Verify(secret, r.Header.Get(constant.WebhookTimestampHeader), r.Header.Get(constant.WebhookSignatureHeader), body, time.Minute),
+			delivery: r.Header.Get(constant.WebhookDeliveryHeader),
+		})
+	}))
+	defer receiver.Close()
+
+	webhookRepo := repository.New(endpointCollection, deliveryCollection)
+	if err := webhookRepo.CreateIndexes(context.TODO()); err != nil {
+		t.Fatal(err)
+	}
+	webhookService := service.New(webhookRepo, webhook.New(receiver.Client(), 0))
+	webhookController := controller.New(webhookService)
+
+	urlRepo := urlRepository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection))
+	links := urlService.New(urlRepo, nil, nil, nil, nil, nil, webhookService)
+
+	limit := middleware.RateLimitMiddleware(rateLimiter, redis_rate.Limit{
+		Rate:   100,
+		Burst:  50,
+		Period: time.Minute * 2,
+	})
+
+	router := mux.NewRouter()
+	router.HandleFunc("/create_endpoint", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		webhookController.CreateEndpoint,
+		middleware.PayloadValidationMiddleware(model.NewCreateEndpointSchema),
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPost)
+	router.HandleFunc("/endpoints", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		webhookController.GetEndpoints,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/deliveries/{endpointId}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		webhookController.GetDeliveries,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodGet)
+	router.HandleFunc("/replay/{deliveryId}", middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+		webhookController.ReplayDelivery,
+		middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+		limit,
+		middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+	))).Methods(http.MethodPost)
+
+	server := httptest.NewServer(router)
+
+	defer server.Close()
+
+	userId := user.InsertedID.(primitive.ObjectID).Hex()
+	jwt := helpers.CreateJWT(types.AuthUser{UserId: userId}, constant.DefaultAccessTokenTTL, cfg.Auth.JwtSecret)
+
+	send := func(method string, endpoint string, payload any, out any) int {
+		body, _ := helpers.AnyTypeToReader(payload)
+		req, _ := http.NewRequest(method, server.URL+endpoint, body)
+		req.Header.Add("Authorization", fmt.Sprintf("%s %s", "Bearer", cfg.Auth.ClientKey))
+		req.Header.Add("Content-Type", "application/json")
+		req.AddCookie(&http.Cookie{Name: constant.JwtCookieName, Value: jwt})
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if out != nil {
+			_ = helpers.JSONDecode(resp.Body, out)
+		}
+		return resp.StatusCode
+	}
+
+	// deliver sends everything that is due, first bringing any scheduled retries forward
+	deliver := func() {
+		_, _ = deliveryCollection.UpdateMany(context.TODO(), bson.D{{"status", constant.PendingDelivery}}, bson.D{{"$set", bson.D{{"nextAttemptAt", time.Now().UTC()}}}})
+		if err := webhookService.Deliver(context.TODO()); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	t.Run("Should return a 400 status code with an unknown event", func(t *testing.T) {
+		if status := send("POST", "/create_endpoint", map[string]any{"url": receiver.URL, "events": []string{"url.visited"}}, nil); status != 400 {
+			t.Errorf("expected %v got %v", 400, status)
+		}
+	})
+
+	var created struct {
+		Data model.CreatedEndpoint `json:"data"`
+	}
+	if status := send("POST", "/create_endpoint", map[string]any{"url": receiver.URL + "/hook", "events": []string{"url.created", "click", "click.threshold"}}, &created); status != 201 {
+		t.Fatalf("expected %v got %v when creating the endpoint", 201, status)
+	}
+	mu.Lock()
+	secret = created.Data.Secret
+	mu.Unlock()
+
+	t.Run("Should only return the secret when the endpoint is created", func(t *testing.T) {
+		if created.Data.Secret == "" {
+			t.Fatalf("expected a secret when creating the endpoint")
+		}
+
+		var listed struct {
+			Data []map[string]any `json:"data"`
+		}
+		if status := send("GET", "/endpoints", nil, &listed); status != 200 {
+			t.Fatalf("expected %v got %v", 200, status)
+		}
+		for _, endpoint := range listed.Data {
+			if _, ok := endpoint["secret"]; ok {
+				t.Errorf("expected no secret when listing endpoints got %v", endpoint)
+			}
+		}
+	})
+
+	url, _, err := links.CreateUrl(context.TODO(), urlModel.CreateUrlSchema{OriginalUrl: "https://example.com/launch", Alias: "Hookedx"}, userId)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("Should retry a failed delivery and sign it", func(t *testing.T) {
+		deliver()
+		deliver()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if requests != 2 || len(accepted) != 1 {
+			t.Fatalf("expected %v requests with %v accepted got %v and %v", 2, 1, requests, len(accepted))
+		}
+		if accepted[0].event.Type != constant.UrlCreatedWebhook || !accepted[0].signed {
+			t.Errorf("expected a signed %v event got %+v", constant.UrlCreatedWebhook, accepted[0])
+		}
+	})
+
+	var history struct {
+		Data types.PaginationResult[model.Delivery] `json:"data"`
+	}
+	t.Run("Should log every attempt of the delivery", func(t *testing.T) {
+		if status := send("GET", "/deliveries/"+created.Data.ID.Hex(), nil, &history); status != 200 {
+			t.Fatalf("expected %v got %v", 200, status)
+		}
+		if history.Data.Total != 1 {
+			t.Fatalf("expected %v delivery got %v", 1, history.Data.Total)
+		}
+		delivery := history.Data.Data[0]
+		if delivery.Status != constant.SucceededDelivery || len(delivery.Attempts) != 2 || delivery.Attempts[0].StatusCode != http.StatusServiceUnavailable {
+			t.Errorf("expected a delivery that succeeded on its second attempt got %+v", delivery)
+		}
+	})
+
+	t.Run("Should replay a delivery with the same event", func(t *testing.T) {
+		if len(history.Data.Data) == 0 {
+			t.Skip("no delivery to replay")
+		}
+		original := history.Data.Data[0]
+		if status := send("POST", "/replay/"+original.ID.Hex(), nil, nil); status != 201 {
+			t.Fatalf("expected %v got %v", 201, status)
+		}
+		deliver()
+
+		mu.Lock()
+		defer mu.Unlock()
+		if len(accepted) != 2 || accepted[1].event.ID != original.EventId || accepted[1].delivery == original.ID.Hex() {
+			t.Errorf("expected the event to be sent again as a new delivery got %+v", accepted)
+		}
+	})
+
+	t.Run("Should send clicks and click milestones", func(t *testing.T) {
+		_, _ = urlCollection.UpdateOne(context.TODO(), bson.D{{"_id", url.ID}}, bson.D{{"$set", bson.D{{"clicks", constant.FirstClickMilestone - 1}}}})
+		visit := urlModel.Visit{IP: "10.0.0.1", UserAgent: "Firefox", Method: http.MethodGet, Header: http.Header{"User-Agent": []string{"Firefox"}}}
+		if _, err := links.Redirect(context.TODO(), "Hookedx", visit); err != nil {
+			t.Fatal(err)
+		}
+
+		// Clicks are queued in the background so the redirect is not held up
+		filter := bson.D{{"endpointId", created.Data.ID}, {"event", bson.D{{"$in", bson.A{constant.ClickWebhook, constant.ClickThresholdWebhook}}}}}
+		for i := 0; i < 50; i++ {
+			if queued, _ := deliveryCollection.CountDocuments(context.TODO(), filter); queued == 2 {
+				break
+			}
+			time.Sleep(100 * time.Millisecond)
+		}
+		deliver()
+
+		mu.Lock()
+		defer mu.Unlock()
+		events := map[types.WebhookEvent]bool{}
+		for _, request := range accepted {
+			events[request.event.Type] = true
+		}
+		if !events[constant.ClickWebhook] || !events[constant.ClickThresholdWebhook] {
+			t.Errorf("expected click and click.threshold events got %+v", accepted)
+		}
+	})
+
+	t.Run("Should return a 404 status code for another user's endpoint", func(t *testing.T) {
+		if status := send("GET", "/deliveries/"+otherEndpoint.InsertedID.(primitive.ObjectID).Hex(), nil, nil); status != 404 {
+			t.Errorf("expected %v got %v", 404, status)
+		}
+	})
+}
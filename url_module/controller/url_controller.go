@@ -2,16 +2,20 @@ package controller
 
 import (
 	"codedln/shared/http_error"
+	"codedln/shared/sse"
+	"codedln/shared/view"
 	"codedln/url_module/model"
 	"codedln/url_module/service"
 	"codedln/util/constant"
 	"codedln/util/helpers"
 	"codedln/util/types"
-	"fmt"
+	"errors"
 	"github.com/gorilla/mux"
 	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 )
 
 type UrlController struct {
@@ -51,12 +55,12 @@ func (c *UrlController) CreateUrl(w http.ResponseWriter, r *http.Request) error
 		return http_error.New(http.StatusBadRequest, "invalid url payload")
 	}
 
-	url, err := c.urlService.CreateUrl(r.Context(), UrlPayload.OriginalUrl, UrlPayload.Alias, userId)
+	url, created, err := c.urlService.CreateUrl(r.Context(), UrlPayload, userId)
 	if err != nil {
 		return err
 	}
 
-	return helpers.JSONResponse(w, http.StatusCreated, url)
+	return helpers.JSONResponse(w, helpers.Ternary(created, http.StatusCreated, http.StatusOK), url)
 }
 
 func (c *UrlController) CheckAliasExistence(w http.ResponseWriter, r *http.Request) error {
@@ -91,43 +95,12 @@ func (c *UrlController) GetUrls(w http.ResponseWriter, r *http.Request) error {
 		return http_error.New(http.StatusBadRequest, "invalid user id")
 	}
 
-	query := r.URL.Query()
-
-	var sort types.DateSort
-	var limit int64
-	var skip int64
-
-	search := query.Get("query")
-	sortStr := query.Get("date_sort")
-
-	switch sortStr {
-	case "-1":
-		sort = constant.NewestDate
-		break
-	case "1":
-		sort = constant.OldestDate
-		break
-	default:
-		sort = constant.NewestDate
-	}
-
-	limitStr := query.Get("limit")
-	val, err := strconv.ParseInt(limitStr, 10, 64)
-	if err != nil || val < 0 {
-		limit = constant.MaxLimit
-	} else {
-		limit = val
-	}
-
-	skipStr := query.Get("skip")
-	val, err = strconv.ParseInt(skipStr, 10, 64)
-	if err != nil || val < 0 {
-		skip = 0
-	} else {
-		skip = val * limit
+	query, err := model.NewUrlListQuery(r.URL.Query())
+	if err != nil {
+		return err
 	}
 
-	result, err := c.urlService.GetUrls(r.Context(), search, sort, limit, skip, UserIdPayload.UserId)
+	result, err := c.urlService.GetUrls(r.Context(), query, UserIdPayload.UserId)
 	if err != nil {
 		return err
 	}
@@ -148,7 +121,6 @@ func (c *UrlController) GetUrl(w http.ResponseWriter, r *http.Request) error {
 	}
 
 	vars := mux.Vars(r)
-	fmt.Println("RAW: ", vars)
 	urlId, exist := vars["urlId"]
 	if !exist {
 		return http_error.New(http.StatusBadRequest, "no url id found")
@@ -162,6 +134,72 @@ func (c *UrlController) GetUrl(w http.ResponseWriter, r *http.Request) error {
 	return helpers.JSONResponse(w, http.StatusOK, url)
 }
 
+func (c *UrlController) UpdateSchedule(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	urlId, exist := mux.Vars(r)["urlId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no url id found")
+	}
+
+	ScheduleValue := r.Context().Value(constant.PayloadKey)
+	if ScheduleValue == nil {
+		return http_error.New(http.StatusBadRequest, "no schedule provided")
+	}
+	SchedulePayload, ok := ScheduleValue.(model.ScheduleSchema)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid schedule payload")
+	}
+
+	updated, err := c.urlService.UpdateSchedule(r.Context(), urlId, UserIdPayload.UserId, SchedulePayload)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, updated)
+}
+
+func (c *UrlController) UpdateSocialPreview(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	urlId, exist := mux.Vars(r)["urlId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no url id found")
+	}
+
+	PreviewValue := r.Context().Value(constant.PayloadKey)
+	if PreviewValue == nil {
+		return http_error.New(http.StatusBadRequest, "no social preview provided")
+	}
+	PreviewPayload, ok := PreviewValue.(model.OpenGraph)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid social preview payload")
+	}
+
+	updated, err := c.urlService.UpdateSocialPreview(r.Context(), urlId, UserIdPayload.UserId, PreviewPayload)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, updated)
+}
+
 func (c *UrlController) DeleteUrl(w http.ResponseWriter, r *http.Request) error {
 	UserIDValue := r.Context().Value(constant.AuthUserKey)
 
@@ -214,6 +252,150 @@ func (c *UrlController) DeleteUrls(w http.ResponseWriter, r *http.Request) error
 	return helpers.JSONResponse(w, http.StatusOK, nil)
 }
 
+func (c *UrlController) GetTrashedUrls(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	query := r.URL.Query()
+
+	var limit int64
+	var skip int64
+
+	val, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil || val <= 0 || val > constant.MaxLimit {
+		limit = constant.MaxLimit
+	} else {
+		limit = val
+	}
+
+	val, err = strconv.ParseInt(query.Get("skip"), 10, 64)
+	if err != nil || val < 0 {
+		skip = 0
+	} else {
+		skip = val * limit
+	}
+
+	result, err := c.urlService.GetTrashedUrls(r.Context(), limit, skip, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, result)
+}
+
+func (c *UrlController) RestoreUrl(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	urlId, exist := mux.Vars(r)["urlId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no url id found")
+	}
+
+	_, err := c.urlService.RestoreUrls(r.Context(), []string{urlId}, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, nil)
+}
+
+func (c *UrlController) RestoreUrls(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	url := r.URL.Query().Get("url")
+	if url == "" {
+		return http_error.New(http.StatusBadRequest, "one url id must be provided")
+	}
+
+	restored, err := c.urlService.RestoreUrls(r.Context(), strings.Split(url, ","), UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, map[string]int64{"restored": restored})
+}
+
+func (c *UrlController) GetGuestUrl(w http.ResponseWriter, r *http.Request) error {
+	alias, exist := mux.Vars(r)["alias"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no alias found")
+	}
+
+	url, err := c.urlService.GetGuestUrl(r.Context(), alias, r.Header.Get(constant.ManagementTokenHeader))
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, url)
+}
+
+func (c *UrlController) DeleteGuestUrl(w http.ResponseWriter, r *http.Request) error {
+	alias, exist := mux.Vars(r)["alias"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no alias found")
+	}
+
+	err := c.urlService.DeleteGuestUrl(r.Context(), alias, r.Header.Get(constant.ManagementTokenHeader))
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, nil)
+}
+
+func (c *UrlController) ClaimGuestUrls(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	ClaimValue := r.Context().Value(constant.PayloadKey)
+
+	if ClaimValue == nil {
+		return http_error.New(http.StatusBadRequest, "unable to get payload")
+	}
+
+	ClaimPayload, ok := ClaimValue.(model.ClaimUrlsSchema)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid claim payload")
+	}
+
+	claimed, err := c.urlService.ClaimGuestUrls(r.Context(), ClaimPayload.Tokens, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, map[string]int64{"claimed": claimed})
+}
+
 func (c *UrlController) Redirect(w http.ResponseWriter, r *http.Request) error {
 	query := r.URL.Query()
 	alias := query.Get("alias")
@@ -221,10 +403,310 @@ func (c *UrlController) Redirect(w http.ResponseWriter, r *http.Request) error {
 		return http_error.New(http.StatusBadRequest, "no alias found")
 	}
 
-	originalUrl, err := c.urlService.Redirect(r.Context(), alias)
+	redirect, err := c.urlService.Redirect(r.Context(), alias, visit(r))
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, redirect.Destination)
+}
+
+func (c *UrlController) Visit(w http.ResponseWriter, r *http.Request) error {
+	alias, exist := mux.Vars(r)["alias"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no alias found")
+	}
+
+	v := visit(r)
+	v.Path = mux.Vars(r)["rest"]
+	v.Query = r.URL.Query()
+
+	redirect, err := c.urlService.Redirect(r.Context(), alias, v)
+	if err != nil {
+		return unavailable(w, alias, err)
+	}
+
+	// Crawlers get a page carrying the link's social preview, which sends anything that runs it on to the destination
+	if redirect.Unfurl != nil {
+		return view.Render(w, http.StatusOK, "unfurl.html", map[string]any{
+			"Url":         requestUrl(r),
+			"Destination": redirect.Destination,
+			"Host":        hostOf(redirect.Destination),
+			"Title":       redirect.Unfurl.Title,
+			"Description": redirect.Unfurl.Description,
+			"Image":       redirect.Unfurl.Image,
+		})
+	}
+
+	if redirect.Interstitial {
+		return view.Render(w, http.StatusOK, "interstitial.html", map[string]any{
+			"Alias":       alias,
+			"Destination": redirect.Destination,
+			"Host":        hostOf(redirect.Destination),
+		})
+	}
+
+	http.Redirect(w, r, redirect.Destination, http.StatusFound)
+	return nil
+}
+
+// Preview shows where a link goes without following it.
+func (c *UrlController) Preview(w http.ResponseWriter, r *http.Request) error {
+	alias, exist := mux.Vars(r)["alias"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no alias found")
+	}
+
+	preview, err := c.urlService.Preview(r.Context(), alias)
+	if err != nil {
+		return unavailable(w, alias, err)
+	}
+
+	unfurl := preview.Unfurl()
+	return view.Render(w, http.StatusOK, "preview.html", map[string]any{
+		"Alias":       alias,
+		"Destination": preview.OriginalUrl,
+		"Host":        hostOf(preview.OriginalUrl),
+		"Title":       unfurl.Title,
+		"Description": unfurl.Description,
+		"Image":       unfurl.Image,
+		"CreatedAt":   preview.CreatedAt,
+		"Clicks":      preview.Clicks,
+	})
+}
+
+func (c *UrlController) GetStats(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	urlId, exist := mux.Vars(r)["urlId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no url id found")
+	}
+
+	query := r.URL.Query()
+	from, to, err := statsRange(query)
+	if err != nil {
+		return err
+	}
+
+	stats, err := c.urlService.GetStats(r.Context(), urlId, UserIdPayload.UserId, from, to, query.Get("include_bots") == "true")
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, stats)
+}
+
+func (c *UrlController) GetCampaigns(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	campaigns, err := c.urlService.GetCampaigns(r.Context(), UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, campaigns)
+}
+
+func (c *UrlController) GetCampaignStats(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	campaign, exist := mux.Vars(r)["campaign"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no campaign found")
+	}
+
+	query := r.URL.Query()
+	from, to, err := statsRange(query)
+	if err != nil {
+		return err
+	}
+
+	stats, err := c.urlService.GetCampaignStats(r.Context(), campaign, UserIdPayload.UserId, from, to, query.Get("include_bots") == "true")
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, stats)
+}
+
+func (c *UrlController) GetUtmTemplate(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	template, err := c.urlService.GetUtmTemplate(r.Context(), UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, template)
+}
+
+func (c *UrlController) SaveUtmTemplate(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	UtmValue := r.Context().Value(constant.PayloadKey)
+	if UtmValue == nil {
+		return http_error.New(http.StatusBadRequest, "no utm template provided")
+	}
+	UtmPayload, ok := UtmValue.(model.UTM)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid utm template payload")
+	}
+
+	template, err := c.urlService.SaveUtmTemplate(r.Context(), UtmPayload, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, template)
+}
+
+func (c *UrlController) LiveUrlClicks(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	urlId, exist := mux.Vars(r)["urlId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no url id found")
+	}
+
+	clicks, err := c.urlService.LiveUrlClicks(r.Context(), urlId, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return sse.Stream(w, r, "click", clicks)
+}
+
+func (c *UrlController) LiveUserClicks(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	clicks, err := c.urlService.LiveUserClicks(r.Context(), UserIdPayload.UserId)
 	if err != nil {
 		return err
 	}
 
-	return helpers.JSONResponse(w, http.StatusOK, originalUrl)
+	return sse.Stream(w, r, "click", clicks)
+}
+
+// unavailable shows the page explaining why a link cannot be followed, or returns the error when there is no page for it.
+func unavailable(w http.ResponseWriter, alias string, err error) error {
+	var inactive *service.InactiveError
+	if errors.As(err, &inactive) {
+		page := helpers.Ternary(errors.Is(err, service.ErrUrlExpired), "expired.html", "coming_soon.html")
+		return view.Render(w, inactive.StatusCode, page, map[string]any{"Alias": alias, "Message": inactive.Message})
+	}
+	if errors.Is(err, service.ErrUrlDisabled) {
+		return view.Render(w, http.StatusGone, "disabled.html", map[string]any{"Alias": alias})
+	}
+	return err
+}
+
+// hostOf is the host a destination points at, shown on its own so look-alike paths cannot hide it.
+func hostOf(destination string) string {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return ""
+	}
+	return parsed.Host
+}
+
+// requestUrl rebuilds the address the request was made to, as the short link the visitor shared.
+func requestUrl(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+// visit collects what the service needs to know about the visitor from the request.
+func visit(r *http.Request) model.Visit {
+	return model.Visit{
+		IP:        helpers.GetClientIP(r),
+		UserAgent: r.UserAgent(),
+		Referrer:  r.Referer(),
+		Host:      r.Host,
+		Method:    r.Method,
+		Header:    r.Header,
+	}
+}
+
+// statsRange reads the from and to dates of a stats request.
+// Ranges are whole UTC days and default to the last 30 days including today.
+func statsRange(query url.Values) (time.Time, time.Time, error) {
+	to := time.Now().UTC().Truncate(24 * time.Hour)
+	if toStr := query.Get("to"); toStr != "" {
+		parsed, err := time.Parse(time.DateOnly, toStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, http_error.New(http.StatusBadRequest, "to must be a date like 2006-01-02")
+		}
+		to = parsed
+	}
+
+	from := to.AddDate(0, 0, 1-constant.DefaultStatsRangeDays)
+	if fromStr := query.Get("from"); fromStr != "" {
+		parsed, err := time.Parse(time.DateOnly, fromStr)
+		if err != nil {
+			return time.Time{}, time.Time{}, http_error.New(http.StatusBadRequest, "from must be a date like 2006-01-02")
+		}
+		from = parsed
+	}
+
+	return from, to, nil
 }
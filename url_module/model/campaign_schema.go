@@ -0,0 +1,27 @@
+package model
+
+import (
+	"codedln/shared/analytics"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Campaign is the combined activity of a user's links that share a utm campaign.
+type Campaign struct {
+	Campaign      string               `bson:"_id" json:"campaign"`
+	Links         int64                `bson:"links" json:"links"`
+	Clicks        int64                `bson:"clicks" json:"clicks"`
+	BotClicks     int64                `bson:"botClicks" json:"botClicks"`
+	LastClickedAt *time.Time           `bson:"lastClickedAt,omitempty" json:"lastClickedAt,omitempty"`
+	UrlIds        []primitive.ObjectID `bson:"urlIds" json:"-"`
+}
+
+// CampaignStats is a campaign's totals with its visitors counted once across all of its links.
+type CampaignStats struct {
+	Campaign
+	IncludesBots   bool             `json:"includesBots"`
+	UniqueVisitors int64            `json:"uniqueVisitors"`
+	From           string           `json:"from"`
+	To             string           `json:"to"`
+	Range          *analytics.Range `json:"range"`
+}
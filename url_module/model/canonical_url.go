@@ -0,0 +1,108 @@
+package model
+
+import (
+	"codedln/util/constant"
+	"errors"
+	"golang.org/x/net/idna"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// leadingScheme only matches a scheme at the start, so a url in the query of a scheme-less url is not taken for its scheme
+var leadingScheme = regexp.MustCompile(`^[a-zA-Z][a-zA-Z0-9+.-]*://`)
+
+var allowedSchemes = map[string]string{
+	"http":  "80",
+	"https": "443",
+}
+
+// CanonicalUrl validates a destination and returns its canonical form so the same destination is always stored the same way.
+// A missing scheme defaults to https, the scheme and host are lowercased, internationalized hosts are converted to punycode,
+// the default port is dropped and an empty path becomes "/".
+func CanonicalUrl(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", errors.New("url is required")
+	}
+
+	if len(raw) > constant.MaxUrlLength {
+		return "", errors.New("url is too long")
+	}
+
+	// Without a scheme the url would be resolved relative to our own host
+	if !leadingScheme.MatchString(raw) {
+		raw = "https://" + raw
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", errors.New("url could not be parsed")
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	defaultPort, ok := allowedSchemes[u.Scheme]
+	if !ok {
+		return "", errors.New("only http and https urls are allowed")
+	}
+
+	// Credentials in the url are a common way to disguise the real host, e.g. https://bank.com@evil.com
+	if u.User != nil {
+		return "", errors.New("urls with credentials are not allowed")
+	}
+
+	host, err := canonicalHost(u.Hostname())
+	if err != nil {
+		return "", err
+	}
+
+	port := u.Port()
+	if port != "" {
+		p, portErr := strconv.Atoi(port)
+		if portErr != nil || p < 1 || p > 65535 {
+			return "", errors.New("invalid port")
+		}
+		port = strconv.Itoa(p)
+	}
+
+	if port != "" && port != defaultPort {
+		u.Host = net.JoinHostPort(host, port)
+	} else if strings.Contains(host, ":") {
+		u.Host = "[" + host + "]"
+	} else {
+		u.Host = host
+	}
+
+	if u.Path == "" {
+		u.Path = "/"
+		u.RawPath = ""
+	}
+
+	return u.String(), nil
+}
+
+func canonicalHost(hostname string) (string, error) {
+	hostname = strings.TrimSuffix(hostname, ".")
+	if hostname == "" {
+		return "", errors.New("url must have a host")
+	}
+
+	if ip := net.ParseIP(hostname); ip != nil {
+		return ip.String(), nil
+	}
+
+	host, err := idna.Lookup.ToASCII(hostname)
+	if err != nil {
+		return "", errors.New("invalid host")
+	}
+	host = strings.ToLower(host)
+
+	// Single label hosts such as localhost only resolve inside a private network
+	if !strings.Contains(host, ".") {
+		return "", errors.New("host must be a fully qualified domain name")
+	}
+
+	return host, nil
+}
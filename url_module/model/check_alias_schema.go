@@ -4,8 +4,17 @@ import (
 	"codedln/shared/http_error"
 	"codedln/util/constant"
 	"net/http"
+	"regexp"
+	"slices"
+	"strings"
 )
 
+// aliasPattern keeps aliases to a single path segment. The same characters are used by generated aliases.
+var aliasPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// reservedAliases are the first path segments of the other routes. Links with these aliases could never be visited.
+var reservedAliases = []string{"admin", "bio", "healthz", "p", "readyz", "report", "url", "user", "webhook"}
+
 type CheckAliasSchema struct {
 	Alias string `json:"alias"`
 }
@@ -15,8 +24,22 @@ func NewCheckAliasSchema() CheckAliasSchema {
 }
 
 func (s CheckAliasSchema) Validate() error {
-	if len(s.Alias) < constant.AliasMinLength || len(s.Alias) > constant.AliasMaxLength {
+	return ValidateAlias(s.Alias)
+}
+
+// ValidateAlias checks that a custom alias can be served from the root path.
+func ValidateAlias(alias string) error {
+	if len(alias) < constant.AliasMinLength || len(alias) > constant.AliasMaxLength {
 		return http_error.New(http.StatusBadRequest, "alias length must be within 3 and 8 characters")
 	}
+
+	if !aliasPattern.MatchString(alias) {
+		return http_error.New(http.StatusBadRequest, "alias can only contain letters, digits, - and _")
+	}
+
+	if slices.Contains(reservedAliases, strings.ToLower(alias)) {
+		return http_error.New(http.StatusBadRequest, "alias is reserved")
+	}
+
 	return nil
 }
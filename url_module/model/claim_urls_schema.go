@@ -0,0 +1,29 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"net/http"
+)
+
+type ClaimUrlsSchema struct {
+	Tokens []string `json:"tokens"`
+}
+
+func NewClaimUrlsSchema() ClaimUrlsSchema {
+	return ClaimUrlsSchema{}
+}
+
+func (s ClaimUrlsSchema) Validate() error {
+	if len(s.Tokens) == 0 || len(s.Tokens) > constant.MaxGuestTokens {
+		return http_error.New(http.StatusBadRequest, "between 1 and 50 tokens must be provided")
+	}
+
+	for _, token := range s.Tokens {
+		if token == "" {
+			return http_error.New(http.StatusBadRequest, "tokens must not be empty")
+		}
+	}
+
+	return nil
+}
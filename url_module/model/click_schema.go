@@ -0,0 +1,66 @@
+package model
+
+import (
+	"codedln/util/constant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Visit is what is known about the request that followed a short link.
+// Path and Query are whatever came after the alias, forwarded when the link allows it.
+type Visit struct {
+	IP        string
+	UserAgent string
+	Referrer  string
+	Host      string
+	Method    string
+	Header    http.Header
+	Path      string
+	Query     url.Values
+}
+
+// Source is where the visit came from for referral counts: "bio:" and the handle when it was clicked on one of our bio pages,
+// otherwise the referring site's host. Visits without a referrer have no source.
+func (v Visit) Source() string {
+	referrer, err := url.Parse(v.Referrer)
+	if err != nil || referrer.Hostname() == "" {
+		return ""
+	}
+
+	if referrer.Host == v.Host {
+		if handle, ok := strings.CutPrefix(referrer.Path, "/p/"); ok && handle != "" && !strings.Contains(handle, "/") {
+			return constant.BioReferralPrefix + strings.ToLower(handle)
+		}
+	}
+
+	return strings.ToLower(referrer.Hostname())
+}
+
+// Redirect is where a visit is sent. Interstitial asks for a warning page before leaving,
+// and Unfurl is set when the visitor is a crawler and the link has a social preview to show it instead of redirecting.
+type Redirect struct {
+	Destination  string
+	Interstitial bool
+	Unfurl       *OpenGraph
+}
+
+// ClickEvent is published for every redirect. It leaves out the visitor's ip so live streams carry no raw personal data.
+type ClickEvent struct {
+	UrlId     primitive.ObjectID `json:"urlId"`
+	Alias     string             `json:"alias"`
+	Referrer  string             `json:"referrer,omitempty"`
+	UserAgent string             `json:"userAgent,omitempty"`
+	Bot       bool               `json:"bot"`
+	ClickedAt time.Time          `json:"clickedAt"`
+}
+
+// ClickThresholdEvent is sent to webhooks when a link's click count reaches a milestone.
+type ClickThresholdEvent struct {
+	UrlId     primitive.ObjectID `json:"urlId"`
+	Alias     string             `json:"alias"`
+	Clicks    int64              `json:"clicks"`
+	ReachedAt time.Time          `json:"reachedAt"`
+}
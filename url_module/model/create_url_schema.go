@@ -4,14 +4,25 @@ import (
 	"codedln/shared/http_error"
 	"codedln/util/constant"
 	"net/http"
-	"regexp"
+	"strings"
+	"time"
 )
 
-var validUrl = regexp.MustCompile(`^(https?://)?([\da-z.-]+)\.([a-z.]{2,6})([/\w .-]*)*/?(\?\S*)?$`)
-
 type CreateUrlSchema struct {
 	OriginalUrl string `json:"originalUrl"`
 	Alias       string `json:"alias"`
+	// ReuseExisting returns the caller's existing link to the same destination instead of creating another one
+	ReuseExisting    bool       `json:"reuseExisting"`
+	Title            string     `json:"title"`
+	Notes            string     `json:"notes"`
+	Tags             []string   `json:"tags"`
+	UTM              *UTM       `json:"utm"`
+	ForwardQuery     bool       `json:"forwardQuery"`
+	ForwardPath      bool       `json:"forwardPath"`
+	ShowInterstitial bool       `json:"showInterstitial"`
+	FetchMetadata    bool       `json:"fetchMetadata"`
+	SocialPreview    *OpenGraph `json:"socialPreview"`
+	ScheduleSchema
 }
 
 func NewCreateUrlSchema() CreateUrlSchema {
@@ -19,12 +30,52 @@ func NewCreateUrlSchema() CreateUrlSchema {
 }
 
 func (s CreateUrlSchema) Validate() error {
-	if s.Alias != "" && (len(s.Alias) < constant.AliasMinLength || len(s.Alias) > constant.AliasMaxLength) {
-		return http_error.New(http.StatusBadRequest, "alias length must be within 8 characters")
+	if s.Alias != "" {
+		if err := ValidateAlias(s.Alias); err != nil {
+			return err
+		}
+	}
+
+	if _, err := CanonicalUrl(s.OriginalUrl); err != nil {
+		return http_error.New(http.StatusBadRequest, "invalid url: "+err.Error())
+	}
+
+	if len(s.Title) > constant.TitleMaxLength {
+		return http_error.New(http.StatusBadRequest, "title must be at most 200 characters")
+	}
+
+	if len(s.Notes) > constant.NotesMaxLength {
+		return http_error.New(http.StatusBadRequest, "notes must be at most 1000 characters")
+	}
+
+	if len(s.Tags) > constant.MaxTags {
+		return http_error.New(http.StatusBadRequest, "at most 20 tags can be added")
+	}
+
+	for _, tag := range s.Tags {
+		if tag = NormalizeTag(tag); tag == "" || len(tag) > constant.TagMaxLength || strings.ContainsAny(tag, " \t\n\"") {
+			return http_error.New(http.StatusBadRequest, "tags must be single words of at most 32 characters")
+		}
+	}
+
+	if s.UTM != nil {
+		if err := s.UTM.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if s.SocialPreview != nil {
+		if err := s.SocialPreview.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := s.ScheduleSchema.Validate(); err != nil {
+		return err
 	}
 
-	if !validUrl.MatchString(s.OriginalUrl) {
-		return http_error.New(http.StatusBadRequest, "invalid url")
+	if s.ActiveUntil != nil && !s.ActiveUntil.After(time.Now()) {
+		return http_error.New(http.StatusBadRequest, "activeUntil must be in the future")
 	}
 
 	return nil
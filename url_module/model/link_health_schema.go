@@ -0,0 +1,16 @@
+package model
+
+import "time"
+
+// LinkHealth is the outcome of the latest check of a link's destination.
+// A link is only flagged as broken once its checks have failed BrokenLinkThreshold times in a row, so a brief outage is not reported.
+type LinkHealth struct {
+	StatusCode          int        `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	LatencyMs           int64      `bson:"latencyMs" json:"latencyMs"`
+	RedirectChain       []string   `bson:"redirectChain,omitempty" json:"redirectChain,omitempty"`
+	Error               string     `bson:"error,omitempty" json:"error,omitempty"`
+	CheckedAt           time.Time  `bson:"checkedAt" json:"checkedAt"`
+	ConsecutiveFailures int        `bson:"consecutiveFailures" json:"consecutiveFailures"`
+	FailingSince        *time.Time `bson:"failingSince,omitempty" json:"failingSince,omitempty"`
+	Broken              bool       `bson:"broken" json:"broken"`
+}
@@ -0,0 +1,72 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// OpenGraph is what a link shows when it is shared on social and chat platforms.
+type OpenGraph struct {
+	Title       string `bson:"title,omitempty" json:"title,omitempty"`
+	Description string `bson:"description,omitempty" json:"description,omitempty"`
+	Image       string `bson:"image,omitempty" json:"image,omitempty"`
+}
+
+// LinkMetadata is the OpenGraph read from the destination page when the link was created.
+// FetchError is set instead when the page could not be read.
+type LinkMetadata struct {
+	OpenGraph  `bson:",inline"`
+	FetchError string    `bson:"fetchError,omitempty" json:"fetchError,omitempty"`
+	FetchedAt  time.Time `bson:"fetchedAt" json:"fetchedAt"`
+}
+
+func NewOpenGraphSchema() OpenGraph {
+	return OpenGraph{}
+}
+
+func (o OpenGraph) Validate() error {
+	if len(o.Title) > constant.TitleMaxLength {
+		return http_error.New(http.StatusBadRequest, "preview title must be at most 200 characters")
+	}
+
+	if len(o.Description) > constant.DescriptionMaxLength {
+		return http_error.New(http.StatusBadRequest, "preview description must be at most 300 characters")
+	}
+
+	if o.Image != "" {
+		if _, err := CanonicalUrl(o.Image); err != nil {
+			return http_error.New(http.StatusBadRequest, "invalid preview image: "+err.Error())
+		}
+	}
+
+	return nil
+}
+
+func (o OpenGraph) IsZero() bool {
+	return o == OpenGraph{}
+}
+
+func (o OpenGraph) Trimmed() OpenGraph {
+	return OpenGraph{
+		Title:       strings.TrimSpace(o.Title),
+		Description: strings.TrimSpace(o.Description),
+		Image:       strings.TrimSpace(o.Image),
+	}
+}
+
+// WithDefaults fills the values left empty from the defaults.
+func (o OpenGraph) WithDefaults(defaults OpenGraph) OpenGraph {
+	if o.Title == "" {
+		o.Title = defaults.Title
+	}
+	if o.Description == "" {
+		o.Description = defaults.Description
+	}
+	if o.Image == "" {
+		o.Image = defaults.Image
+	}
+	return o
+}
@@ -0,0 +1,39 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"net/http"
+	"time"
+)
+
+// ScheduleSchema is a link's active window and what visitors outside it see.
+// Updating a schedule replaces all of it, so leaving a field out clears it.
+type ScheduleSchema struct {
+	ActiveFrom      *time.Time `json:"activeFrom"`
+	ActiveUntil     *time.Time `json:"activeUntil"`
+	FallbackUrl     string     `json:"fallbackUrl"`
+	InactiveMessage string     `json:"inactiveMessage"`
+}
+
+func NewScheduleSchema() ScheduleSchema {
+	return ScheduleSchema{}
+}
+
+func (s ScheduleSchema) Validate() error {
+	if s.ActiveFrom != nil && s.ActiveUntil != nil && !s.ActiveUntil.After(*s.ActiveFrom) {
+		return http_error.New(http.StatusBadRequest, "activeUntil must be after activeFrom")
+	}
+
+	if s.FallbackUrl != "" {
+		if _, err := CanonicalUrl(s.FallbackUrl); err != nil {
+			return http_error.New(http.StatusBadRequest, "invalid fallback url: "+err.Error())
+		}
+	}
+
+	if len(s.InactiveMessage) > constant.InactiveMessageMaxLength {
+		return http_error.New(http.StatusBadRequest, "inactive message must be at most 500 characters")
+	}
+
+	return nil
+}
@@ -0,0 +1,119 @@
+package model
+
+import (
+	"strings"
+	"unicode"
+)
+
+// SearchQuery is a parsed link search.
+//
+// Plain words and "quoted phrases" are matched against the text index. A leading "-" excludes a word, phrase, tag or domain.
+// tag:name matches links with that tag and domain:example.com matches destinations on that domain or its subdomains.
+type SearchQuery struct {
+	Words          []string
+	Phrases        []string
+	ExcludeWords   []string
+	Tags           []string
+	ExcludeTags    []string
+	Domains        []string
+	ExcludeDomains []string
+}
+
+func ParseSearch(raw string) SearchQuery {
+	var query SearchQuery
+
+	for _, token := range tokenize(raw) {
+		exclude := strings.HasPrefix(token, "-") && len(token) > 1
+		if exclude {
+			token = token[1:]
+		}
+
+		switch {
+		case strings.HasPrefix(token, `"`):
+			phrase := strings.TrimSpace(strings.Trim(token, `"`))
+			if phrase == "" {
+				continue
+			}
+			if exclude {
+				query.ExcludeWords = append(query.ExcludeWords, `"`+phrase+`"`)
+			} else {
+				query.Phrases = append(query.Phrases, phrase)
+			}
+		case strings.HasPrefix(strings.ToLower(token), "tag:") && len(token) > len("tag:"):
+			tag := NormalizeTag(token[len("tag:"):])
+			if exclude {
+				query.ExcludeTags = append(query.ExcludeTags, tag)
+			} else {
+				query.Tags = append(query.Tags, tag)
+			}
+		case strings.HasPrefix(strings.ToLower(token), "domain:") && len(token) > len("domain:"):
+			domain := strings.TrimPrefix(strings.ToLower(token[len("domain:"):]), "www.")
+			if exclude {
+				query.ExcludeDomains = append(query.ExcludeDomains, domain)
+			} else {
+				query.Domains = append(query.Domains, domain)
+			}
+		case exclude:
+			query.ExcludeWords = append(query.ExcludeWords, token)
+		default:
+			query.Words = append(query.Words, token)
+		}
+	}
+
+	return query
+}
+
+// HasText reports whether the search has anything for the text index to rank.
+// Exclusions alone are not enough because mongo text search needs at least one positive term.
+func (q SearchQuery) HasText() bool {
+	return len(q.Words) > 0 || len(q.Phrases) > 0
+}
+
+// TextSearch renders the words, phrases and exclusions in mongo $text syntax.
+func (q SearchQuery) TextSearch() string {
+	parts := append([]string{}, q.Words...)
+	for _, phrase := range q.Phrases {
+		parts = append(parts, `"`+phrase+`"`)
+	}
+	for _, word := range q.ExcludeWords {
+		parts = append(parts, "-"+word)
+	}
+	return strings.Join(parts, " ")
+}
+
+// Literal is the positive free text of the search, used for substring matching when the text index finds nothing.
+func (q SearchQuery) Literal() string {
+	return strings.Join(append(append([]string{}, q.Words...), q.Phrases...), " ")
+}
+
+// NormalizeTag lowercases a tag and trims surrounding space so tags compare consistently.
+func NormalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimSpace(tag))
+}
+
+// tokenize splits on whitespace while keeping quoted phrases, including a leading "-", as one token.
+func tokenize(raw string) []string {
+	var tokens []string
+	var current strings.Builder
+	quoted := false
+
+	for _, r := range raw {
+		switch {
+		case r == '"':
+			quoted = !quoted
+			current.WriteRune(r)
+		case unicode.IsSpace(r) && !quoted:
+			if current.Len() > 0 {
+				tokens = append(tokens, current.String())
+				current.Reset()
+			}
+		default:
+			current.WriteRune(r)
+		}
+	}
+	if current.Len() > 0 {
+		tokens = append(tokens, current.String())
+	}
+
+	return tokens
+}
@@ -0,0 +1,169 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"codedln/util/types"
+	"encoding/base64"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// UrlListQuery describes one page of a user's links.
+type UrlListQuery struct {
+	Search       SearchQuery
+	Literal      bool
+	Campaign     string
+	Broken       bool
+	SortBy       types.UrlSort
+	Order        types.DateSort
+	Limit        int64
+	Cursor       *UrlCursor
+	CreatedFrom  *time.Time
+	CreatedTo    *time.Time
+	UpdatedFrom  *time.Time
+	UpdatedTo    *time.Time
+	IncludeTotal bool
+}
+
+// UrlCursor points just after the last link of a page. It is handed to clients as an opaque token.
+// The sort and order are kept in the token so it cannot be replayed against a different ordering.
+type UrlCursor struct {
+	SortBy  types.UrlSort      `bson:"s"`
+	Order   types.DateSort     `bson:"o"`
+	Value   any                `bson:"v"`
+	ID      primitive.ObjectID `bson:"id"`
+	Literal bool               `bson:"l,omitempty"`
+}
+
+var sortFields = map[types.UrlSort]string{
+	constant.SortByCreatedAt:   "createdAt",
+	constant.SortByUpdatedAt:   "updatedAt",
+	constant.SortByAlias:       "alias",
+	constant.SortByClicks:      "clicks",
+	constant.SortByLastClicked: "lastClickedAt",
+	constant.SortByExpiry:      "activeUntil",
+	constant.SortByRelevance:   "score",
+}
+
+// SortField returns the document field a sort orders by.
+func SortField(sort types.UrlSort) string {
+	return sortFields[sort]
+}
+
+// NewUrlListQuery reads the listing options from the request query string.
+func NewUrlListQuery(values url.Values) (UrlListQuery, error) {
+	query := UrlListQuery{
+		Search:       ParseSearch(values.Get("query")),
+		Campaign:     strings.TrimSpace(values.Get("campaign")),
+		Broken:       values.Get("broken") == "true",
+		SortBy:       constant.SortByCreatedAt,
+		Order:        constant.NewestDate,
+		Limit:        constant.MaxLimit,
+		IncludeTotal: values.Get("include_total") == "true",
+	}
+
+	if sort := values.Get("sort"); sort != "" {
+		if _, ok := sortFields[types.UrlSort(sort)]; !ok {
+			return query, http_error.New(http.StatusBadRequest, "invalid sort")
+		}
+		query.SortBy = types.UrlSort(sort)
+	}
+
+	// date_sort is the older way of choosing the order and is still honoured when order is not given
+	switch values.Get("order") {
+	case "asc":
+		query.Order = constant.OldestDate
+	case "desc":
+		query.Order = constant.NewestDate
+	case "":
+		if values.Get("date_sort") == "1" {
+			query.Order = constant.OldestDate
+		}
+	default:
+		return query, http_error.New(http.StatusBadRequest, "order must be asc or desc")
+	}
+
+	if limit, err := strconv.ParseInt(values.Get("limit"), 10, 64); err == nil && limit > 0 && limit < constant.MaxLimit {
+		query.Limit = limit
+	}
+
+	// Searches are ranked by relevance unless another sort is asked for. Relevance is always best match first.
+	if values.Get("sort") == "" && query.Search.HasText() {
+		query.SortBy = constant.SortByRelevance
+	}
+	if query.SortBy == constant.SortByRelevance {
+		if !query.Search.HasText() {
+			return query, http_error.New(http.StatusBadRequest, "relevance sort needs search text")
+		}
+		query.Order = constant.NewestDate
+	}
+
+	if token := values.Get("cursor"); token != "" {
+		cursor, err := DecodeCursor(token)
+		if err != nil {
+			return query, err
+		}
+		// Pages after a substring fallback carry on in that mode, which has no relevance to rank by
+		if cursor.Literal {
+			query.Literal = true
+			if query.SortBy == constant.SortByRelevance {
+				query.SortBy = constant.SortByCreatedAt
+			}
+		}
+		if cursor.SortBy != query.SortBy || cursor.Order != query.Order {
+			return query, http_error.New(http.StatusBadRequest, "cursor does not match the requested sort")
+		}
+		query.Cursor = cursor
+	}
+
+	dates := []struct {
+		key    string
+		target **time.Time
+	}{
+		{"created_from", &query.CreatedFrom},
+		{"created_to", &query.CreatedTo},
+		{"updated_from", &query.UpdatedFrom},
+		{"updated_to", &query.UpdatedTo},
+	}
+	for _, date := range dates {
+		raw := values.Get(date.key)
+		if raw == "" {
+			continue
+		}
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return query, http_error.New(http.StatusBadRequest, date.key+" must be an RFC 3339 date")
+		}
+		*date.target = &parsed
+	}
+
+	return query, nil
+}
+
+func EncodeCursor(cursor UrlCursor) (string, error) {
+	raw, err := bson.Marshal(cursor)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+func DecodeCursor(token string) (*UrlCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, http_error.New(http.StatusBadRequest, "invalid cursor")
+	}
+
+	var cursor UrlCursor
+	if err = bson.Unmarshal(raw, &cursor); err != nil || cursor.ID.IsZero() {
+		return nil, http_error.New(http.StatusBadRequest, "invalid cursor")
+	}
+
+	return &cursor, nil
+}
@@ -1,15 +1,143 @@
 package model
 
 import (
+	"codedln/util/constant"
+	"codedln/util/types"
 	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/url"
+	"path"
+	"strings"
 	"time"
 )
 
 type Url struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
-	UserId      primitive.ObjectID `bson:"userId,omitempty" json:"userId"`
-	OriginalUrl string             `bson:"originalUrl" json:"originalUrl"`
-	Alias       string             `bson:"alias" json:"alias"`
-	CreatedAt   time.Time          `bson:"createdAt" json:"createdAt"`
-	UpdatedAt   time.Time          `bson:"updatedAt" json:"updatedAt"`
+	ID                  primitive.ObjectID `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId              primitive.ObjectID `bson:"userId,omitempty" json:"userId"`
+	OriginalUrl         string             `bson:"originalUrl" json:"originalUrl"`
+	Alias               string             `bson:"alias" json:"alias"`
+	CustomAlias         bool               `bson:"customAlias" json:"customAlias"`
+	Title               string             `bson:"title,omitempty" json:"title,omitempty"`
+	Notes               string             `bson:"notes,omitempty" json:"notes,omitempty"`
+	Tags                []string           `bson:"tags,omitempty" json:"tags,omitempty"`
+	UTM                 *UTM               `bson:"utm,omitempty" json:"utm,omitempty"`
+	Metadata            *LinkMetadata      `bson:"metadata,omitempty" json:"metadata,omitempty"`
+	SocialPreview       *OpenGraph         `bson:"socialPreview,omitempty" json:"socialPreview,omitempty"`
+	ForwardQuery        bool               `bson:"forwardQuery,omitempty" json:"forwardQuery"`
+	ForwardPath         bool               `bson:"forwardPath,omitempty" json:"forwardPath"`
+	ShowInterstitial    bool               `bson:"showInterstitial,omitempty" json:"showInterstitial"`
+	DestinationHash     string             `bson:"destinationHash,omitempty" json:"-"`
+	Status              types.UrlStatus    `bson:"status,omitempty" json:"status,omitempty"`
+	StatusReason        string             `bson:"statusReason,omitempty" json:"statusReason,omitempty"`
+	ManagementTokenHash string             `bson:"managementTokenHash,omitempty" json:"-"`
+	ManagementToken     string             `bson:"-" json:"managementToken,omitempty"`
+	Clicks              int64              `bson:"clicks" json:"clicks"`
+	BotClicks           int64              `bson:"botClicks" json:"botClicks"`
+	LastClickedAt       *time.Time         `bson:"lastClickedAt,omitempty" json:"lastClickedAt,omitempty"`
+	ActiveFrom          *time.Time         `bson:"activeFrom,omitempty" json:"activeFrom,omitempty"`
+	ActiveUntil         *time.Time         `bson:"activeUntil,omitempty" json:"activeUntil,omitempty"`
+	FallbackUrl         string             `bson:"fallbackUrl,omitempty" json:"fallbackUrl,omitempty"`
+	InactiveMessage     string             `bson:"inactiveMessage,omitempty" json:"inactiveMessage,omitempty"`
+	Health              *LinkHealth        `bson:"health,omitempty" json:"health,omitempty"`
+	DeletedAt           *time.Time         `bson:"deletedAt,omitempty" json:"deletedAt,omitempty"`
+	Score               float64            `bson:"score,omitempty" json:"score,omitempty"`
+	CreatedAt           time.Time          `bson:"createdAt" json:"createdAt"`
+	UpdatedAt           time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+// IsDisabled reports whether the link was disabled or taken down by an admin, suspended after abuse reports,
+// or blocked by destination screening. Links created before moderation existed have no status and are treated as active.
+func (u Url) IsDisabled() bool {
+	switch u.Status {
+	case constant.DisabledUrl, constant.TakenDownUrl, constant.SuspendedUrl, constant.BlockedUrl:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsPending reports whether the link has an activation time that has not been reached yet.
+func (u Url) IsPending() bool {
+	return u.ActiveFrom != nil && time.Now().Before(*u.ActiveFrom)
+}
+
+// IsExpired reports whether the link had an end to its active window and it has passed.
+func (u Url) IsExpired() bool {
+	return u.ActiveUntil != nil && !time.Now().Before(*u.ActiveUntil)
+}
+
+// Destination is where a visit is sent. Links that forward paths append the extra path segments to the destination path,
+// and links that forward queries add the visitor's query parameters, leaving any the destination already sets alone.
+func (u Url) Destination(extraPath string, query url.Values) (string, error) {
+	forwardPath := u.ForwardPath && extraPath != ""
+	forwardQuery := u.ForwardQuery && len(query) > 0
+	if !forwardPath && !forwardQuery {
+		return u.OriginalUrl, nil
+	}
+
+	destination, err := url.Parse(u.OriginalUrl)
+	if err != nil {
+		return "", err
+	}
+
+	if forwardPath {
+		// Cleaning against the root keeps "../" from climbing above the destination path
+		rest := path.Clean("/" + extraPath)
+		if strings.HasSuffix(extraPath, "/") && rest != "/" {
+			rest += "/"
+		}
+		destination.Path = strings.TrimSuffix(destination.Path, "/") + rest
+		destination.RawPath = ""
+	}
+
+	if forwardQuery {
+		merged := destination.Query()
+		for key, values := range query {
+			if _, configured := merged[key]; !configured {
+				merged[key] = values
+			}
+		}
+		destination.RawQuery = merged.Encode()
+	}
+
+	return destination.String(), nil
+}
+
+// Unfurl is what crawlers are shown for the link. The owner's social preview wins over what was read from the destination,
+// and the link's own title is used when neither has one.
+func (u Url) Unfurl() OpenGraph {
+	var unfurl OpenGraph
+	if u.SocialPreview != nil {
+		unfurl = *u.SocialPreview
+	}
+	if u.Metadata != nil {
+		unfurl = unfurl.WithDefaults(u.Metadata.OpenGraph)
+	}
+	return unfurl.WithDefaults(OpenGraph{Title: u.Title})
+}
+
+// SortValue returns the value the link is ordered by for the given sort, used to build the next page cursor.
+// Unset optional dates are returned as an untyped nil so they are encoded as null.
+func (u Url) SortValue(sort types.UrlSort) any {
+	switch sort {
+	case constant.SortByUpdatedAt:
+		return u.UpdatedAt
+	case constant.SortByAlias:
+		return u.Alias
+	case constant.SortByClicks:
+		return u.Clicks
+	case constant.SortByLastClicked:
+		if u.LastClickedAt == nil {
+			return nil
+		}
+		return *u.LastClickedAt
+	case constant.SortByRelevance:
+		return u.Score
+	case constant.SortByExpiry:
+		if u.ActiveUntil == nil {
+			return nil
+		}
+		return *u.ActiveUntil
+	default:
+		return u.CreatedAt
+	}
 }
@@ -0,0 +1,23 @@
+package model
+
+import (
+	"codedln/shared/analytics"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// UrlStats is the click activity of a link. Unique visitor counts are estimates with an error of about one percent.
+// Clicks and visitors leave out bots unless they were asked for. BotClicks is always the all time number of bot clicks.
+// Referrals are all time clicks by where they came from, see Visit.Source.
+type UrlStats struct {
+	UrlId          primitive.ObjectID `json:"urlId"`
+	Clicks         int64              `json:"clicks"`
+	BotClicks      int64              `json:"botClicks"`
+	IncludesBots   bool               `json:"includesBots"`
+	UniqueVisitors int64              `json:"uniqueVisitors"`
+	LastClickedAt  *time.Time         `json:"lastClickedAt,omitempty"`
+	From           string             `json:"from"`
+	To             string             `json:"to"`
+	Range          *analytics.Range   `json:"range"`
+	Referrals      map[string]int64   `json:"referrals"`
+}
@@ -0,0 +1,102 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// UTM is the campaign tracking added to a link's destination as utm_ query parameters.
+type UTM struct {
+	Source   string `bson:"source,omitempty" json:"source,omitempty"`
+	Medium   string `bson:"medium,omitempty" json:"medium,omitempty"`
+	Campaign string `bson:"campaign,omitempty" json:"campaign,omitempty"`
+	Term     string `bson:"term,omitempty" json:"term,omitempty"`
+	Content  string `bson:"content,omitempty" json:"content,omitempty"`
+}
+
+// UtmTemplate holds the UTM values a user's new links start from.
+type UtmTemplate struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"-"`
+	UserId    primitive.ObjectID `bson:"userId" json:"-"`
+	UTM       UTM                `bson:"utm" json:"utm"`
+	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
+}
+
+func NewUTMSchema() UTM {
+	return UTM{}
+}
+
+func (u UTM) Validate() error {
+	for _, param := range u.params() {
+		if len(param.value) > constant.UtmMaxLength {
+			return http_error.New(http.StatusBadRequest, param.key+" must be at most 100 characters")
+		}
+	}
+	return nil
+}
+
+func (u UTM) IsZero() bool {
+	return u == UTM{}
+}
+
+// Trimmed returns the values without surrounding space, so " spring " and "spring" group as one campaign.
+func (u UTM) Trimmed() UTM {
+	return UTM{
+		Source:   strings.TrimSpace(u.Source),
+		Medium:   strings.TrimSpace(u.Medium),
+		Campaign: strings.TrimSpace(u.Campaign),
+		Term:     strings.TrimSpace(u.Term),
+		Content:  strings.TrimSpace(u.Content),
+	}
+}
+
+// WithDefaults fills the values left empty from the defaults.
+func (u UTM) WithDefaults(defaults UTM) UTM {
+	fill := func(value string, fallback string) string {
+		if value == "" {
+			return fallback
+		}
+		return value
+	}
+	return UTM{
+		Source:   fill(u.Source, defaults.Source),
+		Medium:   fill(u.Medium, defaults.Medium),
+		Campaign: fill(u.Campaign, defaults.Campaign),
+		Term:     fill(u.Term, defaults.Term),
+		Content:  fill(u.Content, defaults.Content),
+	}
+}
+
+// Apply sets the utm_ parameters on the destination. The values given replace utm_ parameters already in the url,
+// every other parameter and the fragment are left as they were, and all values are query escaped.
+func (u UTM) Apply(destination string) (string, error) {
+	parsed, err := url.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+
+	query := parsed.Query()
+	for _, param := range u.params() {
+		if param.value != "" {
+			query.Set(param.key, param.value)
+		}
+	}
+	parsed.RawQuery = query.Encode()
+
+	return parsed.String(), nil
+}
+
+func (u UTM) params() []struct{ key, value string } {
+	return []struct{ key, value string }{
+		{"utm_source", u.Source},
+		{"utm_medium", u.Medium},
+		{"utm_campaign", u.Campaign},
+		{"utm_term", u.Term},
+		{"utm_content", u.Content},
+	}
+}
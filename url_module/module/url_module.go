@@ -1,28 +1,129 @@
 package module
 
 import (
+	"codedln/shared/analytics"
+	"codedln/shared/config"
+	"codedln/shared/healthcheck"
 	"codedln/shared/middleware"
+	"codedln/shared/mongodb"
+	"codedln/shared/opengraph"
+	"codedln/shared/pubsub"
+	"codedln/shared/screening"
+	"codedln/shared/view"
 	"codedln/url_module/controller"
 	"codedln/url_module/model"
 	"codedln/url_module/repository"
 	"codedln/url_module/service"
 	"codedln/util/constant"
+	"context"
 	"github.com/go-redis/redis_rate/v10"
 	"github.com/gorilla/mux"
+	"github.com/redis/go-redis/v9"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"log"
+	"log/slog"
 	"net/http"
 	"time"
 )
 
-func UrlModule(router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Database) {
+// UrlModule mounts the url routes and starts the background jobs that keep links up to date.
+// The jobs run until ctx is cancelled.
+func UrlModule(ctx context.Context, router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Database, rdb *redis.Client, webhooks service.Notifier, cfg *config.Config) {
 	collection := db.Collection(constant.UrlCollection)
 	if collection == nil {
 		log.Fatalf("%s does not exist:", constant.UrlCollection)
 	}
 
-	urlRepo := repository.New(collection)
-	urlService := service.New(urlRepo)
+	blocklist, err := screening.LoadBlocklist(cfg.Links.BlocklistPath)
+	if err != nil {
+		log.Fatalf("Error loading blocklist: %v", err)
+	}
+
+	var lookup screening.ThreatLookup
+	if cfg.Links.SafeBrowsingApiKey != "" {
+		lookup = screening.NewSafeBrowsingClient(cfg.Links.SafeBrowsingUrl, cfg.Links.SafeBrowsingApiKey, nil)
+	}
+
+	userCollection := db.Collection(constant.UserCollection)
+	if userCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UserCollection)
+	}
+
+	reservedAliasCollection := db.Collection(constant.ReservedAliasCollection)
+	if reservedAliasCollection == nil {
+		log.Fatalf("%s does not exist:", constant.ReservedAliasCollection)
+	}
+
+	utmTemplateCollection := db.Collection(constant.UtmTemplateCollection)
+	if utmTemplateCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UtmTemplateCollection)
+	}
+
+	if err = view.Override(cfg.Links.TemplatesDir); err != nil {
+		log.Fatalf("Error loading page templates: %v", err)
+	}
+
+	bots, err := analytics.NewBotClassifier(cfg.Links.BotSignaturesPath)
+	if err != nil {
+		log.Fatalf("Error loading bot signatures: %v", err)
+	}
+
+	urlRepo := repository.New(collection, reservedAliasCollection, utmTemplateCollection)
+	if indexErr := urlRepo.CreateIndexes(context.Background()); indexErr != nil {
+		log.Fatalf("Error creating %s indexes", constant.UrlCollection)
+	}
+	// Links created before clicks were counted have no clicks field, which would break paging by clicks
+	if backfillErr := mongodb.RunOnce(context.Background(), db, "backfill_url_clicks", func(ctx context.Context) error {
+		return urlRepo.UpdateUrls(ctx, bson.D{{"clicks", bson.D{{"$exists", false}}}}, bson.D{{"$set", bson.D{{"clicks", 0}}}})
+	}); backfillErr != nil {
+		log.Fatalf("Error backfilling %s clicks", constant.UrlCollection)
+	}
+	urlService := service.New(urlRepo, screening.New(blocklist, lookup), pubsub.New(rdb), analytics.New(rdb, bots, cfg.Links.VisitorHashSalt), healthcheck.New(nil, constant.HealthCheckTimeout*time.Second, constant.HealthCheckHostInterval*time.Millisecond), opengraph.New(nil, constant.OpenGraphFetchTimeout*time.Second), webhooks)
+
+	go blocklist.Watch(ctx, constant.BlocklistReloadInterval*time.Second, func() {
+		if rescanErr := urlService.RescanUrls(ctx); rescanErr != nil {
+			slog.Error("unable to rescan urls", "error", rescanErr)
+		}
+	})
+
+	go func() {
+		ticker := time.NewTicker(constant.TrashPurgeInterval * time.Hour)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			purged, purgeErr := urlService.PurgeTrash(ctx, cfg.Links.TrashRetentionDays, cfg.Links.AliasCooldownDays)
+			if purgeErr != nil {
+				slog.Error("unable to purge trashed urls", "error", purgeErr)
+				continue
+			}
+			if purged > 0 {
+				slog.Info("purged trashed urls", "count", purged)
+			}
+		}
+	}()
+
+	// Each pass only checks links that are due, so passes can run more often than a link is checked
+	go func() {
+		ticker := time.NewTicker(constant.HealthCheckScanInterval * time.Hour)
+		defer ticker.Stop()
+		for {
+			if healthErr := urlService.CheckUrlHealth(ctx); healthErr != nil {
+				slog.Error("unable to check url health", "error", healthErr)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
 	urlController := controller.New(urlService)
 
 	urlRouter := router.PathPrefix("/url").Subrouter()
@@ -30,97 +131,206 @@ func UrlModule(router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Databa
 	urlRouter.HandleFunc("/redirect",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.Redirect,
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   1000,
-				Burst:  500,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Public),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodGet)
 
 	urlRouter.HandleFunc("/check_alias",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.CheckAliasExistence,
 			middleware.PayloadValidationMiddleware(model.NewCheckAliasSchema),
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   10,
-				Burst:  5,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodPost)
 
 	urlRouter.HandleFunc("/guest",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.CreateUrl,
 			middleware.PayloadValidationMiddleware(model.NewCreateUrlSchema),
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   10,
-				Burst:  5,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodPost)
 
 	urlRouter.HandleFunc("/get_url/{urlId}",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.GetUrl,
-			middleware.AuthenticationMiddleware,
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   100,
-				Burst:  50,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodGet)
 
 	urlRouter.HandleFunc("/delete_url/{urlId}",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.DeleteUrl,
-			middleware.AuthenticationMiddleware,
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   100,
-				Burst:  50,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodDelete)
 
+	urlRouter.HandleFunc("/update_schedule/{urlId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.UpdateSchedule,
+			middleware.PayloadValidationMiddleware(model.NewScheduleSchema),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	urlRouter.HandleFunc("/update_social_preview/{urlId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.UpdateSocialPreview,
+			middleware.PayloadValidationMiddleware(model.NewOpenGraphSchema),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
 	urlRouter.HandleFunc("/create_url",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.CreateUrl,
-			middleware.AuthenticationMiddleware,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
 			middleware.PayloadValidationMiddleware(model.NewCreateUrlSchema),
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   10,
-				Burst:  5,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodPost)
 
 	urlRouter.HandleFunc("/get_urls",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.GetUrls,
-			middleware.AuthenticationMiddleware,
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   1000,
-				Burst:  500,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Public),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodGet)
 
 	urlRouter.HandleFunc("/delete_urls",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			urlController.DeleteUrls,
-			middleware.AuthenticationMiddleware,
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   10,
-				Burst:  50,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Bulk),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodDelete)
+
+	urlRouter.HandleFunc("/trash",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetTrashedUrls,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	urlRouter.HandleFunc("/restore_url/{urlId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.RestoreUrl,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	urlRouter.HandleFunc("/restore_urls",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.RestoreUrls,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Bulk),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPatch)
+
+	urlRouter.HandleFunc("/get_stats/{urlId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetStats,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	urlRouter.HandleFunc("/campaigns",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetCampaigns,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	urlRouter.HandleFunc("/campaign_stats/{campaign}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetCampaignStats,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	urlRouter.HandleFunc("/utm_template",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetUtmTemplate,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	urlRouter.HandleFunc("/utm_template",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.SaveUtmTemplate,
+			middleware.PayloadValidationMiddleware(model.NewUTMSchema),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPut)
+
+	urlRouter.HandleFunc("/get_guest_url/{alias}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.GetGuestUrl,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	urlRouter.HandleFunc("/delete_guest_url/{alias}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.DeleteGuestUrl,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodDelete)
 
+	urlRouter.HandleFunc("/claim_urls",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.ClaimGuestUrls,
+			middleware.PayloadValidationMiddleware(model.NewClaimUrlsSchema),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPost)
+
+	// Browsers cannot set headers on an EventSource, so the live streams rely on the session cookie alone
+	urlRouter.HandleFunc("/live",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.LiveUserClicks,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+		))).Methods(http.MethodGet)
+
+	urlRouter.HandleFunc("/{urlId}/live",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.LiveUrlClicks,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+		))).Methods(http.MethodGet)
+
+	// Previews must be registered before the catch-all below, which would otherwise take the "+" as part of the alias
+	router.HandleFunc("/{alias}+",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.Preview,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Public),
+		))).Methods(http.MethodGet, http.MethodHead)
+
+	// Public short links, HEAD included for link checkers. This is a catch-all for single segment paths so it must be registered after every other top level route.
+	router.HandleFunc("/{alias}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.Visit,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Public),
+		))).Methods(http.MethodGet, http.MethodHead)
+
+	// Everything after the alias is passed on to links that forward paths
+	router.HandleFunc("/{alias}/{rest:.*}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			urlController.Visit,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Public),
+		))).Methods(http.MethodGet, http.MethodHead)
 }
@@ -2,7 +2,9 @@ package repository
 
 import (
 	"codedln/shared/http_error"
+	"codedln/shared/logging"
 	"codedln/url_module/model"
+	"codedln/util/constant"
 	"codedln/util/types"
 	"context"
 	"errors"
@@ -10,34 +12,152 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
-	"log"
 	"net/http"
+	"regexp"
+	"strings"
 	"time"
 )
 
 type UrlRepository interface {
+	CreateIndexes(ctx context.Context) error
 	CreateUrl(ctx context.Context, url model.Url) (*model.Url, error)
 	GetUrl(ctx context.Context, query bson.D) (*model.Url, error)
-	GetUrls(ctx context.Context, query string, sort types.DateSort, limit int64, skip int64, userId primitive.ObjectID) (*types.PaginationResult[model.Url], error)
+	GetUrls(ctx context.Context, query model.UrlListQuery, userId primitive.ObjectID) (*types.CursorResult[model.Url], error)
+	GetTrashedUrls(ctx context.Context, limit int64, skip int64, userId primitive.ObjectID) (*types.PaginationResult[model.Url], error)
 	DeleteUrl(ctx context.Context, urlId primitive.ObjectID, userId primitive.ObjectID) error
 	DeleteUrls(ctx context.Context, urlIds []primitive.ObjectID, userId primitive.ObjectID) error
+	RestoreUrls(ctx context.Context, urlIds []primitive.ObjectID, userId primitive.ObjectID) (int64, error)
+	PurgeUrls(ctx context.Context, deletedBefore time.Time, reservedUntil time.Time) (int64, error)
+	AliasReserved(ctx context.Context, alias string) (bool, error)
+	UpdateUrls(ctx context.Context, filter bson.D, update bson.D) error
+	RecordClick(ctx context.Context, urlId primitive.ObjectID, bot bool) (int64, error)
+	ScanUrls(ctx context.Context, filter bson.D, fn func(url model.Url) error) error
+	DeleteGuestUrl(ctx context.Context, alias string, tokenHash string) (bool, error)
+	ClaimGuestUrls(ctx context.Context, tokenHashes []string, userId primitive.ObjectID) (int64, error)
+	GetCampaigns(ctx context.Context, userId primitive.ObjectID, campaign string) ([]model.Campaign, error)
+	GetUtmTemplate(ctx context.Context, userId primitive.ObjectID) (*model.UtmTemplate, error)
+	SaveUtmTemplate(ctx context.Context, template model.UtmTemplate) (*model.UtmTemplate, error)
 }
 
 type MongoUrlRepository struct {
-	collection *mongo.Collection
+	collection              *mongo.Collection
+	reservedAliasCollection *mongo.Collection
+	utmTemplateCollection   *mongo.Collection
 }
 
-func New(collection *mongo.Collection) UrlRepository {
+func New(collection *mongo.Collection, reservedAliasCollection *mongo.Collection, utmTemplateCollection *mongo.Collection) UrlRepository {
 	return &MongoUrlRepository{
-		collection: collection,
+		collection:              collection,
+		reservedAliasCollection: reservedAliasCollection,
+		utmTemplateCollection:   utmTemplateCollection,
 	}
 }
 
+// notDeleted matches links that are not in the trash.
+var notDeleted = bson.D{{"deletedAt", bson.D{{"$exists", false}}}}
+
+func (r *MongoUrlRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"userId", 1}, {"destinationHash", 1}},
+			Options: options.Index().SetName("user_destination_hash"),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"createdAt", -1}, {"_id", -1}},
+			Options: options.Index().SetName("user_created_at"),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"updatedAt", -1}, {"_id", -1}},
+			Options: options.Index().SetName("user_updated_at"),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"alias", 1}, {"_id", 1}},
+			Options: options.Index().SetName("user_alias"),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"clicks", -1}, {"_id", -1}},
+			Options: options.Index().SetName("user_clicks"),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"lastClickedAt", -1}, {"_id", -1}},
+			Options: options.Index().SetName("user_last_clicked_at"),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"activeUntil", -1}, {"_id", -1}},
+			Options: options.Index().SetName("user_active_until"),
+		},
+		{
+			Keys: bson.D{
+				{"alias", "text"},
+				{"originalUrl", "text"},
+				{"title", "text"},
+				{"notes", "text"},
+				{"tags", "text"},
+			},
+			// Aliases and urls are not prose, so stemming and stop words are turned off
+			Options: options.Index().
+				SetName("url_text").
+				SetDefaultLanguage("none").
+				SetWeights(bson.D{{"alias", 10}, {"title", 5}, {"tags", 5}, {"originalUrl", 2}, {"notes", 1}}),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"tags", 1}},
+			Options: options.Index().SetName("user_tags"),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"utm.campaign", 1}},
+			Options: options.Index().SetName("user_campaign"),
+		},
+		{
+			Keys:    bson.D{{"userId", 1}, {"health.broken", 1}},
+			Options: options.Index().SetName("user_broken"),
+		},
+		{
+			Keys:    bson.D{{"health.checkedAt", 1}},
+			Options: options.Index().SetName("health_checked_at"),
+		},
+		{
+			Keys:    bson.D{{"deletedAt", 1}},
+			Options: options.Index().SetName("deleted_at").SetSparse(true),
+		},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to create url indexes")
+	}
+
+	// Reservations are removed by mongo once they expire
+	_, err = r.reservedAliasCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"alias", 1}},
+			Options: options.Index().SetName("alias").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{"expiresAt", 1}},
+			Options: options.Index().SetName("expires_at").SetExpireAfterSeconds(0),
+		},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to create reserved alias indexes")
+	}
+
+	_, err = r.utmTemplateCollection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{"userId", 1}},
+		Options: options.Index().SetName("user").SetUnique(true),
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to create utm template indexes")
+	}
+	return nil
+}
+
 func (r *MongoUrlRepository) CreateUrl(ctx context.Context, url model.Url) (*model.Url, error) {
 	res, err := r.collection.InsertOne(ctx, url)
 
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(ctx).Error("database error", "error", err)
 		return nil, http_error.New(http.StatusInternalServerError, "unable to create url")
 	}
 
@@ -45,7 +165,7 @@ func (r *MongoUrlRepository) CreateUrl(ctx context.Context, url model.Url) (*mod
 
 	//Fetch the newly inserted url
 	if findErr := r.collection.FindOne(ctx, bson.D{{"_id", res.InsertedID}}).Decode(&newUrl); findErr != nil {
-		log.Println(findErr)
+		logging.FromContext(ctx).Error("database error", "error", findErr)
 		return nil, http_error.New(http.StatusInternalServerError, "unable to find url")
 	}
 
@@ -61,58 +181,110 @@ func (r *MongoUrlRepository) GetUrl(ctx context.Context, filter bson.D) (*model.
 
 	var url model.Url
 	if decodeErr := res.Decode(&url); decodeErr != nil {
-		log.Println(decodeErr)
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
 		return nil, http_error.New(http.StatusInternalServerError, "unable to get url")
 	}
 
 	return &url, nil
 }
 
-func (r *MongoUrlRepository) GetUrls(ctx context.Context, query string, sort types.DateSort, limit int64, skip int64, userId primitive.ObjectID) (*types.PaginationResult[model.Url], error) {
+func (r *MongoUrlRepository) GetUrls(ctx context.Context, query model.UrlListQuery, userId primitive.ObjectID) (*types.CursorResult[model.Url], error) {
+	result, err := r.getUrls(ctx, query, userId)
+	if err != nil {
+		return nil, err
+	}
 
-	searchFilter := bson.D{
-		{"$and", bson.A{
-			bson.D{{"userId", userId}},
-			bson.D{{"$or", bson.A{
-				bson.D{{"alias", bson.D{{"$regex", query}, {"$options", "i"}}}},
-				bson.D{{"originalUrl", bson.D{{"$regex", query}, {"$options", "i"}}}},
-			}}},
-		}},
+	// The text index only matches whole words, so a search for part of an alias or url finds nothing.
+	// Fall back to a literal substring match before giving up.
+	if len(result.Data) == 0 && query.Cursor == nil && query.Search.HasText() && !query.Literal {
+		query.Literal = true
+		if query.SortBy == constant.SortByRelevance {
+			query.SortBy = constant.SortByCreatedAt
+		}
+		return r.getUrls(ctx, query, userId)
 	}
 
-	// First, calculate the total count of documents matching the searchFilter
-	totalCount, err := r.collection.CountDocuments(ctx, searchFilter)
-	if err != nil {
-		log.Println(err)
-		return nil, http_error.New(http.StatusInternalServerError, "unable to count urls")
+	return result, nil
+}
+
+func (r *MongoUrlRepository) getUrls(ctx context.Context, query model.UrlListQuery, userId primitive.ObjectID) (*types.CursorResult[model.Url], error) {
+
+	conditions := bson.A{
+		bson.D{{"userId", userId}},
+		notDeleted,
+	}
+	conditions = append(conditions, searchConditions(query.Search, query.Literal)...)
+	if query.Campaign != "" {
+		conditions = append(conditions, bson.D{{"utm.campaign", query.Campaign}})
 	}
+	if query.Broken {
+		conditions = append(conditions, bson.D{{"health.broken", true}})
+	}
+	if dateRange := between(query.CreatedFrom, query.CreatedTo); dateRange != nil {
+		conditions = append(conditions, bson.D{{"createdAt", dateRange}})
+	}
+	if dateRange := between(query.UpdatedFrom, query.UpdatedTo); dateRange != nil {
+		conditions = append(conditions, bson.D{{"updatedAt", dateRange}})
+	}
+	searchFilter := bson.D{{"$and", conditions}}
 
-	// Then, fetch the documents with sorting and limiting
-	pipeline := bson.A{
-		bson.D{{Key: "$match", Value: searchFilter}},
-		bson.D{{Key: "$sort", Value: bson.D{{"createdAt", sort}}}},
-		bson.D{{Key: "$skip", Value: skip}},
-		bson.D{{Key: "$limit", Value: limit}},
+	result := &types.CursorResult[model.Url]{}
+
+	// Counting walks every matching document, so it is only done when asked for
+	if query.IncludeTotal {
+		totalCount, err := r.collection.CountDocuments(ctx, searchFilter)
+		if err != nil {
+			logging.FromContext(ctx).Error("database error", "error", err)
+			return nil, http_error.New(http.StatusInternalServerError, "unable to count urls")
+		}
+		result.Total = &totalCount
 	}
 
+	field := model.SortField(query.SortBy)
+	pipeline := bson.A{bson.D{{"$match", searchFilter}}}
+	if query.Search.HasText() && !query.Literal {
+		pipeline = append(pipeline, bson.D{{"$addFields", bson.D{{"score", bson.D{{"$meta", "textScore"}}}}}})
+	}
+	if query.Cursor != nil {
+		pipeline = append(pipeline, bson.D{{"$match", after(field, query.Order, query.Cursor)}})
+	}
+	// One extra document is fetched to tell whether there is a next page
+	pipeline = append(pipeline,
+		bson.D{{"$sort", bson.D{{field, query.Order}, {"_id", query.Order}}}},
+		bson.D{{"$limit", query.Limit + 1}},
+	)
+
 	opts := options.Aggregate().SetMaxTime(2 * time.Second)
 	cursor, err := r.collection.Aggregate(ctx, pipeline, opts)
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(ctx).Error("database error", "error", err)
 		return nil, http_error.New(http.StatusInternalServerError, "unable to fetch urls")
 	}
 
 	var results []model.Url
 	if err = cursor.All(ctx, &results); err != nil {
-		log.Println(err)
+		logging.FromContext(ctx).Error("database error", "error", err)
 		return nil, http_error.New(http.StatusInternalServerError, "unable to process fetch urls")
 	}
 
-	// Return the results along with the total count
-	return &types.PaginationResult[model.Url]{
-		Data:  results,
-		Total: totalCount,
-	}, nil
+	if int64(len(results)) > query.Limit {
+		results = results[:query.Limit]
+		last := results[len(results)-1]
+		result.NextCursor, err = model.EncodeCursor(model.UrlCursor{
+			SortBy:  query.SortBy,
+			Order:   query.Order,
+			Value:   last.SortValue(query.SortBy),
+			ID:      last.ID,
+			Literal: query.Literal,
+		})
+		if err != nil {
+			logging.FromContext(ctx).Error("database error", "error", err)
+			return nil, http_error.New(http.StatusInternalServerError, "unable to create cursor")
+		}
+	}
+
+	result.Data = results
+	return result, nil
 }
 
 func (r *MongoUrlRepository) DeleteUrl(ctx context.Context, urlId primitive.ObjectID, userId primitive.ObjectID) error {
@@ -120,11 +292,12 @@ func (r *MongoUrlRepository) DeleteUrl(ctx context.Context, urlId primitive.Obje
 		{"$and", bson.A{
 			bson.D{{"userId", userId}},
 			bson.D{{"_id", urlId}},
+			notDeleted,
 		}},
 	}
-	_, err := r.collection.DeleteOne(ctx, filter)
+	_, err := r.collection.UpdateOne(ctx, filter, trash())
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(ctx).Error("database error", "error", err)
 		return http_error.New(http.StatusInternalServerError, "unable to delete url")
 	}
 	return nil
@@ -135,12 +308,391 @@ func (r *MongoUrlRepository) DeleteUrls(ctx context.Context, urlIds []primitive.
 		{"$and", bson.A{
 			bson.D{{"userId", userId}},
 			bson.M{"_id": bson.M{"$in": urlIds}},
+			notDeleted,
 		}},
 	}
-	_, err := r.collection.DeleteMany(ctx, filter)
+	_, err := r.collection.UpdateMany(ctx, filter, trash())
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(ctx).Error("database error", "error", err)
 		return http_error.New(http.StatusInternalServerError, "unable to delete urls")
 	}
 	return nil
 }
+
+func (r *MongoUrlRepository) GetTrashedUrls(ctx context.Context, limit int64, skip int64, userId primitive.ObjectID) (*types.PaginationResult[model.Url], error) {
+	filter := bson.D{
+		{"userId", userId},
+		{"deletedAt", bson.D{{"$exists", true}}},
+	}
+
+	totalCount, err := r.collection.CountDocuments(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to count trashed urls")
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"deletedAt", -1}}).
+		SetSkip(skip).
+		SetLimit(limit).
+		SetMaxTime(2 * time.Second)
+	cursor, err := r.collection.Find(ctx, filter, opts)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to fetch trashed urls")
+	}
+
+	var results []model.Url
+	if err = cursor.All(ctx, &results); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to process trashed urls")
+	}
+
+	return &types.PaginationResult[model.Url]{
+		Data:  results,
+		Total: totalCount,
+	}, nil
+}
+
+func (r *MongoUrlRepository) RestoreUrls(ctx context.Context, urlIds []primitive.ObjectID, userId primitive.ObjectID) (int64, error) {
+	filter := bson.D{
+		{"userId", userId},
+		{"_id", bson.D{{"$in", urlIds}}},
+		{"deletedAt", bson.D{{"$exists", true}}},
+	}
+	update := bson.D{
+		{"$set", bson.D{{"updatedAt", time.Now().UTC()}}},
+		{"$unset", bson.D{{"deletedAt", ""}}},
+	}
+	res, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to restore urls")
+	}
+	return res.ModifiedCount, nil
+}
+
+// PurgeUrls permanently removes links trashed before deletedBefore and reserves their aliases until reservedUntil.
+func (r *MongoUrlRepository) PurgeUrls(ctx context.Context, deletedBefore time.Time, reservedUntil time.Time) (int64, error) {
+	filter := bson.D{{"deletedAt", bson.D{{"$lte", deletedBefore}}}}
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetProjection(bson.D{{"alias", 1}}))
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to find trashed urls")
+	}
+
+	var urls []model.Url
+	if err = cursor.All(ctx, &urls); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to process trashed urls")
+	}
+
+	if len(urls) == 0 {
+		return 0, nil
+	}
+
+	// Reserve the aliases before deleting so there is no window where they can be taken
+	ids := make([]primitive.ObjectID, len(urls))
+	reservations := make([]mongo.WriteModel, len(urls))
+	for i, url := range urls {
+		ids[i] = url.ID
+		reservations[i] = mongo.NewUpdateOneModel().
+			SetFilter(bson.D{{"alias", url.Alias}}).
+			SetUpdate(bson.D{
+				{"$set", bson.D{{"expiresAt", reservedUntil}}},
+				{"$setOnInsert", bson.D{{"createdAt", time.Now().UTC()}}},
+			}).
+			SetUpsert(true)
+	}
+
+	if _, err = r.reservedAliasCollection.BulkWrite(ctx, reservations, options.BulkWrite().SetOrdered(false)); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to reserve purged aliases")
+	}
+
+	res, err := r.collection.DeleteMany(ctx, bson.D{{"_id", bson.D{{"$in", ids}}}})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to purge urls")
+	}
+	return res.DeletedCount, nil
+}
+
+// AliasReserved reports whether the alias belonged to a purged link that is still cooling down.
+// Expiry is checked here as well because mongo only removes expired documents periodically.
+func (r *MongoUrlRepository) AliasReserved(ctx context.Context, alias string) (bool, error) {
+	filter := bson.D{
+		{"alias", alias},
+		{"expiresAt", bson.D{{"$gt", time.Now().UTC()}}},
+	}
+	count, err := r.reservedAliasCollection.CountDocuments(ctx, filter, options.Count().SetLimit(1))
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return true, http_error.New(http.StatusInternalServerError, "unable to check reserved alias")
+	}
+	return count > 0, nil
+}
+
+func (r *MongoUrlRepository) DeleteGuestUrl(ctx context.Context, alias string, tokenHash string) (bool, error) {
+	filter := bson.D{
+		{"alias", alias},
+		{"managementTokenHash", tokenHash},
+		{"userId", bson.D{{"$exists", false}}},
+		{"deletedAt", bson.D{{"$exists", false}}},
+	}
+	res, err := r.collection.UpdateOne(ctx, filter, trash())
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return false, http_error.New(http.StatusInternalServerError, "unable to delete url")
+	}
+	return res.ModifiedCount > 0, nil
+}
+
+// ClaimGuestUrls moves the guest links matching the token hashes to the user. The tokens stop working once claimed.
+func (r *MongoUrlRepository) ClaimGuestUrls(ctx context.Context, tokenHashes []string, userId primitive.ObjectID) (int64, error) {
+	filter := bson.D{
+		{"managementTokenHash", bson.D{{"$in", tokenHashes}}},
+		{"userId", bson.D{{"$exists", false}}},
+		{"deletedAt", bson.D{{"$exists", false}}},
+	}
+	update := bson.D{
+		{"$set", bson.D{{"userId", userId}, {"updatedAt", time.Now().UTC()}}},
+		{"$unset", bson.D{{"managementTokenHash", ""}}},
+	}
+	res, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to claim urls")
+	}
+	return res.ModifiedCount, nil
+}
+
+func (r *MongoUrlRepository) UpdateUrls(ctx context.Context, filter bson.D, update bson.D) error {
+	_, err := r.collection.UpdateMany(ctx, filter, update)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to update urls")
+	}
+	return nil
+}
+
+// RecordClick counts a click on the link and returns the link's new count. Bot clicks are counted separately,
+// so for them the bot count is returned, and they do not move lastClickedAt.
+func (r *MongoUrlRepository) RecordClick(ctx context.Context, urlId primitive.ObjectID, bot bool) (int64, error) {
+	update := bson.D{
+		{"$inc", bson.D{{"clicks", 1}}},
+		{"$set", bson.D{{"lastClickedAt", time.Now().UTC()}}},
+	}
+	if bot {
+		update = bson.D{{"$inc", bson.D{{"botClicks", 1}}}}
+	}
+	opts := options.FindOneAndUpdate().
+		SetProjection(bson.D{{"clicks", 1}, {"botClicks", 1}}).
+		SetReturnDocument(options.After)
+
+	var url model.Url
+	if err := r.collection.FindOneAndUpdate(ctx, bson.D{{"_id", urlId}}, update, opts).Decode(&url); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to record click")
+	}
+	if bot {
+		return url.BotClicks, nil
+	}
+	return url.Clicks, nil
+}
+
+// ScanUrls calls fn for every url matching filter without loading them all into memory.
+func (r *MongoUrlRepository) ScanUrls(ctx context.Context, filter bson.D, fn func(url model.Url) error) error {
+	cursor, err := r.collection.Find(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to scan urls")
+	}
+	defer func() {
+		_ = cursor.Close(ctx)
+	}()
+
+	for cursor.Next(ctx) {
+		var url model.Url
+		if decodeErr := cursor.Decode(&url); decodeErr != nil {
+			logging.FromContext(ctx).Error("database error", "error", decodeErr)
+			continue
+		}
+
+		if fnErr := fn(url); fnErr != nil {
+			return fnErr
+		}
+	}
+
+	return cursor.Err()
+}
+
+// GetCampaigns totals the user's links by utm campaign, or only the one campaign when it is given.
+func (r *MongoUrlRepository) GetCampaigns(ctx context.Context, userId primitive.ObjectID, campaign string) ([]model.Campaign, error) {
+	var campaignFilter any = bson.D{{"$exists", true}}
+	if campaign != "" {
+		campaignFilter = campaign
+	}
+	match := bson.D{
+		{"userId", userId},
+		{"deletedAt", bson.D{{"$exists", false}}},
+		{"utm.campaign", campaignFilter},
+	}
+
+	pipeline := bson.A{
+		bson.D{{"$match", match}},
+		bson.D{{"$group", bson.D{
+			{"_id", "$utm.campaign"},
+			{"links", bson.D{{"$sum", 1}}},
+			{"clicks", bson.D{{"$sum", "$clicks"}}},
+			{"botClicks", bson.D{{"$sum", "$botClicks"}}},
+			{"lastClickedAt", bson.D{{"$max", "$lastClickedAt"}}},
+			{"urlIds", bson.D{{"$push", "$_id"}}},
+		}}},
+		bson.D{{"$sort", bson.D{{"_id", 1}}}},
+	}
+
+	opts := options.Aggregate().SetMaxTime(2 * time.Second)
+	cursor, err := r.collection.Aggregate(ctx, pipeline, opts)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to fetch campaigns")
+	}
+
+	campaigns := []model.Campaign{}
+	if err = cursor.All(ctx, &campaigns); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to process campaigns")
+	}
+
+	return campaigns, nil
+}
+
+func (r *MongoUrlRepository) GetUtmTemplate(ctx context.Context, userId primitive.ObjectID) (*model.UtmTemplate, error) {
+	res := r.utmTemplateCollection.FindOne(ctx, bson.D{{"userId", userId}})
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	var template model.UtmTemplate
+	if decodeErr := res.Decode(&template); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get utm template")
+	}
+
+	return &template, nil
+}
+
+// SaveUtmTemplate replaces the user's template, creating it the first time.
+func (r *MongoUrlRepository) SaveUtmTemplate(ctx context.Context, template model.UtmTemplate) (*model.UtmTemplate, error) {
+	update := bson.D{{"$set", bson.D{{"utm", template.UTM}, {"updatedAt", template.UpdatedAt}}}}
+	opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+
+	var saved model.UtmTemplate
+	if err := r.utmTemplateCollection.FindOneAndUpdate(ctx, bson.D{{"userId", template.UserId}}, update, opts).Decode(&saved); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to save utm template")
+	}
+
+	return &saved, nil
+}
+
+// trash moves links to the trash. They stop redirecting but keep their alias until purged.
+func trash() bson.D {
+	now := time.Now().UTC()
+	return bson.D{{"$set", bson.D{{"deletedAt", now}, {"updatedAt", now}}}}
+}
+
+// searchConditions turns a parsed search into match conditions. Every piece of user input is either passed to
+// the text index or quoted before it is used in a regular expression.
+func searchConditions(search model.SearchQuery, literal bool) bson.A {
+	var conditions bson.A
+
+	// The text index handles exclusions itself, otherwise excluded words are matched literally
+	textIndex := search.HasText() && !literal
+	if textIndex {
+		conditions = append(conditions, bson.D{{"$text", bson.D{{"$search", search.TextSearch()}}}})
+	} else {
+		if search.HasText() {
+			conditions = append(conditions, bson.D{{"$or", containing(search.Literal())}})
+		}
+		for _, word := range search.ExcludeWords {
+			conditions = append(conditions, bson.D{{"$nor", containing(strings.Trim(word, `"`))}})
+		}
+	}
+
+	if len(search.Tags) > 0 {
+		conditions = append(conditions, bson.D{{"tags", bson.D{{"$all", search.Tags}}}})
+	}
+	if len(search.ExcludeTags) > 0 {
+		conditions = append(conditions, bson.D{{"tags", bson.D{{"$nin", search.ExcludeTags}}}})
+	}
+
+	if len(search.Domains) > 0 {
+		var domains bson.A
+		for _, domain := range search.Domains {
+			domains = append(domains, bson.D{{"originalUrl", primitive.Regex{Pattern: domainPattern(domain)}}})
+		}
+		conditions = append(conditions, bson.D{{"$or", domains}})
+	}
+	for _, domain := range search.ExcludeDomains {
+		conditions = append(conditions, bson.D{{"originalUrl", bson.D{{"$not", primitive.Regex{Pattern: domainPattern(domain)}}}}})
+	}
+
+	return conditions
+}
+
+// containing matches links with text in any of their searchable fields, ignoring case.
+func containing(text string) bson.A {
+	pattern := regexp.QuoteMeta(text)
+	var fields bson.A
+	for _, field := range []string{"alias", "originalUrl", "title", "notes"} {
+		fields = append(fields, bson.D{{field, bson.D{{"$regex", pattern}, {"$options", "i"}}}})
+	}
+	return fields
+}
+
+// domainPattern matches canonical urls whose host is the domain or one of its subdomains.
+// The pattern is anchored so mongo can stop at the scheme instead of scanning the whole url.
+func domainPattern(domain string) string {
+	return `^https?://([^/?#]*\.)?` + regexp.QuoteMeta(domain) + `(:[0-9]+)?([/?#]|$)`
+}
+
+// between builds a date range condition, or returns nil when neither bound is set.
+func between(from *time.Time, to *time.Time) bson.D {
+	var dateRange bson.D
+	if from != nil {
+		dateRange = append(dateRange, bson.E{Key: "$gte", Value: *from})
+	}
+	if to != nil {
+		dateRange = append(dateRange, bson.E{Key: "$lte", Value: *to})
+	}
+	return dateRange
+}
+
+// after matches the links that come after the cursor in (field, _id) order.
+// Mongo sorts missing and null values before everything else, and comparison operators never match null,
+// so links without the field are handled separately.
+func after(field string, order types.DateSort, cursor *model.UrlCursor) bson.D {
+	op := "$gt"
+	if order == constant.NewestDate {
+		op = "$lt"
+	}
+
+	if cursor.Value == nil {
+		sameValue := bson.D{{field, nil}, {"_id", bson.D{{op, cursor.ID}}}}
+		if order == constant.NewestDate {
+			return sameValue
+		}
+		return bson.D{{"$or", bson.A{sameValue, bson.D{{field, bson.D{{"$ne", nil}}}}}}}
+	}
+
+	branches := bson.A{
+		bson.D{{field, bson.D{{op, cursor.Value}}}},
+		bson.D{{field, cursor.Value}, {"_id", bson.D{{op, cursor.ID}}}},
+	}
+	if order == constant.NewestDate {
+		branches = append(branches, bson.D{{field, nil}})
+	}
+	return bson.D{{"$or", branches}}
+}
@@ -1,46 +1,165 @@
 package service
 
 import (
+	"codedln/shared/analytics"
+	"codedln/shared/healthcheck"
 	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	"codedln/shared/metrics"
+	"codedln/shared/opengraph"
+	"codedln/shared/pubsub"
+	"codedln/shared/screening"
 	"codedln/url_module/model"
 	"codedln/url_module/repository"
 	"codedln/util/constant"
+	"codedln/util/helpers"
 	"codedln/util/types"
 	"context"
 	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
-	"log"
+	"log/slog"
 	"math/rand"
 	"net/http"
+	"slices"
+	"strings"
+	"sync"
 	"time"
 )
 
+// ErrUrlDisabled is returned when a disabled, taken down, suspended or blocked link is visited.
+var ErrUrlDisabled = http_error.New(http.StatusGone, "this link has been disabled")
+
+// ErrUrlNotActive is returned when a link is visited before its active window opens.
+// It is a 404 so an embargoed link does not reveal that it exists.
+var ErrUrlNotActive = http_error.New(http.StatusNotFound, "this link is not active yet")
+
+// ErrUrlExpired is returned when a link is visited after its active window closed.
+var ErrUrlExpired = http_error.New(http.StatusGone, "this link has expired")
+
+// InactiveError is returned for a visit outside a link's active window when the link has no fallback url.
+// It wraps ErrUrlNotActive or ErrUrlExpired and carries the owner's message for the page shown instead.
+type InactiveError struct {
+	*http_error.HTTPError
+	Message string
+}
+
+func (e *InactiveError) Unwrap() error {
+	return e.HTTPError
+}
+
+// Notifier tells the owner's webhooks about changes to their links and clicks on them.
+type Notifier interface {
+	Notify(ctx context.Context, userId primitive.ObjectID, event types.WebhookEvent, data any) error
+}
+
 type UrlService struct {
-	repo repository.UrlRepository
+	repo     repository.UrlRepository
+	screener *screening.Screener
+	broker   *pubsub.Broker
+	tracker  *analytics.Tracker
+	checker  *healthcheck.Checker
+	fetcher  *opengraph.Fetcher
+	notifier Notifier
 }
 
-func New(repo repository.UrlRepository) *UrlService {
+func New(repo repository.UrlRepository, screener *screening.Screener, broker *pubsub.Broker, tracker *analytics.Tracker, checker *healthcheck.Checker, fetcher *opengraph.Fetcher, notifier Notifier) *UrlService {
 	return &UrlService{
-		repo: repo,
+		repo:     repo,
+		screener: screener,
+		broker:   broker,
+		tracker:  tracker,
+		checker:  checker,
+		fetcher:  fetcher,
+		notifier: notifier,
 	}
 }
 
-func (s *UrlService) CreateUrl(ctx context.Context, originalUrl string, alias string, userId string) (*model.Url, error) {
+// CreateUrl shortens the payload's destination. The boolean result is false when an existing link was reused instead of created.
+func (s *UrlService) CreateUrl(ctx context.Context, payload model.CreateUrlSchema, userId string) (*model.Url, bool, error) {
+
+	originalUrl, err := model.CanonicalUrl(payload.OriginalUrl)
+	if err != nil {
+		return nil, false, http_error.New(http.StatusBadRequest, "invalid url: "+err.Error())
+	}
+	alias := payload.Alias
+
+	var userIdObj primitive.ObjectID
+	if userId != "" {
+		userIdObj, err = primitive.ObjectIDFromHex(userId)
+		if err != nil {
+			logging.FromContext(ctx).Error("unable to parse user id", "error", err)
+			return nil, false, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+		}
+	}
+
+	// Tags are added before hashing so only links tagged the same way are reused
+	var utm *model.UTM
+	if payload.UTM != nil {
+		utm, originalUrl, err = s.applyUTM(ctx, *payload.UTM, originalUrl, userIdObj)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	destinationHash := hashDestination(originalUrl)
+
+	// Guests have no account to manage their link with, so they get a secret token instead
+	var managementToken string
+	if userIdObj.IsZero() {
+		managementToken, err = helpers.GenerateToken()
+		if err != nil {
+			logging.FromContext(ctx).Error("unable to create management token", "error", err)
+			return nil, false, http_error.New(http.StatusInternalServerError, "unable to create management token")
+		}
+	}
+
+	// Guests share the zero user id, so only signed-in users can reuse their own links
+	if payload.ReuseExisting && alias == "" && !userIdObj.IsZero() {
+		existing, existErr := s.repo.GetUrl(ctx, bson.D{
+			{"userId", userIdObj},
+			{"destinationHash", destinationHash},
+			{"customAlias", false},
+			{"status", bson.D{{"$in", bson.A{constant.ActiveUrl, nil}}}},
+			{"deletedAt", bson.D{{"$exists", false}}},
+			{"activeFrom", bson.D{{"$not", bson.D{{"$gt", time.Now().UTC()}}}}},
+			{"$or", bson.A{
+				bson.D{{"activeUntil", nil}},
+				bson.D{{"activeUntil", bson.D{{"$gt", time.Now().UTC()}}}},
+			}},
+		})
+		if existErr != nil {
+			return nil, false, existErr
+		}
+
+		if existing != nil {
+			return existing, false, nil
+		}
+	}
+
+	if err = s.screener.Screen(ctx, originalUrl); err != nil {
+		return nil, false, err
+	}
+
+	fallbackUrl, err := s.checkFallback(ctx, payload.FallbackUrl)
+	if err != nil {
+		return nil, false, err
+	}
 
 	var shortUrl string
 
 	if alias != "" {
 		exist, err := s.AliasExist(ctx, alias)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 
 		if !exist {
 			shortUrl = alias
 		} else {
-			return nil, http_error.New(http.StatusBadRequest, "alias already exist. try another one")
+			return nil, false, http_error.New(http.StatusBadRequest, "alias already exist. try another one")
 		}
 
 	} else {
@@ -48,7 +167,7 @@ func (s *UrlService) CreateUrl(ctx context.Context, originalUrl string, alias st
 
 		aliasExist, err := s.AliasExist(ctx, shortUrl)
 		if err != nil {
-			return nil, err
+			return nil, false, err
 		}
 		if aliasExist {
 			found := false
@@ -56,7 +175,7 @@ func (s *UrlService) CreateUrl(ctx context.Context, originalUrl string, alias st
 				sUrl := s.GenerateAlias(originalUrl)
 				exist, existErr := s.AliasExist(ctx, sUrl)
 				if existErr != nil {
-					return nil, existErr
+					return nil, false, existErr
 				}
 				if !exist {
 					shortUrl = sUrl
@@ -66,52 +185,76 @@ func (s *UrlService) CreateUrl(ctx context.Context, originalUrl string, alias st
 			}
 
 			if !found {
-				return nil, http_error.New(http.StatusInternalServerError, "unable to generate a short url. please contact support")
+				return nil, false, http_error.New(http.StatusInternalServerError, "unable to generate a short url. please contact support")
 			}
 		}
 	}
 
-	var userIdObj primitive.ObjectID
-	var err error
-	if userId != "" {
-		userIdObj, err = primitive.ObjectIDFromHex(userId)
-		if err != nil {
-			log.Println(err)
-			return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	url := model.Url{
+		UserId:           userIdObj,
+		OriginalUrl:      originalUrl,
+		Alias:            shortUrl,
+		CustomAlias:      alias != "",
+		Title:            strings.TrimSpace(payload.Title),
+		Notes:            strings.TrimSpace(payload.Notes),
+		Tags:             normalizeTags(payload.Tags),
+		UTM:              utm,
+		ForwardQuery:     payload.ForwardQuery,
+		ForwardPath:      payload.ForwardPath,
+		ShowInterstitial: payload.ShowInterstitial,
+		DestinationHash:  destinationHash,
+		Status:           constant.ActiveUrl,
+		ActiveFrom:       payload.ActiveFrom,
+		ActiveUntil:      payload.ActiveUntil,
+		FallbackUrl:      fallbackUrl,
+		InactiveMessage:  strings.TrimSpace(payload.InactiveMessage),
+		CreatedAt:        time.Now().UTC(),
+		UpdatedAt:        time.Now().UTC(),
+	}
+	if managementToken != "" {
+		url.ManagementTokenHash = helpers.HashToken(managementToken)
+	}
+	if payload.SocialPreview != nil {
+		if preview := payload.SocialPreview.Trimmed(); !preview.IsZero() {
+			url.SocialPreview = &preview
 		}
 	}
-	url := model.Url{
-		UserId:      userIdObj,
-		OriginalUrl: originalUrl,
-		Alias:       shortUrl,
-		CreatedAt:   time.Now().UTC(),
-		UpdatedAt:   time.Now().UTC(),
+
+	newUrl, err := s.repo.CreateUrl(ctx, url)
+	if err != nil {
+		return nil, false, err
+	}
+	newUrl.ManagementToken = managementToken
+
+	// Reading the destination can take seconds, so the link is returned straight away and its metadata shows up later
+	if payload.FetchMetadata && s.fetcher != nil {
+		go s.fetchMetadata(newUrl.ID, newUrl.OriginalUrl)
 	}
+	s.notify(ctx, newUrl, constant.UrlCreatedWebhook, newUrl)
 
-	return s.repo.CreateUrl(ctx, url)
+	return newUrl, true, nil
 }
 
 func (s *UrlService) CheckAliasExistence(ctx context.Context, alias string) error {
 
-	query := bson.D{{"alias", alias}}
-	url, err := s.repo.GetUrl(ctx, query)
+	exist, err := s.AliasExist(ctx, alias)
 	if err != nil {
 		return err
 	}
 
-	if url != nil {
+	if exist {
 		return http_error.New(http.StatusBadRequest, "alias exist")
 	}
 
 	return nil
 }
 
-func (s *UrlService) GetUrls(ctx context.Context, query string, sort types.DateSort, limit int64, skip int64, userId string) (*types.PaginationResult[model.Url], error) {
+func (s *UrlService) GetUrls(ctx context.Context, query model.UrlListQuery, userId string) (*types.CursorResult[model.Url], error) {
 	userIdObj, err := primitive.ObjectIDFromHex(userId)
 	if err != nil {
 		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
 	}
-	return s.repo.GetUrls(ctx, query, sort, limit, skip, userIdObj)
+	return s.repo.GetUrls(ctx, query, userIdObj)
 }
 
 func (s *UrlService) GetUrl(ctx context.Context, urlId string, userId string) (*model.Url, error) {
@@ -128,6 +271,7 @@ func (s *UrlService) GetUrl(ctx context.Context, urlId string, userId string) (*
 	query := bson.D{{"$and", bson.A{
 		bson.D{{"_id", urlIdObj}},
 		bson.D{{"userId", userIdObj}},
+		bson.D{{"deletedAt", bson.D{{"$exists", false}}}},
 	}}}
 	url, err := s.repo.GetUrl(ctx, query)
 	if url == nil {
@@ -136,6 +280,69 @@ func (s *UrlService) GetUrl(ctx context.Context, urlId string, userId string) (*
 	return url, err
 }
 
+// UpdateSchedule replaces the link's active window, fallback url and inactive message.
+func (s *UrlService) UpdateSchedule(ctx context.Context, urlId string, userId string, payload model.ScheduleSchema) (*model.Url, error) {
+	url, err := s.GetUrl(ctx, urlId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	fallbackUrl, err := s.checkFallback(ctx, payload.FallbackUrl)
+	if err != nil {
+		return nil, err
+	}
+
+	set := bson.D{{"updatedAt", time.Now().UTC()}}
+	unset := bson.D{}
+	fields := []struct {
+		key   string
+		value any
+		empty bool
+	}{
+		{"activeFrom", payload.ActiveFrom, payload.ActiveFrom == nil},
+		{"activeUntil", payload.ActiveUntil, payload.ActiveUntil == nil},
+		{"fallbackUrl", fallbackUrl, fallbackUrl == ""},
+		{"inactiveMessage", strings.TrimSpace(payload.InactiveMessage), strings.TrimSpace(payload.InactiveMessage) == ""},
+	}
+	for _, field := range fields {
+		if field.empty {
+			unset = append(unset, bson.E{Key: field.key, Value: ""})
+		} else {
+			set = append(set, bson.E{Key: field.key, Value: field.value})
+		}
+	}
+
+	update := bson.D{{"$set", set}}
+	if len(unset) > 0 {
+		update = append(update, bson.E{Key: "$unset", Value: unset})
+	}
+	if err = s.repo.UpdateUrls(ctx, bson.D{{"_id", url.ID}}, update); err != nil {
+		return nil, err
+	}
+
+	return s.updated(ctx, urlId, userId)
+}
+
+// UpdateSocialPreview replaces what the owner chose to show crawlers in place of the destination's own metadata.
+// An empty preview removes it.
+func (s *UrlService) UpdateSocialPreview(ctx context.Context, urlId string, userId string, payload model.OpenGraph) (*model.Url, error) {
+	url, err := s.GetUrl(ctx, urlId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := payload.Trimmed()
+	update := bson.D{{"$set", bson.D{{"socialPreview", preview}, {"updatedAt", time.Now().UTC()}}}}
+	if preview.IsZero() {
+		update = bson.D{{"$set", bson.D{{"updatedAt", time.Now().UTC()}}}, {"$unset", bson.D{{"socialPreview", ""}}}}
+	}
+	if err = s.repo.UpdateUrls(ctx, bson.D{{"_id", url.ID}}, update); err != nil {
+		return nil, err
+	}
+
+	return s.updated(ctx, urlId, userId)
+}
+
 func (s *UrlService) DeleteUrl(ctx context.Context, urlId string, userId string) error {
 	urlIdObj, err := primitive.ObjectIDFromHex(urlId)
 	if err != nil {
@@ -146,7 +353,17 @@ func (s *UrlService) DeleteUrl(ctx context.Context, urlId string, userId string)
 	if err != nil {
 		return http_error.New(http.StatusInternalServerError, "unable to parse user id")
 	}
-	return s.repo.DeleteUrl(ctx, urlIdObj, userIdObj)
+
+	trashed, err := s.trashable(ctx, []primitive.ObjectID{urlIdObj}, userIdObj)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.DeleteUrl(ctx, urlIdObj, userIdObj); err != nil {
+		return err
+	}
+	s.notifyDeleted(ctx, trashed)
+	return nil
 }
 
 func (s *UrlService) DeleteUrls(ctx context.Context, urlIds []string, userId string) error {
@@ -156,39 +373,629 @@ func (s *UrlService) DeleteUrls(ctx context.Context, urlIds []string, userId str
 		return http_error.New(http.StatusInternalServerError, "unable to parse user id")
 	}
 
-	objectIDs := make([]primitive.ObjectID, len(urlIds))
-	for i, id := range urlIds {
-		oid, err := primitive.ObjectIDFromHex(id)
-		if err != nil {
-			log.Println(err)
-			return http_error.New(http.StatusInternalServerError, "invalid id format")
+	objectIDs, err := parseObjectIds(urlIds)
+	if err != nil {
+		return err
+	}
+
+	trashed, err := s.trashable(ctx, objectIDs, userIdObj)
+	if err != nil {
+		return err
+	}
+
+	if err = s.repo.DeleteUrls(ctx, objectIDs, userIdObj); err != nil {
+		return err
+	}
+	s.notifyDeleted(ctx, trashed)
+	return nil
+}
+
+// trashable returns the user's links among urlIds that are not already in the trash, so only links that are actually deleted are announced.
+func (s *UrlService) trashable(ctx context.Context, urlIds []primitive.ObjectID, userId primitive.ObjectID) ([]model.Url, error) {
+	if s.notifier == nil {
+		return nil, nil
+	}
+
+	var urls []model.Url
+	filter := bson.D{{"_id", bson.D{{"$in", urlIds}}}, {"userId", userId}, {"deletedAt", bson.D{{"$exists", false}}}}
+	err := s.repo.ScanUrls(ctx, filter, func(url model.Url) error {
+		urls = append(urls, url)
+		return nil
+	})
+	return urls, err
+}
+
+func (s *UrlService) notifyDeleted(ctx context.Context, urls []model.Url) {
+	now := time.Now().UTC()
+	for _, url := range urls {
+		url.DeletedAt = &now
+		s.notify(ctx, &url, constant.UrlDeletedWebhook, url)
+	}
+}
+
+func (s *UrlService) GetTrashedUrls(ctx context.Context, limit int64, skip int64, userId string) (*types.PaginationResult[model.Url], error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+	return s.repo.GetTrashedUrls(ctx, limit, skip, userIdObj)
+}
+
+// RestoreUrls takes links back out of the trash. It returns a 404 when none of the ids were in the user's trash.
+func (s *UrlService) RestoreUrls(ctx context.Context, urlIds []string, userId string) (int64, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return 0, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	objectIDs, err := parseObjectIds(urlIds)
+	if err != nil {
+		return 0, err
+	}
+
+	restored, err := s.repo.RestoreUrls(ctx, objectIDs, userIdObj)
+	if err != nil {
+		return 0, err
+	}
+
+	if restored == 0 {
+		return 0, http_error.New(http.StatusNotFound, "no trashed url found")
+	}
+
+	return restored, nil
+}
+
+// PurgeTrash permanently removes links that have been in the trash longer than retentionDays.
+// Their aliases stay reserved for cooldownDays afterwards so they cannot be hijacked.
+func (s *UrlService) PurgeTrash(ctx context.Context, retentionDays int, cooldownDays int) (int64, error) {
+	now := time.Now().UTC()
+	deletedBefore := now.AddDate(0, 0, -retentionDays)
+	reservedUntil := now.AddDate(0, 0, cooldownDays)
+	return s.repo.PurgeUrls(ctx, deletedBefore, reservedUntil)
+}
+
+func (s *UrlService) GetGuestUrl(ctx context.Context, alias string, token string) (*model.Url, error) {
+	if token == "" {
+		return nil, http_error.New(http.StatusUnauthorized, "management token required")
+	}
+
+	filter := bson.D{
+		{"alias", alias},
+		{"managementTokenHash", helpers.HashToken(token)},
+		{"userId", bson.D{{"$exists", false}}},
+		{"deletedAt", bson.D{{"$exists", false}}},
+	}
+	url, err := s.repo.GetUrl(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if url == nil {
+		return nil, http_error.New(http.StatusNotFound, "no guest url found for the alias and token")
+	}
+
+	return url, nil
+}
+
+func (s *UrlService) DeleteGuestUrl(ctx context.Context, alias string, token string) error {
+	if token == "" {
+		return http_error.New(http.StatusUnauthorized, "management token required")
+	}
+
+	deleted, err := s.repo.DeleteGuestUrl(ctx, alias, helpers.HashToken(token))
+	if err != nil {
+		return err
+	}
+
+	if !deleted {
+		return http_error.New(http.StatusNotFound, "no guest url found for the alias and token")
+	}
+
+	return nil
+}
+
+func (s *UrlService) ClaimGuestUrls(ctx context.Context, tokens []string, userId string) (int64, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return 0, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	tokenHashes := make([]string, len(tokens))
+	for i, token := range tokens {
+		tokenHashes[i] = helpers.HashToken(token)
+	}
+
+	return s.repo.ClaimGuestUrls(ctx, tokenHashes, userIdObj)
+}
+
+// Redirect records the visit and returns where to send the visitor.
+func (s *UrlService) Redirect(ctx context.Context, alias string, visit model.Visit) (*model.Redirect, error) {
+	filter := bson.D{{"alias", alias}, {"deletedAt", bson.D{{"$exists", false}}}}
+	url, err := s.repo.GetUrl(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	if url == nil {
+		metrics.RecordRedirect(constant.RedirectMiss)
+		return nil, http_error.New(http.StatusBadRequest, "no url found for the alias")
+	}
+
+	// Only links that act as a prefix have anything under them
+	if visit.Path != "" && !url.ForwardPath {
+		metrics.RecordRedirect(constant.RedirectMiss)
+		return nil, http_error.New(http.StatusNotFound, "no url found for the path")
+	}
+
+	if url.IsDisabled() {
+		metrics.RecordRedirect(constant.RedirectDisabled)
+		return nil, ErrUrlDisabled
+	}
+
+	// Visits outside the window go to the fallback url without counting as clicks on the link
+	if err = inactive(url); err != nil {
+		if url.FallbackUrl != "" {
+			metrics.RecordRedirect(constant.RedirectFallback)
+			return &model.Redirect{Destination: url.FallbackUrl}, nil
 		}
-		objectIDs[i] = oid
+		metrics.RecordRedirect(constant.RedirectInactive)
+		return nil, err
+	}
+
+	// A failed count should not stop the visitor from being redirected
+	bot := s.tracker.IsBot(visit.Method, visit.Header)
+	clicks, _ := s.repo.RecordClick(ctx, url.ID, bot)
+	if err = s.tracker.Track(ctx, url.ID.Hex(), visit.IP, visit.UserAgent, visit.Source(), bot, time.Now()); err != nil {
+		logging.FromContext(ctx).Error("unable to track visitor", "url_id", url.ID.Hex(), "error", err)
+	}
+
+	click := model.ClickEvent{
+		UrlId:     url.ID,
+		Alias:     url.Alias,
+		Referrer:  visit.Referrer,
+		UserAgent: visit.UserAgent,
+		Bot:       bot,
+		ClickedAt: time.Now().UTC(),
+	}
+	s.publishClick(ctx, url, click)
+
+	// Webhooks only hear about people, and queueing them should not hold up the redirect
+	if !bot && s.notifier != nil && !url.UserId.IsZero() {
+		go func(ctx context.Context) {
+			s.notify(ctx, url, constant.ClickWebhook, click)
+			if milestone(clicks) {
+				s.notify(ctx, url, constant.ClickThresholdWebhook, model.ClickThresholdEvent{UrlId: url.ID, Alias: url.Alias, Clicks: clicks, ReachedAt: click.ClickedAt})
+			}
+		}(context.WithoutCancel(ctx))
+	}
+
+	destination, err := url.Destination(visit.Path, visit.Query)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to build the destination url", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to build the destination url")
 	}
 
-	return s.repo.DeleteUrls(ctx, objectIDs, userIdObj)
+	metrics.RecordRedirect(constant.RedirectHit)
+	redirect := &model.Redirect{Destination: destination, Interstitial: url.ShowInterstitial}
 
+	// Crawlers that unfurl the link are only held back when there is something better to show them than the destination's own tags
+	if bot && (url.SocialPreview != nil || (url.Metadata != nil && !url.Metadata.OpenGraph.IsZero())) {
+		unfurl := url.Unfurl()
+		redirect.Unfurl = &unfurl
+	}
+
+	return redirect, nil
 }
 
-func (s *UrlService) Redirect(ctx context.Context, alias string) (string, error) {
-	filter := bson.D{{"alias", alias}}
+// Preview returns the link behind an alias so it can be inspected without following it or counting a click.
+// Links that would not redirect are not previewed either, which keeps embargoed destinations hidden.
+func (s *UrlService) Preview(ctx context.Context, alias string) (*model.Url, error) {
+	filter := bson.D{{"alias", alias}, {"deletedAt", bson.D{{"$exists", false}}}}
 	url, err := s.repo.GetUrl(ctx, filter)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
 	if url == nil {
-		return "", http_error.New(http.StatusBadRequest, "no url found for the alias")
+		return nil, http_error.New(http.StatusNotFound, "no url found for the alias")
+	}
+
+	if url.IsDisabled() {
+		return nil, ErrUrlDisabled
+	}
+
+	if err = inactive(url); err != nil {
+		return nil, err
+	}
+
+	return url, nil
+}
+
+// GetStats returns the link's totals along with its daily activity from the start of from to the end of to.
+// Bot traffic is only counted when includeBots is set.
+func (s *UrlService) GetStats(ctx context.Context, urlId string, userId string, from time.Time, to time.Time, includeBots bool) (*model.UrlStats, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	url, err := s.GetUrl(ctx, urlId, userId)
+	if err != nil {
+		return nil, err
+	}
+
+	uniqueVisitors, err := s.tracker.UniqueVisitors(ctx, []string{url.ID.Hex()}, includeBots)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to count unique visitors", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to count unique visitors")
+	}
+
+	activity, err := s.tracker.Between(ctx, []string{url.ID.Hex()}, from, to, includeBots)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to get daily stats", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get daily stats")
+	}
+
+	referrals, err := s.tracker.Referrals(ctx, []string{url.ID.Hex()}, includeBots)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to get referrals", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get referrals")
+	}
+
+	return &model.UrlStats{
+		UrlId:          url.ID,
+		Clicks:         url.Clicks + helpers.Ternary(includeBots, url.BotClicks, 0),
+		BotClicks:      url.BotClicks,
+		IncludesBots:   includeBots,
+		UniqueVisitors: uniqueVisitors,
+		LastClickedAt:  url.LastClickedAt,
+		From:           from.Format(time.DateOnly),
+		To:             to.Format(time.DateOnly),
+		Range:          activity,
+		Referrals:      referrals,
+	}, nil
+}
+
+func (s *UrlService) GetCampaigns(ctx context.Context, userId string) ([]model.Campaign, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+	return s.repo.GetCampaigns(ctx, userIdObj, "")
+}
+
+// GetCampaignStats combines the stats of every link in the campaign. Visitors who clicked several of its links are counted once.
+func (s *UrlService) GetCampaignStats(ctx context.Context, campaign string, userId string, from time.Time, to time.Time, includeBots bool) (*model.CampaignStats, error) {
+	if err := validateRange(from, to); err != nil {
+		return nil, err
+	}
+
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	campaigns, err := s.repo.GetCampaigns(ctx, userIdObj, campaign)
+	if err != nil {
+		return nil, err
+	}
+	if len(campaigns) == 0 {
+		return nil, http_error.New(http.StatusNotFound, "no links found for the campaign")
+	}
+	totals := campaigns[0]
+
+	urlIds := make([]string, len(totals.UrlIds))
+	for i, id := range totals.UrlIds {
+		urlIds[i] = id.Hex()
+	}
+
+	uniqueVisitors, err := s.tracker.UniqueVisitors(ctx, urlIds, includeBots)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to count unique visitors", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to count unique visitors")
+	}
+
+	activity, err := s.tracker.Between(ctx, urlIds, from, to, includeBots)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to get daily stats", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get daily stats")
+	}
+
+	totals.Clicks += helpers.Ternary(includeBots, totals.BotClicks, 0)
+	return &model.CampaignStats{
+		Campaign:       totals,
+		IncludesBots:   includeBots,
+		UniqueVisitors: uniqueVisitors,
+		From:           from.Format(time.DateOnly),
+		To:             to.Format(time.DateOnly),
+		Range:          activity,
+	}, nil
+}
+
+// GetUtmTemplate returns the user's default UTM values, which are empty until a template is saved.
+func (s *UrlService) GetUtmTemplate(ctx context.Context, userId string) (*model.UtmTemplate, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	template, err := s.repo.GetUtmTemplate(ctx, userIdObj)
+	if err != nil {
+		return nil, err
+	}
+	if template == nil {
+		return &model.UtmTemplate{UserId: userIdObj}, nil
+	}
+	return template, nil
+}
+
+func (s *UrlService) SaveUtmTemplate(ctx context.Context, utm model.UTM, userId string) (*model.UtmTemplate, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	return s.repo.SaveUtmTemplate(ctx, model.UtmTemplate{
+		UserId:    userIdObj,
+		UTM:       utm.Trimmed(),
+		UpdatedAt: time.Now().UTC(),
+	})
+}
+
+// applyUTM fills the values left empty from the user's template and sets them on the destination.
+func (s *UrlService) applyUTM(ctx context.Context, utm model.UTM, destination string, userId primitive.ObjectID) (*model.UTM, string, error) {
+	utm = utm.Trimmed()
+	if !userId.IsZero() {
+		template, err := s.repo.GetUtmTemplate(ctx, userId)
+		if err != nil {
+			return nil, "", err
+		}
+		if template != nil {
+			utm = utm.WithDefaults(template.UTM)
+		}
+	}
+
+	if utm.IsZero() {
+		return nil, destination, nil
+	}
+
+	tagged, err := utm.Apply(destination)
+	if err != nil {
+		return nil, "", http_error.New(http.StatusBadRequest, "invalid url: "+err.Error())
+	}
+
+	// The tags can push the url past the length limit
+	tagged, err = model.CanonicalUrl(tagged)
+	if err != nil {
+		return nil, "", http_error.New(http.StatusBadRequest, "invalid url: "+err.Error())
+	}
+
+	return &utm, tagged, nil
+}
+
+// inactive returns an InactiveError when the link is outside its active window.
+func inactive(url *model.Url) error {
+	if !url.IsPending() && !url.IsExpired() {
+		return nil
+	}
+	return &InactiveError{
+		HTTPError: helpers.Ternary(url.IsPending(), ErrUrlNotActive, ErrUrlExpired),
+		Message:   url.InactiveMessage,
+	}
+}
+
+// checkFallback canonicalizes a fallback url and screens it like any other destination. An empty url is left empty.
+func (s *UrlService) checkFallback(ctx context.Context, raw string) (string, error) {
+	if raw == "" {
+		return "", nil
 	}
 
-	return url.OriginalUrl, nil
+	fallbackUrl, err := model.CanonicalUrl(raw)
+	if err != nil {
+		return "", http_error.New(http.StatusBadRequest, "invalid fallback url: "+err.Error())
+	}
+
+	if err = s.screener.Screen(ctx, fallbackUrl); err != nil {
+		return "", err
+	}
+
+	return fallbackUrl, nil
+}
+
+// publishClick sends the click to anyone watching the link or its owner's links live.
+func (s *UrlService) publishClick(ctx context.Context, url *model.Url, event model.ClickEvent) {
+	channels := []string{constant.UrlClickChannel + url.ID.Hex()}
+	if !url.UserId.IsZero() {
+		channels = append(channels, constant.UserClickChannel+url.UserId.Hex())
+	}
+
+	if err := s.broker.Publish(ctx, event, channels...); err != nil {
+		logging.FromContext(ctx).Error("unable to publish click", "error", err)
+	}
+}
+
+// notify queues the event for the owner's webhooks. Guest links have no owner to notify,
+// and a failure to queue is logged rather than failing the change that caused it.
+func (s *UrlService) notify(ctx context.Context, url *model.Url, event types.WebhookEvent, data any) {
+	if s.notifier == nil || url.UserId.IsZero() {
+		return
+	}
+	if err := s.notifier.Notify(ctx, url.UserId, event, data); err != nil {
+		logging.FromContext(ctx).Error("unable to queue webhook", "event", event, "url_id", url.ID.Hex(), "error", err)
+	}
+}
+
+// updated returns the link after a change and tells the owner's webhooks about it.
+func (s *UrlService) updated(ctx context.Context, urlId string, userId string) (*model.Url, error) {
+	url, err := s.GetUrl(ctx, urlId, userId)
+	if err != nil {
+		return nil, err
+	}
+	s.notify(ctx, url, constant.UrlUpdatedWebhook, url)
+	return url, nil
+}
+
+// milestone reports whether a click count is one that click.threshold webhooks are sent for: FirstClickMilestone and every power of ten after it.
+func milestone(clicks int64) bool {
+	for reached := int64(constant.FirstClickMilestone); reached <= clicks; reached *= 10 {
+		if reached == clicks {
+			return true
+		}
+	}
+	return false
+}
+
+// LiveUrlClicks streams click events for one of the user's links until ctx is cancelled.
+func (s *UrlService) LiveUrlClicks(ctx context.Context, urlId string, userId string) (<-chan []byte, error) {
+	url, err := s.GetUrl(ctx, urlId, userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.subscribe(ctx, constant.UrlClickChannel+url.ID.Hex())
+}
+
+// LiveUserClicks streams click events for all of the user's links until ctx is cancelled.
+func (s *UrlService) LiveUserClicks(ctx context.Context, userId string) (<-chan []byte, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+	return s.subscribe(ctx, constant.UserClickChannel+userIdObj.Hex())
+}
+
+func (s *UrlService) subscribe(ctx context.Context, channel string) (<-chan []byte, error) {
+	if s.broker == nil {
+		return nil, http_error.New(http.StatusServiceUnavailable, "live clicks are unavailable")
+	}
+
+	messages, err := s.broker.Subscribe(ctx, channel)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to subscribe to clicks", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to subscribe to clicks")
+	}
+	return messages, nil
+}
+
+// RescanUrls checks every active or blocked link against the current blocklist.
+// Links that now match are blocked and links that were blocked but no longer match are restored.
+func (s *UrlService) RescanUrls(ctx context.Context) error {
+	filter := bson.D{{"status", bson.D{{"$in", bson.A{constant.ActiveUrl, constant.BlockedUrl, nil}}}}}
+
+	return s.repo.ScanUrls(ctx, filter, func(url model.Url) error {
+		reason, blocked := s.screener.Check(url.OriginalUrl)
+
+		var update bson.D
+		switch {
+		case blocked && url.Status != constant.BlockedUrl:
+			update = bson.D{{"$set", bson.D{{"status", constant.BlockedUrl}, {"statusReason", reason}, {"updatedAt", time.Now().UTC()}}}}
+		case !blocked && url.Status == constant.BlockedUrl:
+			update = bson.D{{"$set", bson.D{{"status", constant.ActiveUrl}, {"statusReason", ""}, {"updatedAt", time.Now().UTC()}}}}
+		default:
+			return nil
+		}
+
+		return s.repo.UpdateUrls(ctx, bson.D{{"_id", url.ID}}, update)
+	})
+}
+
+// fetchMetadata reads the title and Open Graph tags of the link's destination and stores them on the link.
+// It runs in the background after the link is created, so failures are recorded on the link rather than returned.
+func (s *UrlService) fetchMetadata(urlId primitive.ObjectID, destination string) {
+	ctx, cancel := context.WithTimeout(context.Background(), constant.OpenGraphFetchTimeout*time.Second)
+	defer cancel()
+
+	metadata := model.LinkMetadata{FetchedAt: time.Now().UTC()}
+	fetched, err := s.fetcher.Fetch(ctx, destination)
+	if err != nil {
+		metadata.FetchError = err.Error()
+	} else {
+		metadata.OpenGraph = model.OpenGraph(fetched)
+	}
+
+	// The fetch may have used up its whole timeout, which should not stop the outcome being saved
+	if err = s.repo.UpdateUrls(context.Background(), bson.D{{"_id", urlId}}, bson.D{{"$set", bson.D{{"metadata", metadata}}}}); err != nil {
+		slog.Error("unable to save url metadata", "url_id", urlId.Hex(), "error", err)
+	}
+}
+
+// CheckUrlHealth requests the destination of every live link that has not been checked within HealthCheckInterval
+// and records the outcome on the link. Links that keep failing are flagged as broken until a check succeeds again.
+func (s *UrlService) CheckUrlHealth(ctx context.Context) error {
+	if s.checker == nil {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	filter := bson.D{
+		{"status", bson.D{{"$in", bson.A{constant.ActiveUrl, nil}}}},
+		{"deletedAt", bson.D{{"$exists", false}}},
+		{"$or", bson.A{
+			bson.D{{"health.checkedAt", bson.D{{"$exists", false}}}},
+			bson.D{{"health.checkedAt", bson.D{{"$lte", now.Add(-constant.HealthCheckInterval * time.Hour)}}}},
+		}},
+	}
+
+	slots := make(chan struct{}, constant.HealthCheckConcurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	return s.repo.ScanUrls(ctx, filter, func(url model.Url) error {
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+
+			result := s.checker.Check(ctx, url.OriginalUrl)
+			// A check cut short by shutdown says nothing about the destination
+			if ctx.Err() != nil {
+				return
+			}
+
+			health := nextHealth(url.Health, result)
+			update := bson.D{{"$set", bson.D{{"health", health}}}}
+			if err := s.repo.UpdateUrls(ctx, bson.D{{"_id", url.ID}}, update); err != nil {
+				logging.FromContext(ctx).Error("unable to record url health", "url_id", url.ID.Hex(), "error", err)
+			}
+		}()
+		return nil
+	})
+}
+
+// nextHealth folds a check result into the link's previous health.
+func nextHealth(previous *model.LinkHealth, result healthcheck.Result) model.LinkHealth {
+	health := model.LinkHealth{
+		StatusCode:    result.StatusCode,
+		LatencyMs:     result.Latency.Milliseconds(),
+		RedirectChain: result.RedirectChain,
+		Error:         result.Error,
+		CheckedAt:     result.CheckedAt,
+	}
+	if result.Healthy() {
+		return health
+	}
+
+	health.ConsecutiveFailures = 1
+	health.FailingSince = &result.CheckedAt
+	if previous != nil && previous.ConsecutiveFailures > 0 {
+		health.ConsecutiveFailures = previous.ConsecutiveFailures + 1
+		health.FailingSince = previous.FailingSince
+	}
+	health.Broken = health.ConsecutiveFailures >= constant.BrokenLinkThreshold
+	return health
 }
 
 func (s *UrlService) AliasExist(ctx context.Context, shortUrl string) (bool, error) {
+	// Trashed links still hold their alias, so this deliberately does not filter on deletedAt
 	filter := bson.D{{"alias", shortUrl}}
 	url, err := s.repo.GetUrl(ctx, filter)
 	if err != nil {
-		log.Println(err)
 		return true, err
 	}
 
@@ -196,7 +1003,7 @@ func (s *UrlService) AliasExist(ctx context.Context, shortUrl string) (bool, err
 		return true, nil
 	}
 
-	return false, nil
+	return s.repo.AliasReserved(ctx, shortUrl)
 }
 
 func (s *UrlService) GenerateAlias(originalUrl string) string {
@@ -218,8 +1025,48 @@ func (s *UrlService) GenerateAlias(originalUrl string) string {
 		selectedBytes[i] = hashBytes[r.Intn(len(hashBytes))]
 	}
 
-	// Encode the selected bytes into Base64
-	encoded := base64.StdEncoding.EncodeToString(selectedBytes)
+	// Encode the selected bytes into URL safe Base64 so the alias can be used as a path segment
+	encoded := base64.RawURLEncoding.EncodeToString(selectedBytes)
 
 	return encoded
 }
+
+// hashDestination is stored on every link and indexed per user so duplicate destinations can be found without comparing long urls.
+func hashDestination(canonicalUrl string) string {
+	h := sha256.Sum256([]byte(canonicalUrl))
+	return hex.EncodeToString(h[:])
+}
+
+// normalizeTags lowercases tags and drops duplicates so tag: searches match regardless of how a tag was typed.
+func normalizeTags(tags []string) []string {
+	var normalized []string
+	for _, tag := range tags {
+		tag = model.NormalizeTag(tag)
+		if !slices.Contains(normalized, tag) {
+			normalized = append(normalized, tag)
+		}
+	}
+	return normalized
+}
+
+func parseObjectIds(ids []string) ([]primitive.ObjectID, error) {
+	objectIDs := make([]primitive.ObjectID, len(ids))
+	for i, id := range ids {
+		oid, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return nil, http_error.New(http.StatusInternalServerError, "invalid id format")
+		}
+		objectIDs[i] = oid
+	}
+	return objectIDs, nil
+}
+
+func validateRange(from time.Time, to time.Time) error {
+	if to.Before(from) {
+		return http_error.New(http.StatusBadRequest, "from must not be after to")
+	}
+	if to.Sub(from) >= constant.MaxStatsRangeDays*24*time.Hour {
+		return http_error.New(http.StatusBadRequest, "the date range can be at most 366 days")
+	}
+	return nil
+}
@@ -1,7 +1,9 @@
 package controller
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/http_error"
+	"codedln/shared/logging"
 	"codedln/user_module/model"
 	"codedln/user_module/service"
 	"codedln/util/constant"
@@ -12,11 +14,15 @@ import (
 
 type UserController struct {
 	userService *service.UserService
+	auth        config.Auth
+	production  bool
 }
 
-func New(userService *service.UserService) *UserController {
+func New(userService *service.UserService, auth config.Auth, production bool) *UserController {
 	return &UserController{
 		userService: userService,
+		auth:        auth,
+		production:  production,
 	}
 }
 
@@ -37,13 +43,18 @@ func (c *UserController) CreateUser(w http.ResponseWriter, r *http.Request) erro
 		return err
 	}
 
-	accessToken := helpers.CreateJWT(types.AuthUser{UserId: user.ID.Hex()}, constant.AccessTokenTTL)
+	// Failing to claim guest links should not stop the user from signing in, they can claim them again later
+	if _, claimErr := c.userService.ClaimGuestUrls(r.Context(), payload.GuestTokens, user.ID); claimErr != nil {
+		logging.FromContext(r.Context()).Error("unable to claim guest urls", "error", claimErr)
+	}
+
+	accessToken := helpers.CreateJWT(types.AuthUser{UserId: user.ID.Hex()}, c.auth.AccessTokenTTL, c.auth.JwtSecret)
 
 	if accessToken == "" {
 		return http_error.New(http.StatusInternalServerError, "unable to create jwt")
 	}
 
-	cookie := helpers.CreateCookie(constant.JwtCookieName, accessToken, constant.AccessTokenTTL)
+	cookie := helpers.CreateCookie(constant.JwtCookieName, accessToken, c.auth.AccessTokenTTL, c.production)
 	http.SetCookie(w, &cookie)
 
 	return helpers.JSONResponse(w, http.StatusCreated, user)
@@ -88,7 +99,7 @@ func (c *UserController) DeleteUser(w http.ResponseWriter, r *http.Request) erro
 }
 
 func (c *UserController) Logout(w http.ResponseWriter, r *http.Request) error {
-	cookie := helpers.CreateCookie(constant.JwtCookieName, "", 0)
+	cookie := helpers.CreateCookie(constant.JwtCookieName, "", 0, c.production)
 	http.SetCookie(w, &cookie)
 	return helpers.JSONResponse(w, http.StatusOK, nil)
 }
@@ -1,6 +1,7 @@
 package model
 
 import (
+	"codedln/util/types"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"time"
 )
@@ -12,6 +13,8 @@ type User struct {
 	Email     string             `bson:"email" json:"email"`
 	Picture   string             `bson:"picture" json:"picture"`
 	Verified  bool               `bson:"verified" json:"verified"`
+	Role      types.Role         `bson:"role" json:"role"`
+	Suspended bool               `bson:"suspended" json:"suspended"`
 	CreatedAt time.Time          `bson:"createdAt" json:"createdAt"`
 	UpdatedAt time.Time          `bson:"updatedAt" json:"updatedAt"`
 }
@@ -9,8 +9,9 @@ import (
 )
 
 type CreateUserSchema struct {
-	IdToken    string            `json:"idToken"`
-	SignInWith types.OAuthSignIn `json:"signInWith"`
+	IdToken     string            `json:"idToken"`
+	SignInWith  types.OAuthSignIn `json:"signInWith"`
+	GuestTokens []string          `json:"guestTokens"`
 }
 
 func NewCreateUserSchema() CreateUserSchema {
@@ -28,5 +29,9 @@ func (s CreateUserSchema) Validate() error {
 		return http_error.New(http.StatusBadRequest, "invalid sign in method")
 	}
 
+	if len(s.GuestTokens) > constant.MaxGuestTokens {
+		return http_error.New(http.StatusBadRequest, "at most 50 guest tokens can be claimed")
+	}
+
 	return nil
 }
@@ -1,7 +1,9 @@
 package module
 
 import (
+	"codedln/shared/config"
 	"codedln/shared/middleware"
+	urlRepository "codedln/url_module/repository"
 	"codedln/user_module/controller"
 	"codedln/user_module/model"
 	"codedln/user_module/repository"
@@ -12,65 +14,53 @@ import (
 	"go.mongodb.org/mongo-driver/mongo"
 	"log"
 	"net/http"
-	"time"
 )
 
-func UserModule(router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Database) {
+func UserModule(router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Database, cfg *config.Config) {
 
 	collection := db.Collection(constant.UserCollection)
 	if collection == nil {
 		log.Fatalf("%s does not exist:", constant.UserCollection)
 	}
+	urlCollection := db.Collection(constant.UrlCollection)
+	if urlCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UrlCollection)
+	}
+
 	userRepo := repository.New(collection)
-	userService := service.New(userRepo)
-	userController := controller.New(userService)
+	userService := service.New(userRepo, urlRepository.New(urlCollection, db.Collection(constant.ReservedAliasCollection), db.Collection(constant.UtmTemplateCollection)), cfg.Auth.GoogleClientId)
+	userController := controller.New(userService, cfg.Auth, cfg.Production())
 
 	userRouter := router.PathPrefix("/user").Subrouter()
 
 	userRouter.HandleFunc("/logout",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			userController.Logout,
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   10,
-				Burst:  5,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodDelete)
 
 	userRouter.HandleFunc("",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			userController.DeleteUser,
-			middleware.AuthenticationMiddleware,
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   100,
-				Burst:  50,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.AuthenticationMiddleware(collection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodDelete)
 
 	userRouter.HandleFunc("",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			userController.CreateUser,
 			middleware.PayloadValidationMiddleware(model.NewCreateUserSchema),
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   10,
-				Burst:  5,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodPost)
 
 	userRouter.HandleFunc("",
 		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
 			userController.GetUser,
-			middleware.AuthenticationMiddleware,
-			middleware.RateLimitMiddleware(limiter, redis_rate.Limit{
-				Rate:   100,
-				Burst:  50,
-				Period: time.Minute * 2,
-			}),
-			middleware.ValidateAPIKeyMiddleware,
+			middleware.AuthenticationMiddleware(collection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
 		))).Methods(http.MethodGet)
 }
@@ -2,6 +2,7 @@ package repository
 
 import (
 	"codedln/shared/http_error"
+	"codedln/shared/logging"
 	"codedln/user_module/model"
 	"context"
 	"errors"
@@ -11,7 +12,6 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 	"go.mongodb.org/mongo-driver/mongo/readconcern"
 	"go.mongodb.org/mongo-driver/mongo/readpref"
-	"log"
 	"net/http"
 )
 
@@ -35,7 +35,7 @@ func (r *MongoUserRepository) CreateUser(ctx context.Context, user model.User) (
 	res, err := r.collection.InsertOne(ctx, user)
 
 	if err != nil {
-		log.Println(err)
+		logging.FromContext(ctx).Error("database error", "error", err)
 		return nil, errors.New("unable to create user")
 	}
 
@@ -43,7 +43,7 @@ func (r *MongoUserRepository) CreateUser(ctx context.Context, user model.User) (
 
 	//Fetch the newly inserted user
 	if findErr := r.collection.FindOne(ctx, bson.D{{"_id", res.InsertedID}}).Decode(&newUser); findErr != nil {
-		log.Println(findErr)
+		logging.FromContext(ctx).Error("database error", "error", findErr)
 		return nil, errors.New("unable to find user")
 	}
 
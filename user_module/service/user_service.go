@@ -5,21 +5,30 @@ import (
 	"codedln/user_module/model"
 	"codedln/user_module/repository"
 	"codedln/util/constant"
+	"codedln/util/helpers"
 	"codedln/util/types"
 	"context"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"google.golang.org/api/idtoken"
 	"net/http"
-	"os"
 )
 
+// GuestUrlClaimer moves links created as a guest into an account. It is implemented by the url repository.
+type GuestUrlClaimer interface {
+	ClaimGuestUrls(ctx context.Context, tokenHashes []string, userId primitive.ObjectID) (int64, error)
+}
+
 type UserService struct {
-	repo repository.UserRepository
+	repo           repository.UserRepository
+	claimer        GuestUrlClaimer
+	googleClientId string
 }
 
-func New(repo repository.UserRepository) *UserService {
+func New(repo repository.UserRepository, claimer GuestUrlClaimer, googleClientId string) *UserService {
 	return &UserService{
-		repo: repo,
+		repo:           repo,
+		claimer:        claimer,
+		googleClientId: googleClientId,
 	}
 }
 
@@ -27,7 +36,7 @@ func (s *UserService) CreateUser(ctx context.Context, idToken string, signInWith
 
 	switch signInWith {
 	case constant.GoogleSignIn:
-		payload, err := google(ctx, idToken)
+		payload, err := google(ctx, idToken, s.googleClientId)
 		if err != nil {
 			return nil, err
 		}
@@ -40,6 +49,9 @@ func (s *UserService) CreateUser(ctx context.Context, idToken string, signInWith
 
 		//User exist in database
 		if user != nil && err == nil {
+			if user.Suspended {
+				return nil, http_error.New(http.StatusForbidden, "account suspended")
+			}
 			return user, nil
 		}
 
@@ -51,6 +63,19 @@ func (s *UserService) CreateUser(ctx context.Context, idToken string, signInWith
 	}
 }
 
+func (s *UserService) ClaimGuestUrls(ctx context.Context, tokens []string, userId primitive.ObjectID) (int64, error) {
+	if s.claimer == nil || len(tokens) == 0 {
+		return 0, nil
+	}
+
+	tokenHashes := make([]string, len(tokens))
+	for i, token := range tokens {
+		tokenHashes[i] = helpers.HashToken(token)
+	}
+
+	return s.claimer.ClaimGuestUrls(ctx, tokenHashes, userId)
+}
+
 func (s *UserService) GetUser(ctx context.Context, userId string) (*model.User, error) {
 	objectId, err := primitive.ObjectIDFromHex(userId)
 	if err != nil {
@@ -64,9 +89,9 @@ func (s *UserService) DeleteUser(ctx context.Context, userId string) error {
 	return s.repo.DeleteUser(ctx, userId)
 }
 
-func google(ctx context.Context, idToken string) (*model.User, error) {
+func google(ctx context.Context, idToken string, clientId string) (*model.User, error) {
 	// Validate the ID token and obtain the payload
-	payload, err := idtoken.Validate(ctx, idToken, os.Getenv("GOOGLE_CLIENT_ID"))
+	payload, err := idtoken.Validate(ctx, idToken, clientId)
 	if err != nil {
 		// This error might occur if the token is expired or invalid
 		return nil, http_error.New(http.StatusBadRequest, "invalid id token")
@@ -105,6 +130,7 @@ func google(ctx context.Context, idToken string) (*model.User, error) {
 		Email:     email,
 		Picture:   picture,
 		Verified:  emailVerified,
+		Role:      constant.UserRole,
 	}
 
 	return user, nil
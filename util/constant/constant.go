@@ -5,22 +5,181 @@ import "codedln/util/types"
 const MaxPayloadSize int64 = 10_485_760 // 10MB
 const UserCollection = "users"
 const UrlCollection = "urls"
+const AuditCollection = "audit_logs"
+const ReportCollection = "reports"
+const ReservedAliasCollection = "reserved_aliases"
+const MigrationCollection = "migrations"
+const UtmTemplateCollection = "utm_templates"
+const BioPageCollection = "bio_pages"
+const WebhookEndpointCollection = "webhook_endpoints"
+const WebhookDeliveryCollection = "webhook_deliveries"
 
 const GoogleSignIn types.OAuthSignIn = "google"
 const GitHubSignIn types.OAuthSignIn = "github"
 
+const UserRole types.Role = "user"
+const AdminRole types.Role = "admin"
+
+const ActiveUrl types.UrlStatus = "active"
+const DisabledUrl types.UrlStatus = "disabled"
+const TakenDownUrl types.UrlStatus = "taken_down"
+const SuspendedUrl types.UrlStatus = "suspended"
+const BlockedUrl types.UrlStatus = "blocked"
+const OwnerSuspendedReason = "owner account suspended"                    //status reason of the links suspended together with their owner
+const ReportSuspendedReason = "suspended pending review of abuse reports" //status reason of the links suspended by abuse reports
+
+const DisableUrlAction types.AdminAction = "url.disable"
+const EnableUrlAction types.AdminAction = "url.enable"
+const TakeDownUrlAction types.AdminAction = "url.takedown"
+const SuspendUserAction types.AdminAction = "user.suspend"
+const UnsuspendUserAction types.AdminAction = "user.unsuspend"
+const ReviewReportAction types.AdminAction = "report.review"
+
+const PhishingReport types.ReportCategory = "phishing"
+const MalwareReport types.ReportCategory = "malware"
+const SpamReport types.ReportCategory = "spam"
+const IllegalContentReport types.ReportCategory = "illegal_content"
+const OtherReport types.ReportCategory = "other"
+
+const LightBioTheme types.BioTheme = "light"
+const DarkBioTheme types.BioTheme = "dark"
+const SunsetBioTheme types.BioTheme = "sunset"
+const ForestBioTheme types.BioTheme = "forest"
+
+const UrlCreatedWebhook types.WebhookEvent = "url.created"
+const UrlUpdatedWebhook types.WebhookEvent = "url.updated"
+const UrlDeletedWebhook types.WebhookEvent = "url.deleted"
+const ClickWebhook types.WebhookEvent = "click"
+const ClickThresholdWebhook types.WebhookEvent = "click.threshold"
+
+const PendingDelivery types.DeliveryStatus = "pending"
+const SucceededDelivery types.DeliveryStatus = "succeeded"
+const FailedDelivery types.DeliveryStatus = "failed"
+
+const RedirectHit types.RedirectResult = "hit"
+const RedirectMiss types.RedirectResult = "miss"
+const RedirectDisabled types.RedirectResult = "disabled"
+const RedirectInactive types.RedirectResult = "inactive"
+const RedirectFallback types.RedirectResult = "fallback"
+
+const OpenReport types.ReportState = "open"
+const ReviewedReport types.ReportState = "reviewed"
+const ActionedReport types.ReportState = "actioned"
+const DismissedReport types.ReportState = "dismissed"
+
 const (
-	PayloadKey  types.ContextKey = iota // iota increments for each constant, ensuring uniqueness
-	AuthUserKey types.ContextKey = iota
+	PayloadKey   types.ContextKey = iota // iota increments for each constant, ensuring uniqueness
+	AuthUserKey  types.ContextKey = iota
+	LoggerKey    types.ContextKey = iota
+	RequestIdKey types.ContextKey = iota
+	AccessLogKey types.ContextKey = iota
+	ClientIPKey  types.ContextKey = iota
 )
 
-const AccessTokenTTL = 24 //hours
+const DefaultAccessTokenTTL = 24 //hours
 const JwtCookieName = "_codedln_access_token"
 
 const AliasMaxLength = 8
 const AliasMinLength = 3
 const AliasRetry = 50
+const MaxUrlLength = 2048
+const TitleMaxLength = 200
+const DescriptionMaxLength = 300
+const NotesMaxLength = 1000
+const MaxTags = 20
+const TagMaxLength = 32
+const UtmMaxLength = 100
+const InactiveMessageMaxLength = 500
+const MaxGuestTokens = 50
+const BioHandleMinLength = 3
+const BioHandleMaxLength = 30
+const BioMaxLength = 300
+const MaxBioLinks = 50
+const ManagementTokenHeader = "X-Management-Token"
 
 const NewestDate types.DateSort = -1
 const OldestDate types.DateSort = 1
 const MaxLimit int64 = 25
+
+const SortByCreatedAt types.UrlSort = "created_at"
+const SortByUpdatedAt types.UrlSort = "updated_at"
+const SortByAlias types.UrlSort = "alias"
+const SortByClicks types.UrlSort = "clicks"
+const SortByLastClicked types.UrlSort = "last_clicked"
+const SortByExpiry types.UrlSort = "expiry"
+const SortByRelevance types.UrlSort = "relevance"
+
+const ModerationReasonMaxLength = 500
+
+const ReportCommentMaxLength = 1000
+const DefaultReportThreshold = 5 //open reports before a link is suspended
+
+const DefaultTrashRetentionDays = 30 //days a deleted link stays restorable
+const DefaultAliasCooldownDays = 90  //days a purged alias stays reserved
+const TrashPurgeInterval = 1         //hours
+
+const UrlClickChannel = "clicks:url:"   //followed by the url id
+const UserClickChannel = "clicks:user:" //followed by the owner's user id
+const SSEHeartbeatInterval = 15         //seconds
+
+const VisitorsKeyPrefix = "visitors:"        //followed by the url id, and a UTC date for daily counts
+const DailyClicksKeyPrefix = "clicks:daily:" //followed by the url id
+const BotKeySegment = "bots:"                //between the prefix and the url id for bot traffic
+const ReferralsKeyPrefix = "referrals:"      //followed by the url id
+const BioReferralPrefix = "bio:"             //followed by the handle of the bio page a click came from
+const VisitorSaltKey = "visitors:salt"
+const DailyStatsRetentionDays = 400
+const DefaultStatsRangeDays = 30
+const MaxStatsRangeDays = 366
+
+const BlocklistReloadInterval = 30 //seconds
+const DefaultSafeBrowsingUrl = "https://safebrowsing.googleapis.com"
+
+const HealthCheckInterval = 6        //hours between checks of the same link
+const HealthCheckScanInterval = 1    //hours between looking for links due a check
+const HealthCheckConcurrency = 8     //links checked at once
+const HealthCheckHostInterval = 1000 //milliseconds between requests to the same host
+const HealthCheckTimeout = 10        //seconds
+const BrokenLinkThreshold = 3        //failed checks in a row before a link is flagged
+const MaxHealthCheckRedirects = 10
+const HealthCheckDrainBytes = 64 << 10
+
+const DevelopmentEnvironment = "development"
+const ProductionEnvironment = "production"
+
+const DefaultServerAddr = ":8080"
+const DefaultMetricsAddr = ":9090" //admin port serving /metrics, kept apart from public traffic
+const DefaultReadTimeout = 10      //seconds
+const DefaultWriteTimeout = 10     //seconds
+
+const ServiceName = "codedln" //reported to the trace collector by default
+const TracerName = "codedln"
+
+const RequestIdHeader = "X-Request-ID"
+const RequestIdMaxLength = 128 //longer ids sent by clients are replaced rather than trusted
+
+const ProbeOk types.ProbeStatus = "ok"
+const ProbeUnavailable types.ProbeStatus = "unavailable"
+const ProbeDraining types.ProbeStatus = "draining"
+const DefaultReadinessTimeout = 2    //seconds allowed for every dependency to answer a readiness check
+const DefaultShutdownDrainDelay = 10 //seconds readiness fails for before the server stops accepting requests
+
+const OpenGraphFetchTimeout = 5     //seconds
+const OpenGraphMaxBytes = 512 << 10 //bytes of a page read when looking for its metadata
+
+const MaxWebhookEndpoints = 10 //per user
+const WebhookDescriptionMaxLength = 200
+const WebhookSecretPrefix = "whsec_"
+const WebhookEventHeader = "X-Codedln-Event"
+const WebhookDeliveryHeader = "X-Codedln-Delivery"
+const WebhookTimestampHeader = "X-Codedln-Timestamp"
+const WebhookSignatureHeader = "X-Codedln-Signature"
+const WebhookTimeout = 10               //seconds
+const WebhookPollInterval = 5           //seconds between looking for deliveries that are due
+const WebhookConcurrency = 8            //deliveries sent at once
+const WebhookMaxAttempts = 12           //attempts before a delivery is given up on
+const WebhookRetryBase = 30             //seconds before the first retry, doubling after each failure
+const WebhookRetryMax = 6               //hours between retries at most
+const WebhookResponseMaxBytes = 1 << 10 //bytes of the receiver's response kept in the delivery log
+const WebhookDeliveryRetentionDays = 30 //days deliveries stay in the log
+const FirstClickMilestone = 10          //clicks, then every power of ten after it
@@ -3,38 +3,37 @@ package helpers
 import (
 	"bytes"
 	"codedln/shared/http_error"
+	"codedln/util/constant"
 	"codedln/util/types"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"github.com/golang-jwt/jwt/v5"
 	"io"
 	"net"
 	"net/http"
-	"os"
 	"time"
 )
 
+// GetClientIP returns the address resolved by ClientIPMiddleware, or the address of the connection when the request
+// did not go through it. Forwarding headers are never read here since any client can set them.
 func GetClientIP(req *http.Request) string {
-	// Standard headers used by Amazon ELB, Heroku, and others.
-	if ip := req.Header.Get("X-Forwarded-For"); ip != "" {
-		return ip
-	}
-	if ip := req.Header.Get("X-Real-IP"); ip != "" {
+	if ip, ok := req.Context().Value(constant.ClientIPKey).(string); ok {
 		return ip
 	}
 
-	// Fallback to using the remote address from the request.
-	// This will give the network IP, which might be a proxy.
-	ip, _, _ := net.SplitHostPort(req.RemoteAddr)
+	ip, _, err := net.SplitHostPort(req.RemoteAddr)
+	if err != nil {
+		return req.RemoteAddr
+	}
 	return ip
 }
 
 // JSONResponse sends a JSON response with a given status code.
 func JSONResponse(w http.ResponseWriter, statusCode int, data any) error {
 	w.Header().Set("Content-Type", "application/json")
-	w.Header().Set("Access-Control-Allow-Origin", os.Getenv("ORIGIN"))
-	w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
-	w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-	w.Header().Set("Access-Control-Allow-Credentials", "true")
 	w.WriteHeader(statusCode)
 
 	var payload any
@@ -78,7 +77,7 @@ func Ternary[T any](expr bool, result1 T, result2 T) T {
 	}
 }
 
-func CreateJWT(claim types.AuthUser, ttl int) string {
+func CreateJWT(claim types.AuthUser, ttl int, secret string) string {
 	claims := types.JWTClaim{
 		UserId: claim.UserId,
 		RegisteredClaims: jwt.RegisteredClaims{
@@ -93,7 +92,7 @@ func CreateJWT(claim types.AuthUser, ttl int) string {
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString([]byte(os.Getenv("JWT_SECRET")))
+	ss, err := token.SignedString([]byte(secret))
 	if err != nil {
 		return ""
 	}
@@ -101,15 +100,16 @@ func CreateJWT(claim types.AuthUser, ttl int) string {
 	return ss
 }
 
-func CreateCookie(name string, value string, ttl int) http.Cookie {
+// CreateCookie returns a cookie for the whole site. In production it is also hidden from scripts and only sent over https.
+func CreateCookie(name string, value string, ttl int, production bool) http.Cookie {
 	cookie := http.Cookie{
 		Name:     name,
 		Value:    value,
 		Path:     "/",
 		Expires:  time.Now().Add(time.Duration(ttl) * time.Hour),
-		HttpOnly: Ternary[bool](os.Getenv("ENVIRONMENT") == "production", true, false),
+		HttpOnly: production,
 		SameSite: http.SameSiteStrictMode,
-		Secure:   Ternary[bool](os.Getenv("ENVIRONMENT") == "production", true, false),
+		Secure:   production,
 	}
 
 	return cookie
@@ -133,18 +133,30 @@ func MethodNotAllowed() http.HandlerFunc {
 	}
 }
 
+// PreflightRequest answers CORS preflight requests. The CORS headers themselves are set by middleware.CorsMiddleware.
 func PreflightRequest() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		w.Header().Set("Access-Control-Allow-Origin", os.Getenv("ORIGIN"))
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
-		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
-		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.WriteHeader(http.StatusOK)
 		return
 	}
 }
 
+// GenerateToken returns a random url safe secret suitable for bearer style tokens.
+func GenerateToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// HashToken returns the sha256 of a secret token. Only the hash is stored so a database leak does not leak tokens.
+func HashToken(token string) string {
+	h := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(h[:])
+}
+
 func AnyTypeToReader(data interface{}) (io.Reader, error) {
 	// Serialize the data to JSON
 	jsonData, err := json.Marshal(data)
@@ -157,3 +169,43 @@ func AnyTypeToReader(data interface{}) (io.Reader, error) {
 
 	return reader, nil
 }
+
+// StatusRecorder remembers the status code written through it. Unwrap lets http.ResponseController reach
+// the underlying writer, which the live click streams need to flush.
+type StatusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func NewStatusRecorder(w http.ResponseWriter) *StatusRecorder {
+	if recorder, ok := w.(*StatusRecorder); ok {
+		return recorder
+	}
+	return &StatusRecorder{ResponseWriter: w}
+}
+
+func (s *StatusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *StatusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	return s.ResponseWriter.Write(b)
+}
+
+func (s *StatusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
+
+// Status is the code that was written, which is 200 when the handler wrote nothing at all.
+func (s *StatusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
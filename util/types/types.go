@@ -17,6 +17,24 @@ type PayloadKey struct{}
 
 type OAuthSignIn string
 
+type Role string
+
+type UrlStatus string
+
+type AdminAction string
+
+type ReportCategory string
+
+type ReportState string
+
+type BioTheme string
+
+type WebhookEvent string
+
+type DeliveryStatus string
+
+type RedirectResult string
+
 type ContextKey int
 
 type JWTClaim struct {
@@ -26,6 +44,7 @@ type JWTClaim struct {
 
 type AuthUser struct {
 	UserId string `json:"userId"`
+	Role   Role   `json:"role,omitempty"`
 }
 
 type ServerResponse struct {
@@ -49,4 +68,20 @@ type PaginationResult[T any] struct {
 	Total int64 `json:"total"`
 }
 
+type CursorResult[T any] struct {
+	Data       []T    `json:"data"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	Total      *int64 `json:"total,omitempty"`
+}
+
 type DateSort int
+
+type UrlSort string
+
+// AccessLog collects what is only known deep in the handler chain, such as the authenticated user, so the
+// access log written once the request is done can include it.
+type AccessLog struct {
+	UserId string
+}
+
+type ProbeStatus string
@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"codedln/shared/http_error"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"codedln/webhook_module/model"
+	"codedln/webhook_module/service"
+	"github.com/gorilla/mux"
+	"net/http"
+	"strconv"
+)
+
+type WebhookController struct {
+	webhookService *service.WebhookService
+}
+
+func New(webhookService *service.WebhookService) *WebhookController {
+	return &WebhookController{
+		webhookService: webhookService,
+	}
+}
+
+func (c *WebhookController) CreateEndpoint(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	EndpointValue := r.Context().Value(constant.PayloadKey)
+	if EndpointValue == nil {
+		return http_error.New(http.StatusBadRequest, "no endpoint provided")
+	}
+	EndpointPayload, ok := EndpointValue.(model.CreateEndpointSchema)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid endpoint payload")
+	}
+
+	endpoint, err := c.webhookService.CreateEndpoint(r.Context(), EndpointPayload, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusCreated, model.CreatedEndpoint{Endpoint: *endpoint, Secret: endpoint.Secret})
+}
+
+func (c *WebhookController) GetEndpoints(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	endpoints, err := c.webhookService.GetEndpoints(r.Context(), UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, endpoints)
+}
+
+func (c *WebhookController) DeleteEndpoint(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	endpointId, exist := mux.Vars(r)["endpointId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no endpoint id found")
+	}
+
+	if err := c.webhookService.DeleteEndpoint(r.Context(), endpointId, UserIdPayload.UserId); err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, nil)
+}
+
+func (c *WebhookController) GetDeliveries(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	endpointId, exist := mux.Vars(r)["endpointId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no endpoint id found")
+	}
+
+	query := r.URL.Query()
+
+	var limit int64
+	var skip int64
+
+	val, err := strconv.ParseInt(query.Get("limit"), 10, 64)
+	if err != nil || val <= 0 || val > constant.MaxLimit {
+		limit = constant.MaxLimit
+	} else {
+		limit = val
+	}
+
+	val, err = strconv.ParseInt(query.Get("skip"), 10, 64)
+	if err != nil || val < 0 {
+		skip = 0
+	} else {
+		skip = val * limit
+	}
+
+	result, err := c.webhookService.GetDeliveries(r.Context(), endpointId, UserIdPayload.UserId, limit, skip)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusOK, result)
+}
+
+func (c *WebhookController) ReplayDelivery(w http.ResponseWriter, r *http.Request) error {
+	UserIDValue := r.Context().Value(constant.AuthUserKey)
+
+	if UserIDValue == nil {
+		return http_error.New(http.StatusBadRequest, "no user id")
+	}
+	UserIdPayload, ok := UserIDValue.(types.AuthUser)
+	if !ok {
+		return http_error.New(http.StatusBadRequest, "invalid user id")
+	}
+
+	deliveryId, exist := mux.Vars(r)["deliveryId"]
+	if !exist {
+		return http_error.New(http.StatusBadRequest, "no delivery id found")
+	}
+
+	delivery, err := c.webhookService.ReplayDelivery(r.Context(), deliveryId, UserIdPayload.UserId)
+	if err != nil {
+		return err
+	}
+
+	return helpers.JSONResponse(w, http.StatusCreated, delivery)
+}
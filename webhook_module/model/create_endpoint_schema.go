@@ -0,0 +1,44 @@
+package model
+
+import (
+	"codedln/shared/http_error"
+	urlModel "codedln/url_module/model"
+	"codedln/util/constant"
+	"codedln/util/types"
+	"net/http"
+	"slices"
+)
+
+type CreateEndpointSchema struct {
+	Url         string               `json:"url"`
+	Description string               `json:"description"`
+	Events      []types.WebhookEvent `json:"events"`
+}
+
+var webhookEvents = []types.WebhookEvent{constant.UrlCreatedWebhook, constant.UrlUpdatedWebhook, constant.UrlDeletedWebhook, constant.ClickWebhook, constant.ClickThresholdWebhook}
+
+func NewCreateEndpointSchema() CreateEndpointSchema {
+	return CreateEndpointSchema{}
+}
+
+func (s CreateEndpointSchema) Validate() error {
+	if _, err := urlModel.CanonicalUrl(s.Url); err != nil {
+		return http_error.New(http.StatusBadRequest, "invalid url: "+err.Error())
+	}
+
+	if len(s.Description) > constant.WebhookDescriptionMaxLength {
+		return http_error.New(http.StatusBadRequest, "description must be at most 200 characters")
+	}
+
+	if len(s.Events) == 0 {
+		return http_error.New(http.StatusBadRequest, "at least one event is required")
+	}
+
+	for _, event := range s.Events {
+		if !slices.Contains(webhookEvents, event) {
+			return http_error.New(http.StatusBadRequest, "unknown event "+string(event))
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"codedln/util/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Event is the json body of every delivery. A replayed delivery carries the same event id so receivers can ignore duplicates.
+type Event struct {
+	ID        string             `json:"id"`
+	Type      types.WebhookEvent `json:"type"`
+	CreatedAt time.Time          `json:"createdAt"`
+	Data      any                `json:"data"`
+}
+
+// Delivery is one event queued for one endpoint. Pending deliveries are retried until they succeed or run out of attempts,
+// and every delivery stays in the endpoint's log for WebhookDeliveryRetentionDays.
+type Delivery struct {
+	ID            primitive.ObjectID   `bson:"_id,omitempty" json:"_id,omitempty"`
+	EndpointId    primitive.ObjectID   `bson:"endpointId" json:"endpointId"`
+	UserId        primitive.ObjectID   `bson:"userId" json:"userId"`
+	EventId       string               `bson:"eventId" json:"eventId"`
+	Event         types.WebhookEvent   `bson:"event" json:"event"`
+	Payload       string               `bson:"payload" json:"payload"`
+	Status        types.DeliveryStatus `bson:"status" json:"status"`
+	Attempts      []DeliveryAttempt    `bson:"attempts" json:"attempts"`
+	NextAttemptAt *time.Time           `bson:"nextAttemptAt,omitempty" json:"nextAttemptAt,omitempty"`
+	LockedUntil   *time.Time           `bson:"lockedUntil,omitempty" json:"-"`
+	ReplayOf      *primitive.ObjectID  `bson:"replayOf,omitempty" json:"replayOf,omitempty"`
+	CreatedAt     time.Time            `bson:"createdAt" json:"createdAt"`
+	UpdatedAt     time.Time            `bson:"updatedAt" json:"updatedAt"`
+}
+
+// DeliveryAttempt is what happened when a delivery was sent once.
+type DeliveryAttempt struct {
+	StatusCode  int       `bson:"statusCode,omitempty" json:"statusCode,omitempty"`
+	Response    string    `bson:"response,omitempty" json:"response,omitempty"`
+	Error       string    `bson:"error,omitempty" json:"error,omitempty"`
+	LatencyMs   int64     `bson:"latencyMs" json:"latencyMs"`
+	AttemptedAt time.Time `bson:"attemptedAt" json:"attemptedAt"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import (
+	"codedln/util/types"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"time"
+)
+
+// Endpoint is a url the owner wants told about events on their links. Every delivery to it is signed with Secret,
+// which is never sent back once the endpoint has been created.
+type Endpoint struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"_id,omitempty"`
+	UserId      primitive.ObjectID   `bson:"userId" json:"userId"`
+	Url         string               `bson:"url" json:"url"`
+	Description string               `bson:"description,omitempty" json:"description,omitempty"`
+	Events      []types.WebhookEvent `bson:"events" json:"events"`
+	Secret      string               `bson:"secret" json:"-"`
+	CreatedAt   time.Time            `bson:"createdAt" json:"createdAt"`
+	UpdatedAt   time.Time            `bson:"updatedAt" json:"updatedAt"`
+}
+
+// CreatedEndpoint is the answer to creating an endpoint, the only time its signing secret is shown.
+type CreatedEndpoint struct {
+	Endpoint
+	Secret string `json:"secret"`
+}
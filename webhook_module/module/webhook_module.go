@@ -0,0 +1,108 @@
+package module
+
+import (
+	"codedln/shared/config"
+	"codedln/shared/middleware"
+	"codedln/shared/webhook"
+	"codedln/util/constant"
+	"codedln/webhook_module/controller"
+	"codedln/webhook_module/model"
+	"codedln/webhook_module/repository"
+	"codedln/webhook_module/service"
+	"context"
+	"github.com/go-redis/redis_rate/v10"
+	"github.com/gorilla/mux"
+	"go.mongodb.org/mongo-driver/mongo"
+	"log"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WebhookModule mounts the webhook routes and starts sending queued deliveries until ctx is cancelled.
+// The service is returned so the modules whose events are sent can queue them.
+func WebhookModule(ctx context.Context, router *mux.Router, limiter *redis_rate.Limiter, db *mongo.Database, cfg *config.Config) *service.WebhookService {
+	collection := db.Collection(constant.WebhookEndpointCollection)
+	if collection == nil {
+		log.Fatalf("%s does not exist:", constant.WebhookEndpointCollection)
+	}
+
+	deliveryCollection := db.Collection(constant.WebhookDeliveryCollection)
+	if deliveryCollection == nil {
+		log.Fatalf("%s does not exist:", constant.WebhookDeliveryCollection)
+	}
+
+	userCollection := db.Collection(constant.UserCollection)
+	if userCollection == nil {
+		log.Fatalf("%s does not exist:", constant.UserCollection)
+	}
+
+	webhookRepo := repository.New(collection, deliveryCollection)
+	if indexErr := webhookRepo.CreateIndexes(context.Background()); indexErr != nil {
+		log.Fatalf("Error creating %s indexes", constant.WebhookDeliveryCollection)
+	}
+	webhookService := service.New(webhookRepo, webhook.New(nil, constant.WebhookTimeout*time.Second))
+	webhookController := controller.New(webhookService)
+
+	// Deliveries are queued in the database, so anything still due after a restart is sent on the first pass
+	go func() {
+		ticker := time.NewTicker(constant.WebhookPollInterval * time.Second)
+		defer ticker.Stop()
+		for {
+			if deliverErr := webhookService.Deliver(ctx); deliverErr != nil {
+				slog.Error("unable to deliver webhooks", "error", deliverErr)
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+
+	webhookRouter := router.PathPrefix("/webhook").Subrouter()
+
+	webhookRouter.HandleFunc("/create_endpoint",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			webhookController.CreateEndpoint,
+			middleware.PayloadValidationMiddleware(model.NewCreateEndpointSchema),
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPost)
+
+	webhookRouter.HandleFunc("/endpoints",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			webhookController.GetEndpoints,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	webhookRouter.HandleFunc("/delete_endpoint/{endpointId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			webhookController.DeleteEndpoint,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodDelete)
+
+	webhookRouter.HandleFunc("/deliveries/{endpointId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			webhookController.GetDeliveries,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Standard),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodGet)
+
+	webhookRouter.HandleFunc("/replay/{deliveryId}",
+		middleware.ExceptionMiddleware(middleware.ChainMiddlewares(
+			webhookController.ReplayDelivery,
+			middleware.AuthenticationMiddleware(userCollection, cfg.Auth.JwtSecret),
+			middleware.RateLimitMiddleware(limiter, cfg.RateLimits.Strict),
+			middleware.ValidateAPIKeyMiddleware(cfg.Auth.ClientKey),
+		))).Methods(http.MethodPost)
+
+	return webhookService
+}
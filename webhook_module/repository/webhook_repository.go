@@ -0,0 +1,240 @@
+package repository
+
+import (
+	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	"codedln/util/constant"
+	"codedln/util/types"
+	"codedln/webhook_module/model"
+	"context"
+	"errors"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+	"net/http"
+	"time"
+)
+
+type WebhookRepository interface {
+	CreateIndexes(ctx context.Context) error
+	CreateEndpoint(ctx context.Context, endpoint model.Endpoint) (*model.Endpoint, error)
+	GetEndpoint(ctx context.Context, filter bson.D) (*model.Endpoint, error)
+	GetEndpoints(ctx context.Context, filter bson.D) ([]model.Endpoint, error)
+	CountEndpoints(ctx context.Context, userId primitive.ObjectID) (int64, error)
+	DeleteEndpoint(ctx context.Context, endpointId primitive.ObjectID, userId primitive.ObjectID) (bool, error)
+	CreateDeliveries(ctx context.Context, deliveries []model.Delivery) error
+	GetDelivery(ctx context.Context, filter bson.D) (*model.Delivery, error)
+	GetDeliveries(ctx context.Context, endpointId primitive.ObjectID, limit int64, skip int64) (*types.PaginationResult[model.Delivery], error)
+	ClaimDelivery(ctx context.Context, now time.Time, lease time.Duration) (*model.Delivery, error)
+	UpdateDelivery(ctx context.Context, deliveryId primitive.ObjectID, update bson.D) error
+}
+
+type MongoWebhookRepository struct {
+	collection         *mongo.Collection
+	deliveryCollection *mongo.Collection
+}
+
+func New(collection *mongo.Collection, deliveryCollection *mongo.Collection) WebhookRepository {
+	return &MongoWebhookRepository{
+		collection:         collection,
+		deliveryCollection: deliveryCollection,
+	}
+}
+
+func (r *MongoWebhookRepository) CreateIndexes(ctx context.Context) error {
+	_, err := r.collection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"userId", 1}, {"events", 1}},
+			Options: options.Index().SetName("user_events"),
+		},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to create webhook endpoint indexes")
+	}
+
+	_, err = r.deliveryCollection.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{"status", 1}, {"nextAttemptAt", 1}},
+			Options: options.Index().SetName("due"),
+		},
+		{
+			Keys:    bson.D{{"endpointId", 1}, {"createdAt", -1}},
+			Options: options.Index().SetName("endpoint_log"),
+		},
+		{
+			Keys:    bson.D{{"createdAt", 1}},
+			Options: options.Index().SetName("retention").SetExpireAfterSeconds(constant.WebhookDeliveryRetentionDays * 24 * 60 * 60),
+		},
+	})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to create webhook delivery indexes")
+	}
+	return nil
+}
+
+func (r *MongoWebhookRepository) CreateEndpoint(ctx context.Context, endpoint model.Endpoint) (*model.Endpoint, error) {
+	res, err := r.collection.InsertOne(ctx, endpoint)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to create webhook endpoint")
+	}
+
+	endpoint.ID = res.InsertedID.(primitive.ObjectID)
+	return &endpoint, nil
+}
+
+func (r *MongoWebhookRepository) GetEndpoint(ctx context.Context, filter bson.D) (*model.Endpoint, error) {
+	res := r.collection.FindOne(ctx, filter)
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	var endpoint model.Endpoint
+	if decodeErr := res.Decode(&endpoint); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get webhook endpoint")
+	}
+
+	return &endpoint, nil
+}
+
+func (r *MongoWebhookRepository) GetEndpoints(ctx context.Context, filter bson.D) ([]model.Endpoint, error) {
+	cursor, err := r.collection.Find(ctx, filter, options.Find().SetSort(bson.D{{"createdAt", 1}}))
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get webhook endpoints")
+	}
+
+	endpoints := []model.Endpoint{}
+	if err = cursor.All(ctx, &endpoints); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to decode webhook endpoints")
+	}
+
+	return endpoints, nil
+}
+
+func (r *MongoWebhookRepository) CountEndpoints(ctx context.Context, userId primitive.ObjectID) (int64, error) {
+	count, err := r.collection.CountDocuments(ctx, bson.D{{"userId", userId}})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return 0, http_error.New(http.StatusInternalServerError, "unable to count webhook endpoints")
+	}
+	return count, nil
+}
+
+// DeleteEndpoint removes the endpoint along with its delivery log, which also stops any retries still queued for it.
+func (r *MongoWebhookRepository) DeleteEndpoint(ctx context.Context, endpointId primitive.ObjectID, userId primitive.ObjectID) (bool, error) {
+	res, err := r.collection.DeleteOne(ctx, bson.D{{"_id", endpointId}, {"userId", userId}})
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return false, http_error.New(http.StatusInternalServerError, "unable to delete webhook endpoint")
+	}
+	if res.DeletedCount == 0 {
+		return false, nil
+	}
+
+	if _, err = r.deliveryCollection.DeleteMany(ctx, bson.D{{"endpointId", endpointId}}); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return true, http_error.New(http.StatusInternalServerError, "unable to delete webhook deliveries")
+	}
+	return true, nil
+}
+
+func (r *MongoWebhookRepository) CreateDeliveries(ctx context.Context, deliveries []model.Delivery) error {
+	documents := make([]any, len(deliveries))
+	for i, delivery := range deliveries {
+		documents[i] = delivery
+	}
+
+	if _, err := r.deliveryCollection.InsertMany(ctx, documents); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to queue webhook deliveries")
+	}
+	return nil
+}
+
+func (r *MongoWebhookRepository) GetDelivery(ctx context.Context, filter bson.D) (*model.Delivery, error) {
+	res := r.deliveryCollection.FindOne(ctx, filter)
+	if errors.Is(res.Err(), mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+
+	var delivery model.Delivery
+	if decodeErr := res.Decode(&delivery); decodeErr != nil {
+		logging.FromContext(ctx).Error("database error", "error", decodeErr)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to get webhook delivery")
+	}
+
+	return &delivery, nil
+}
+
+func (r *MongoWebhookRepository) GetDeliveries(ctx context.Context, endpointId primitive.ObjectID, limit int64, skip int64) (*types.PaginationResult[model.Delivery], error) {
+	filter := bson.D{{"endpointId", endpointId}}
+
+	totalCount, err := r.deliveryCollection.CountDocuments(ctx, filter)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to count webhook deliveries")
+	}
+
+	opts := options.Find().
+		SetSort(bson.D{{"createdAt", -1}, {"_id", -1}}).
+		SetSkip(skip).
+		SetLimit(limit).
+		SetMaxTime(2 * time.Second)
+	cursor, err := r.deliveryCollection.Find(ctx, filter, opts)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to fetch webhook deliveries")
+	}
+
+	results := []model.Delivery{}
+	if err = cursor.All(ctx, &results); err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to process webhook deliveries")
+	}
+
+	return &types.PaginationResult[model.Delivery]{
+		Data:  results,
+		Total: totalCount,
+	}, nil
+}
+
+// ClaimDelivery locks the pending delivery that has been due the longest for lease, so that no other server instance sends it meanwhile.
+// A delivery whose sender died is picked up again once its lease runs out. It returns nil when nothing is due.
+func (r *MongoWebhookRepository) ClaimDelivery(ctx context.Context, now time.Time, lease time.Duration) (*model.Delivery, error) {
+	filter := bson.D{
+		{"status", constant.PendingDelivery},
+		{"nextAttemptAt", bson.D{{"$lte", now}}},
+		{"$or", bson.A{
+			bson.D{{"lockedUntil", bson.D{{"$exists", false}}}},
+			bson.D{{"lockedUntil", bson.D{{"$lte", now}}}},
+		}},
+	}
+	update := bson.D{{"$set", bson.D{{"lockedUntil", now.Add(lease)}}}}
+	opts := options.FindOneAndUpdate().SetSort(bson.D{{"nextAttemptAt", 1}}).SetReturnDocument(options.After)
+
+	var delivery model.Delivery
+	if err := r.deliveryCollection.FindOneAndUpdate(ctx, filter, update, opts).Decode(&delivery); err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, nil
+		}
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to claim webhook delivery")
+	}
+
+	return &delivery, nil
+}
+
+func (r *MongoWebhookRepository) UpdateDelivery(ctx context.Context, deliveryId primitive.ObjectID, update bson.D) error {
+	_, err := r.deliveryCollection.UpdateOne(ctx, bson.D{{"_id", deliveryId}}, update)
+	if err != nil {
+		logging.FromContext(ctx).Error("database error", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to update webhook delivery")
+	}
+	return nil
+}
@@ -0,0 +1,313 @@
+package service
+
+import (
+	"codedln/shared/http_error"
+	"codedln/shared/logging"
+	"codedln/shared/webhook"
+	urlModel "codedln/url_module/model"
+	"codedln/util/constant"
+	"codedln/util/helpers"
+	"codedln/util/types"
+	"codedln/webhook_module/model"
+	"codedln/webhook_module/repository"
+	"context"
+	"encoding/json"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+type WebhookService struct {
+	repo   repository.WebhookRepository
+	sender *webhook.Sender
+}
+
+func New(repo repository.WebhookRepository, sender *webhook.Sender) *WebhookService {
+	return &WebhookService{
+		repo:   repo,
+		sender: sender,
+	}
+}
+
+// CreateEndpoint registers a receiver for the given events. Its signing secret is generated here and returned with it.
+func (s *WebhookService) CreateEndpoint(ctx context.Context, payload model.CreateEndpointSchema, userId string) (*model.Endpoint, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	count, err := s.repo.CountEndpoints(ctx, userIdObj)
+	if err != nil {
+		return nil, err
+	}
+	if count >= constant.MaxWebhookEndpoints {
+		return nil, http_error.New(http.StatusBadRequest, "at most 10 webhook endpoints can be added")
+	}
+
+	url, err := urlModel.CanonicalUrl(payload.Url)
+	if err != nil {
+		return nil, http_error.New(http.StatusBadRequest, "invalid url: "+err.Error())
+	}
+
+	secret, err := helpers.GenerateToken()
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to create webhook secret", "error", err)
+		return nil, http_error.New(http.StatusInternalServerError, "unable to create webhook secret")
+	}
+
+	events := slices.Clone(payload.Events)
+	slices.Sort(events)
+
+	return s.repo.CreateEndpoint(ctx, model.Endpoint{
+		UserId:      userIdObj,
+		Url:         url,
+		Description: strings.TrimSpace(payload.Description),
+		Events:      slices.Compact(events),
+		Secret:      constant.WebhookSecretPrefix + secret,
+		CreatedAt:   time.Now().UTC(),
+		UpdatedAt:   time.Now().UTC(),
+	})
+}
+
+func (s *WebhookService) GetEndpoints(ctx context.Context, userId string) ([]model.Endpoint, error) {
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+	return s.repo.GetEndpoints(ctx, bson.D{{"userId", userIdObj}})
+}
+
+func (s *WebhookService) DeleteEndpoint(ctx context.Context, endpointId string, userId string) error {
+	endpoint, err := s.getEndpoint(ctx, endpointId, userId)
+	if err != nil {
+		return err
+	}
+
+	deleted, err := s.repo.DeleteEndpoint(ctx, endpoint.ID, endpoint.UserId)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return http_error.New(http.StatusNotFound, "no webhook endpoint found")
+	}
+	return nil
+}
+
+// GetDeliveries returns the endpoint's delivery log, newest first.
+func (s *WebhookService) GetDeliveries(ctx context.Context, endpointId string, userId string, limit int64, skip int64) (*types.PaginationResult[model.Delivery], error) {
+	endpoint, err := s.getEndpoint(ctx, endpointId, userId)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.GetDeliveries(ctx, endpoint.ID, limit, skip)
+}
+
+// ReplayDelivery queues the body of an earlier delivery to be sent again, whatever happened to it the first time.
+// The replay is a new delivery in the log with a fresh set of attempts.
+func (s *WebhookService) ReplayDelivery(ctx context.Context, deliveryId string, userId string) (*model.Delivery, error) {
+	deliveryIdObj, err := primitive.ObjectIDFromHex(deliveryId)
+	if err != nil {
+		return nil, http_error.New(http.StatusBadRequest, "invalid delivery id")
+	}
+
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	original, err := s.repo.GetDelivery(ctx, bson.D{{"_id", deliveryIdObj}, {"userId", userIdObj}})
+	if err != nil {
+		return nil, err
+	}
+	if original == nil {
+		return nil, http_error.New(http.StatusNotFound, "no webhook delivery found")
+	}
+
+	now := time.Now().UTC()
+	replay := model.Delivery{
+		ID:            primitive.NewObjectID(),
+		EndpointId:    original.EndpointId,
+		UserId:        original.UserId,
+		EventId:       original.EventId,
+		Event:         original.Event,
+		Payload:       original.Payload,
+		Status:        constant.PendingDelivery,
+		Attempts:      []model.DeliveryAttempt{},
+		NextAttemptAt: &now,
+		ReplayOf:      &original.ID,
+		CreatedAt:     now,
+		UpdatedAt:     now,
+	}
+	if err = s.repo.CreateDeliveries(ctx, []model.Delivery{replay}); err != nil {
+		return nil, err
+	}
+	return &replay, nil
+}
+
+// Notify queues the event for every endpoint of the user that listens for it. The body is built once so every endpoint,
+// and every retry or replay, receives exactly the same bytes.
+func (s *WebhookService) Notify(ctx context.Context, userId primitive.ObjectID, event types.WebhookEvent, data any) error {
+	endpoints, err := s.repo.GetEndpoints(ctx, bson.D{{"userId", userId}, {"events", event}})
+	if err != nil {
+		return err
+	}
+	if len(endpoints) == 0 {
+		return nil
+	}
+
+	now := time.Now().UTC()
+	envelope := model.Event{
+		ID:        primitive.NewObjectIDFromTimestamp(now).Hex(),
+		Type:      event,
+		CreatedAt: now,
+		Data:      data,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		logging.FromContext(ctx).Error("unable to encode webhook event", "error", err)
+		return http_error.New(http.StatusInternalServerError, "unable to encode webhook event")
+	}
+
+	deliveries := make([]model.Delivery, len(endpoints))
+	for i, endpoint := range endpoints {
+		deliveries[i] = model.Delivery{
+			EndpointId:    endpoint.ID,
+			UserId:        userId,
+			EventId:       envelope.ID,
+			Event:         event,
+			Payload:       string(payload),
+			Status:        constant.PendingDelivery,
+			Attempts:      []model.DeliveryAttempt{},
+			NextAttemptAt: &now,
+			CreatedAt:     now,
+			UpdatedAt:     now,
+		}
+	}
+	return s.repo.CreateDeliveries(ctx, deliveries)
+}
+
+// Deliver sends every delivery that is due, a few at a time, and returns once they have all been attempted.
+func (s *WebhookService) Deliver(ctx context.Context) error {
+	slots := make(chan struct{}, constant.WebhookConcurrency)
+	var wg sync.WaitGroup
+	defer wg.Wait()
+
+	for {
+		// A slot is taken before claiming so a claimed delivery never waits out its lease in the queue
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		delivery, err := s.repo.ClaimDelivery(ctx, time.Now().UTC(), 2*constant.WebhookTimeout*time.Second)
+		if err != nil || delivery == nil {
+			<-slots
+			return err
+		}
+
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-slots
+				wg.Done()
+			}()
+			s.deliver(ctx, *delivery)
+		}()
+	}
+}
+
+// deliver sends the delivery once and records the attempt. Failures are retried with exponential backoff until WebhookMaxAttempts.
+func (s *WebhookService) deliver(ctx context.Context, delivery model.Delivery) {
+	// The delivery is tried again once its lease runs out
+	endpoint, err := s.repo.GetEndpoint(ctx, bson.D{{"_id", delivery.EndpointId}})
+	if err != nil {
+		return
+	}
+
+	// Deliveries to a deleted endpoint can never succeed, so they are given up on straight away
+	if endpoint == nil {
+		s.record(ctx, delivery, model.DeliveryAttempt{Error: "the endpoint was deleted", AttemptedAt: time.Now().UTC()}, constant.FailedDelivery)
+		return
+	}
+
+	sent := s.sender.Send(ctx, webhook.Message{
+		Url:        endpoint.Url,
+		Secret:     endpoint.Secret,
+		DeliveryId: delivery.ID.Hex(),
+		Event:      delivery.Event,
+		Body:       []byte(delivery.Payload),
+	})
+	attempt := model.DeliveryAttempt{
+		StatusCode:  sent.StatusCode,
+		Response:    sent.Response,
+		Error:       sent.Error,
+		LatencyMs:   sent.Latency.Milliseconds(),
+		AttemptedAt: sent.AttemptedAt,
+	}
+
+	// An attempt cut short by shutdown is not the endpoint's fault; the delivery is sent again once its lease runs out
+	if ctx.Err() != nil {
+		return
+	}
+
+	switch {
+	case sent.Succeeded():
+		s.record(ctx, delivery, attempt, constant.SucceededDelivery)
+	case len(delivery.Attempts)+1 >= constant.WebhookMaxAttempts:
+		s.record(ctx, delivery, attempt, constant.FailedDelivery)
+	default:
+		s.record(ctx, delivery, attempt, constant.PendingDelivery)
+	}
+}
+
+// record adds the attempt to the delivery's log and releases its lease. Deliveries left pending are scheduled for another attempt.
+func (s *WebhookService) record(ctx context.Context, delivery model.Delivery, attempt model.DeliveryAttempt, status types.DeliveryStatus) {
+	set := bson.D{{"status", status}, {"updatedAt", time.Now().UTC()}}
+	unset := bson.D{{"lockedUntil", ""}}
+	if status == constant.PendingDelivery {
+		set = append(set, bson.E{Key: "nextAttemptAt", Value: time.Now().UTC().Add(backoff(len(delivery.Attempts) + 1))})
+	} else {
+		unset = append(unset, bson.E{Key: "nextAttemptAt", Value: ""})
+	}
+
+	update := bson.D{{"$set", set}, {"$push", bson.D{{"attempts", attempt}}}, {"$unset", unset}}
+	if err := s.repo.UpdateDelivery(ctx, delivery.ID, update); err != nil {
+		logging.FromContext(ctx).Error("unable to record webhook delivery", "delivery_id", delivery.ID.Hex(), "error", err)
+	}
+}
+
+// backoff is how long to wait after the given number of failed attempts: WebhookRetryBase doubled after each failure, up to WebhookRetryMax.
+func backoff(attempts int) time.Duration {
+	wait := constant.WebhookRetryBase * time.Second
+	for i := 1; i < attempts && wait < constant.WebhookRetryMax*time.Hour; i++ {
+		wait *= 2
+	}
+	return min(wait, constant.WebhookRetryMax*time.Hour)
+}
+
+// getEndpoint returns one of the user's endpoints, or a 404 when they have no endpoint with the id.
+func (s *WebhookService) getEndpoint(ctx context.Context, endpointId string, userId string) (*model.Endpoint, error) {
+	endpointIdObj, err := primitive.ObjectIDFromHex(endpointId)
+	if err != nil {
+		return nil, http_error.New(http.StatusBadRequest, "invalid endpoint id")
+	}
+
+	userIdObj, err := primitive.ObjectIDFromHex(userId)
+	if err != nil {
+		return nil, http_error.New(http.StatusInternalServerError, "unable to parse user id")
+	}
+
+	endpoint, err := s.repo.GetEndpoint(ctx, bson.D{{"_id", endpointIdObj}, {"userId", userIdObj}})
+	if err != nil {
+		return nil, err
+	}
+	if endpoint == nil {
+		return nil, http_error.New(http.StatusNotFound, "no webhook endpoint found")
+	}
+	return endpoint, nil
+}